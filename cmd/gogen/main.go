@@ -0,0 +1,300 @@
+// Command gogen generates a Go project scaffold without running the HTTP server.
+//
+// It builds a GenerateRequest from a JSON/YAML file and/or flags, runs it through
+// the same GeneratorService used by POST /generate, and writes the result to a
+// directory, or as a ZIP, tar.gz or tar.zst archive to a file or stdout.
+//
+// Usage:
+//
+//	gogen -name my-api -module github.com/user/my-api -framework gin -libs redis,postgres -example
+//	gogen -f request.yaml -archive my-api.zip
+//	gogen -f request.json -stdout > my-api.zip
+//	gogen -f request.json -archive my-api.tar.gz -format tar.gz
+//	gogen -name petstore -module github.com/user/petstore -framework gin -openapi petstore.yaml
+//	gogen -name shop -module github.com/user/shop -framework gin -libs postgres -sql schema.sql
+//	gogen -verify
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/models"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// options holds the parsed command line flags
+type options struct {
+	requestFile  string
+	root         string
+	manifestPath string
+
+	projectName    string
+	moduleName     string
+	framework      string
+	architecture   string
+	libs           string
+	includeExample bool
+	openAPIFile    string
+	sqlFile        string
+	configFormat   string
+
+	outDir      string
+	archivePath string
+	format      string
+	stdout      bool
+	force       bool
+	timeout     time.Duration
+
+	verify bool
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "gogen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	opts, setFlags, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+	if opts.verify {
+		return verify(opts)
+	}
+
+	// Build the request from the file first, then let explicit flags override it
+	req := &models.GenerateRequest{}
+	if opts.requestFile != "" {
+		if req, err = loadRequestFile(opts.requestFile); err != nil {
+			return err
+		}
+	}
+	if err := applyFlags(req, opts, setFlags); err != nil {
+		return err
+	}
+
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	// Resolve output paths before changing into the templates root
+	if err := resolveOutputPaths(opts, req); err != nil {
+		return err
+	}
+
+	if opts.root != "" {
+		if err := os.Chdir(opts.root); err != nil {
+			return fmt.Errorf("failed to enter root directory %s: %w", opts.root, err)
+		}
+	}
+
+	genService, err := newService(opts.manifestPath)
+	if err != nil {
+		return err
+	}
+
+	genService.SetGenerationTimeout(opts.timeout)
+
+	// Ctrl-C stops the generation at its next stage
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := genService.GenerateProject(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
+	switch {
+	case opts.stdout:
+		_, err = result.WriteArchive(os.Stdout, opts.format)
+		return err
+	case opts.archivePath != "":
+		size, digest, err := writeArchiveFile(result, opts.archivePath, opts.format)
+		if err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s (%d bytes, sha256 %s)\n", opts.archivePath, size, digest)
+		return nil
+	default:
+		var zipData bytes.Buffer
+		if _, err := result.WriteArchive(&zipData, constants.ArchiveFormatZip); err != nil {
+			return err
+		}
+		n, err := extractZip(zipData.Bytes(), opts.outDir, opts.force)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Generated %d files in %s\n", n, opts.outDir)
+		return nil
+	}
+}
+
+// parseFlags parses command line arguments and reports which flags were set explicitly
+func parseFlags(args []string) (*options, map[string]bool, error) {
+	opts := &options{}
+	fs := flag.NewFlagSet("gogen", flag.ContinueOnError)
+
+	fs.StringVar(&opts.requestFile, "f", "", "read the generate request from a JSON or YAML file")
+	fs.StringVar(&opts.root, "root", "", "directory containing manifest.json and templates/ (default: current directory)")
+	fs.StringVar(&opts.manifestPath, "manifest", defaultManifestPath(), "path to the manifest, relative to -root")
+
+	fs.StringVar(&opts.projectName, "name", "", "project name (e.g. my-project)")
+	fs.StringVar(&opts.moduleName, "module", "", "Go module path (e.g. github.com/user/my-project)")
+	fs.StringVar(&opts.framework, "framework", "", "Framework (gin | fiber | echo | nethttp | chi | grpc)")
+	fs.StringVar(&opts.architecture, "architecture", "", "project architecture")
+	fs.StringVar(&opts.libs, "libs", "", "comma-separated list of libraries (e.g. redis,postgres)")
+	fs.BoolVar(&opts.includeExample, "example", false, "include example code")
+	fs.StringVar(&opts.openAPIFile, "openapi", "", "generate DTOs, handlers and routes from an OpenAPI 3 document (YAML or JSON)")
+	fs.StringVar(&opts.sqlFile, "sql", "", "generate entities from CREATE TABLE statements (dialect of the postgres or mysql lib)")
+	fs.StringVar(&opts.configFormat, "config-format", "", "config file format (json | yaml | toml | env)")
+
+	fs.StringVar(&opts.outDir, "o", "", "write the project into this directory (default: ./<projectName>)")
+	fs.StringVar(&opts.archivePath, "archive", "", "write the project as an archive to this path")
+	fs.BoolVar(&opts.stdout, "stdout", false, "write the archive to stdout")
+	fs.StringVar(&opts.format, "format", "", "archive format for -archive and -stdout (zip | tar.gz | tar.zst, default zip)")
+	fs.BoolVar(&opts.force, "force", false, "write into a non-empty output directory")
+	fs.DurationVar(&opts.timeout, "timeout", constants.DefaultGenerationTimeout, "abort the generation after this long (0 disables the deadline)")
+	fs.BoolVar(&opts.verify, "verify", false, "type-check a pairwise sample of framework, architecture, lib and example combinations instead of generating a project")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	outputs := 0
+	for _, set := range []bool{opts.outDir != "", opts.archivePath != "", opts.stdout} {
+		if set {
+			outputs++
+		}
+	}
+	if outputs > 1 {
+		return nil, nil, fmt.Errorf("only one of -o, -archive and -stdout may be given")
+	}
+	if opts.verify && (outputs > 0 || opts.requestFile != "") {
+		return nil, nil, fmt.Errorf("-verify does not generate a project; drop -f, -o, -archive and -stdout")
+	}
+	if opts.format != "" {
+		if opts.archivePath == "" && !opts.stdout {
+			return nil, nil, fmt.Errorf("-format applies only to -archive and -stdout")
+		}
+		if err := service.ValidateArchiveFormat(opts.format); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	return opts, setFlags, nil
+}
+
+// applyFlags copies explicitly set flags onto the request
+func applyFlags(req *models.GenerateRequest, opts *options, setFlags map[string]bool) error {
+	if setFlags["name"] {
+		req.ProjectName = opts.projectName
+	}
+	if setFlags["module"] {
+		req.ModuleName = opts.moduleName
+	}
+	if setFlags["framework"] {
+		req.Framework = opts.framework
+	}
+	if setFlags["architecture"] {
+		req.Architecture = opts.architecture
+	}
+	if setFlags["libs"] {
+		req.Libs = splitList(opts.libs)
+	}
+	if setFlags["example"] {
+		req.IncludeExample = opts.includeExample
+	}
+	if setFlags["config-format"] {
+		req.ConfigFormat = opts.configFormat
+	}
+	if setFlags["openapi"] {
+		data, err := os.ReadFile(opts.openAPIFile)
+		if err != nil {
+			return fmt.Errorf("failed to read openapi document: %w", err)
+		}
+		req.OpenAPI = string(data)
+	}
+	if setFlags["sql"] {
+		data, err := os.ReadFile(opts.sqlFile)
+		if err != nil {
+			return fmt.Errorf("failed to read sql schema: %w", err)
+		}
+		req.SQL = string(data)
+	}
+	return nil
+}
+
+// resolveOutputPaths fills in the default output directory and makes paths absolute
+func resolveOutputPaths(opts *options, req *models.GenerateRequest) error {
+	if opts.stdout {
+		return nil
+	}
+	if opts.archivePath == "" && opts.outDir == "" {
+		opts.outDir = req.ProjectName
+	}
+
+	var err error
+	if opts.archivePath != "" {
+		if opts.archivePath, err = filepath.Abs(opts.archivePath); err != nil {
+			return fmt.Errorf("invalid archive path: %w", err)
+		}
+	}
+	if opts.outDir != "" {
+		if opts.outDir, err = filepath.Abs(opts.outDir); err != nil {
+			return fmt.Errorf("invalid output directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// newService loads the manifest, honouring the PROTOC_GEN_GO setting of the server
+func newService(manifestPath string) (*service.GeneratorService, error) {
+	genService, err := service.NewGeneratorService(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	if plugin := os.Getenv("PROTOC_GEN_GO"); plugin != "" {
+		genService.SetProtocGenGo(plugin)
+	}
+	return genService, nil
+}
+
+func defaultManifestPath() string {
+	if path := os.Getenv("MANIFEST_PATH"); path != "" {
+		return path
+	}
+	return constants.DefaultManifestPath
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// repoRoot is the directory holding manifest.json and templates/
+func repoRoot(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// runIn runs gogen from dir against the repository's templates, restoring the working
+// directory that -root changes
+func runIn(t *testing.T, dir string, args ...string) error {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := repoRoot(t)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	return run(append([]string{"-root", root}, args...))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"valid", []string{"-name", "demo", "-archive", "demo.tar.gz", "-format", "tar.gz"}, ""},
+		{"two outputs", []string{"-o", "out", "-stdout"}, "only one of -o, -archive and -stdout"},
+		{"format without archive", []string{"-format", "zip"}, "-format applies only to -archive and -stdout"},
+		{"unknown format", []string{"-stdout", "-format", "rar"}, "format must be one of"},
+		{"verify with output", []string{"-verify", "-o", "out"}, "-verify does not generate a project"},
+		{"arguments", []string{"-name", "demo", "extra"}, "unexpected arguments: extra"},
+		{"unknown flag", []string{"-nam", "demo"}, "flag provided but not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseFlags(tt.args)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("parseFlags() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseFlags() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestMerging(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"request.json": `{"projectName": "shop", "moduleName": "example.com/shop", "framework": "gin",
+			"libs": ["redis"], "includeExample": true, "configFormat": "yaml"}`,
+		"request.yaml": "projectName: shop\nmoduleName: example.com/shop\nframework: gin\nlibs:\n  - redis\nincludeExample: true\nconfigFormat: yaml\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			writeFile(t, path, content)
+
+			// Flags given on the command line override the file; the others keep its values
+			opts, setFlags, err := parseFlags([]string{"-f", path, "-name", "store", "-libs", "postgres, kafka", "-example=false"})
+			if err != nil {
+				t.Fatalf("parseFlags() error = %v", err)
+			}
+			req, err := loadRequestFile(opts.requestFile)
+			if err != nil {
+				t.Fatalf("loadRequestFile() error = %v", err)
+			}
+			if err := applyFlags(req, opts, setFlags); err != nil {
+				t.Fatalf("applyFlags() error = %v", err)
+			}
+
+			if req.ProjectName != "store" || req.ModuleName != "example.com/shop" || req.Framework != "gin" {
+				t.Errorf("request = %s %s %s, want store example.com/shop gin", req.ProjectName, req.ModuleName, req.Framework)
+			}
+			if want := []string{"postgres", "kafka"}; !reflect.DeepEqual(req.Libs, want) {
+				t.Errorf("libs = %v, want %v", req.Libs, want)
+			}
+			if req.IncludeExample || req.ConfigFormat != "yaml" {
+				t.Errorf("includeExample = %v, configFormat = %q; want false and yaml", req.IncludeExample, req.ConfigFormat)
+			}
+		})
+	}
+}
+
+func TestLoadRequestFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, file, content, want string
+	}{
+		{"unknown field", "request.json", `{"projectName": "shop", "framwork": "gin"}`, `unknown field "framwork"`},
+		{"unknown yaml field", "request.yml", "projectName: shop\nlib: [redis]\n", `unknown field "lib"`},
+		{"invalid yaml", "request.yaml", "projectName: [shop\n", "failed to parse request file"},
+		{"missing", "missing.json", "", "failed to read request file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if tt.content != "" {
+				writeFile(t, path, tt.content)
+			}
+			if _, err := loadRequestFile(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadRequestFile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_Directory(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{"-name", "demo", "-module", "example.com/demo", "-framework", "gin"}
+
+	// The project goes into ./<projectName> by default
+	if err := runIn(t, dir, args...); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "demo", "go.mod")); err != nil {
+		t.Errorf("go.mod not written: %v", err)
+	}
+
+	// A non-empty directory is only written into with -force
+	out := filepath.Join(dir, "out")
+	if err := os.MkdirAll(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(out, "keep.txt"), "keep")
+	if err := runIn(t, dir, append(args, "-o", "out")...); err == nil || !strings.Contains(err.Error(), "is not empty") {
+		t.Errorf("run() into a non-empty directory error = %v, want a -force hint", err)
+	}
+	if err := runIn(t, dir, append(args, "-o", "out", "-force")...); err != nil {
+		t.Fatalf("run() with -force error = %v", err)
+	}
+	for _, name := range []string{"keep.txt", "go.mod", "cmd/main.go"} {
+		if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s missing after -force: %v", name, err)
+		}
+	}
+}
+
+func TestRun_Archive(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{"-name", "demo", "-module", "example.com/demo", "-framework", "chi"}
+
+	if err := runIn(t, dir, append(args, "-archive", "demo.zip")...); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "demo.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Errorf("archive is not a ZIP file: %v", err)
+	}
+
+	// -stdout writes the same archive
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	err = runIn(t, dir, append(args, "-stdout")...)
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatalf("run() with -stdout error = %v", err)
+	}
+	written, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, data) {
+		t.Errorf("-stdout wrote %d bytes, want the %d of -archive", len(written), len(data))
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"invalid request", []string{"-name", "demo", "-framework", "gin"}, "invalid request"},
+		{"unknown framework", []string{"-name", "demo", "-module", "example.com/demo", "-framework", "rails"}, "framework not found"},
+		{"missing request file", []string{"-f", "missing.json"}, "failed to read request file"},
+		{"missing openapi document", []string{"-name", "demo", "-module", "example.com/demo", "-framework", "gin", "-openapi", "missing.yaml"}, "failed to read openapi document"},
+		{"missing manifest", []string{"-name", "demo", "-module", "example.com/demo", "-framework", "gin", "-manifest", "missing.json"}, "missing.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runIn(t, dir, tt.args...); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("run() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// writeArchiveFile writes the generated project as an archive to path and returns its size
+// and hex-encoded SHA-256
+func writeArchiveFile(result *service.GenerateResult, path, format string) (int64, string, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, "", err
+	}
+	h := sha256.New()
+	size, err := result.WriteArchive(io.MultiWriter(f, h), format)
+	if err != nil {
+		f.Close()
+		return size, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), f.Close()
+}
+
+// extractZip writes every entry of the generated archive into dir and returns
+// the number of files written
+func extractZip(data []byte, dir string, force bool) (int, error) {
+	if !force {
+		entries, err := os.ReadDir(dir)
+		if err == nil && len(entries) > 0 {
+			return 0, fmt.Errorf("output directory %s is not empty (use -force to overwrite)", dir)
+		}
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read generated archive: %w", err)
+	}
+
+	count := 0
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if !strings.HasPrefix(dst, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return count, fmt.Errorf("archive entry escapes output directory: %s", f.Name)
+		}
+
+		if err := writeZipEntry(f, dst); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// writeZipEntry copies a single archive entry to dst
+func writeZipEntry(f *zip.File, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), constants.DirPerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(dst), err)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open archive entry %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, rc); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", dst, err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/models"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// loadRequestFile reads a GenerateRequest from a JSON or YAML file.
+// YAML documents use the same field names as the JSON API (projectName, moduleName, ...).
+func loadRequestFile(path string) (*models.GenerateRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request file: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		if data, err = service.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to parse request file %s: %w", path, err)
+		}
+	}
+
+	// A misspelt field in a file fails rather than being ignored; the API stays lenient
+	var req models.GenerateRequest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return nil, fmt.Errorf("failed to parse request file %s: %w", path, err)
+	}
+	return &req, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// verify generates and type-checks a pairwise sample of the manifest's combinations and
+// reports the ones that fail
+func verify(opts *options) error {
+	if opts.root != "" {
+		if err := os.Chdir(opts.root); err != nil {
+			return fmt.Errorf("failed to enter root directory %s: %w", opts.root, err)
+		}
+	}
+
+	genService, err := newService(opts.manifestPath)
+	if err != nil {
+		return err
+	}
+
+	results := genService.VerifyCombinations(genService.PairwiseCombinations())
+	if failed := writeVerifyReport(os.Stdout, results); failed > 0 {
+		return fmt.Errorf("%d of %d combinations failed", failed, len(results))
+	}
+	fmt.Fprintf(os.Stderr, "Verified %d combinations\n", len(results))
+	return nil
+}
+
+// writeVerifyReport lists each failing combination with its errors and returns their number
+func writeVerifyReport(w io.Writer, results []service.CombinationResult) int {
+	failed := 0
+	for _, result := range results {
+		if !result.Failed() {
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL %s\n", result.Combination)
+		for _, e := range result.Errors {
+			fmt.Fprintf(w, "\t%s\n", e)
+		}
+	}
+	return failed
+}
@@ -15,14 +15,69 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/reload": {
+            "post": {
+                "description": "Reloads the manifest, the deps and config metadata and the templates. The new set replaces the current one only once it parses and validates; generations already running keep the set they started with. Requires the admin token as a bearer token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reload the manifest and templates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cADMIN_TOKEN\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.ReloadResult"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The admin API is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "The new manifest or templates are invalid; the current ones stay in use",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/generate": {
             "post": {
-                "description": "Generates a Go project scaffold based on the provided configuration and returns it as a ZIP archive.",
+                "description": "Generates a Go project scaffold based on the provided configuration and streams it as a ZIP, tar.gz or tar.zst archive. Archives are reproducible: entries are sorted, stored with fixed modification times and modes (shell scripts are executable), and include a SHA256SUMS file.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/zip"
+                    "application/zip",
+                    "application/gzip",
+                    "application/zstd"
                 ],
                 "tags": [
                     "generator"
@@ -37,6 +92,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/service.GenerateRequest"
                         }
+                    },
+                    {
+                        "enum": [
+                            "zip",
+                            "tar.gz",
+                            "tar.zst"
+                        ],
+                        "type": "string",
+                        "default": "zip",
+                        "description": "Archive format",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -44,6 +111,16 @@ const docTemplate = `{
                         "description": "Generated project archive",
                         "schema": {
                             "type": "file"
+                        },
+                        "headers": {
+                            "X-Content-SHA256": {
+                                "type": "string",
+                                "description": "Hex-encoded SHA-256 of the archive, sent as a trailer after the streamed body"
+                            },
+                            "X-Generator-Warnings": {
+                                "type": "string",
+                                "description": "JSON array of generation warnings (at most 50)"
+                            }
                         }
                     },
                     "400": {
@@ -63,6 +140,82 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Generation timed out or the server is busy",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds to wait before retrying when the server is busy"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/generate/stream": {
+            "post": {
+                "description": "Runs the same generation as /generate and streams its progress as Server-Sent Events. Every stage (parse, framework, middleware, each library, config, layers, app, main, deps, docs, go.mod, project files, format and archive) sends a start event and a finish event with its duration and the number of files it wrote. A done event then carries the download token: the archive is downloaded from GET /jobs/{token}/archive. A failed generation ends with an error event instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "generator"
+                ],
+                "summary": "Generate a project and stream its progress",
+                "parameters": [
+                    {
+                        "description": "Generator configuration",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.GenerateRequest"
+                        }
+                    },
+                    {
+                        "enum": [
+                            "zip",
+                            "tar.gz",
+                            "tar.zst"
+                        ],
+                        "type": "string",
+                        "default": "zip",
+                        "description": "Archive format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream; the data of the done event",
+                        "schema": {
+                            "$ref": "#/definitions/handler.StreamDone"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many jobs or the server is busy",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -76,38 +229,398 @@ const docTemplate = `{
                 "tags": [
                     "health"
                 ],
-                "summary": "Check service health",
+                "summary": "Check service health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.HealthResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs": {
+            "post": {
+                "description": "Accepts the same configuration as /generate and generates the project in the background. Poll GET /jobs/{id} for its status and download the result from GET /jobs/{id}/archive. Finished jobs are kept for a limited time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Start an asynchronous generation",
+                "parameters": [
+                    {
+                        "description": "Generator configuration",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.GenerateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/service.Job"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the job"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many jobs",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "Reports whether the job is queued, running (with the stage it is in), succeeded or failed (with its error).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get the status of a generation job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/archive": {
+            "get": {
+                "description": "Streams the project generated by a succeeded job as a ZIP, tar.gz or tar.zst archive, as /generate does.",
+                "produces": [
+                    "application/zip",
+                    "application/gzip",
+                    "application/zstd"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Download the project of a generation job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "zip",
+                            "tar.gz",
+                            "tar.zst"
+                        ],
+                        "type": "string",
+                        "default": "zip",
+                        "description": "Archive format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Generated project archive",
+                        "schema": {
+                            "type": "file"
+                        },
+                        "headers": {
+                            "X-Content-SHA256": {
+                                "type": "string",
+                                "description": "Hex-encoded SHA-256 of the archive, sent as a trailer after the streamed body"
+                            },
+                            "X-Generator-Warnings": {
+                                "type": "string",
+                                "description": "JSON array of generation warnings (at most 50)"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The job has not succeeded",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/manifest": {
+            "get": {
+                "description": "Returns the manifest describing available frameworks and libraries.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "manifest"
+                ],
+                "summary": "Get generator manifest",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Manifest"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/preview": {
+            "post": {
+                "description": "Runs the same generation as /generate and returns the generated files as JSON: path, size and SHA-256 of every file, with the contents of every file or, when path is given, of the requested files only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "generator"
+                ],
+                "summary": "Preview a generated project",
+                "parameters": [
+                    {
+                        "description": "Generator configuration",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.GenerateRequest"
+                        }
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Return contents only for these files",
+                        "name": "path",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.PreviewResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Generation timed out or the server is busy",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds to wait before retrying when the server is busy"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/preview/file": {
+            "get": {
+                "description": "Generates the project described by the query parameters and returns the rendered file at path. Entities, OpenAPI and SQL input, config overrides and lib options need POST /preview.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "generator"
+                ],
+                "summary": "Preview a single generated file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File path relative to the project root, e.g. cmd/main.go",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "projectName",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Go module path",
+                        "name": "moduleName",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Framework",
+                        "name": "framework",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project layout",
+                        "name": "architecture",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated list of libraries",
+                        "name": "libs",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include example code",
+                        "name": "includeExample",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Config file format",
+                        "name": "configFormat",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "File contents",
                         "schema": {
-                            "$ref": "#/definitions/handler.HealthResponse"
+                            "type": "string"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/manifest": {
-            "get": {
-                "description": "Returns the manifest describing available frameworks and libraries.",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "manifest"
-                ],
-                "summary": "Get generator manifest",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/models.Manifest"
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "405": {
@@ -121,6 +634,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Generation timed out or the server is busy",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds to wait before retrying when the server is busy"
+                            }
+                        }
                     }
                 }
             }
@@ -146,6 +671,135 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.StreamDone": {
+            "type": "object",
+            "properties": {
+                "durationMs": {
+                    "type": "number"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "token": {
+                    "description": "Token is the job holding the generated project; its archive is downloaded from URL",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Warning"
+                    }
+                }
+            }
+        },
+        "models.EntityDef": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldDef"
+                    }
+                },
+                "indexes": {
+                    "description": "Optional: composite indexes",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.IndexDef"
+                    }
+                },
+                "name": {
+                    "description": "Entity name, e.g. \"Product\" or \"order_item\"",
+                    "type": "string"
+                },
+                "table": {
+                    "description": "Optional: database table, defaults to the snake_case plural of name",
+                    "type": "string"
+                }
+            }
+        },
+        "models.FieldDef": {
+            "type": "object",
+            "properties": {
+                "autoIncrement": {
+                    "description": "Primary key values are generated by the database (int keys only)",
+                    "type": "boolean"
+                },
+                "column": {
+                    "description": "Optional: database column, defaults to the snake_case name",
+                    "type": "string"
+                },
+                "default": {
+                    "description": "Optional: SQL default expression, e.g. \"now()\" or \"'draft'\"",
+                    "type": "string"
+                },
+                "example": {
+                    "description": "Optional: example value for Swagger docs",
+                    "type": "string"
+                },
+                "indexed": {
+                    "description": "Non-unique index",
+                    "type": "boolean"
+                },
+                "name": {
+                    "description": "Field name, e.g. \"price\" or \"createdAt\"",
+                    "type": "string"
+                },
+                "nullable": {
+                    "description": "Nullable column, mapped to a pointer",
+                    "type": "boolean"
+                },
+                "onDelete": {
+                    "description": "Optional: CASCADE | SET NULL | SET DEFAULT | RESTRICT | NO ACTION",
+                    "type": "string"
+                },
+                "onUpdate": {
+                    "description": "Optional: same actions as onDelete",
+                    "type": "string"
+                },
+                "primaryKey": {
+                    "description": "Use this field as the primary key instead of the implicit id (int, uuid or string)",
+                    "type": "boolean"
+                },
+                "references": {
+                    "description": "Optional: name of the entity this field is a foreign key to",
+                    "type": "string"
+                },
+                "required": {
+                    "description": "NOT NULL column, required in create requests",
+                    "type": "boolean"
+                },
+                "sqlType": {
+                    "description": "Optional: column type, e.g. \"varchar(100)\" or \"timestamptz\"",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "string | int | bool | time | uuid | decimal | float | enum",
+                    "type": "string"
+                },
+                "unique": {
+                    "description": "Unique index",
+                    "type": "boolean"
+                },
+                "validate": {
+                    "description": "Optional: extra validator rules, e.g. \"email\" or \"min=2,max=100\"",
+                    "type": "string"
+                },
+                "values": {
+                    "description": "Allowed values (enum only)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "models.FrameworkDef": {
             "type": "object",
             "properties": {
@@ -163,7 +817,7 @@ const docTemplate = `{
                 "imports": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/models.ImportDef"
                     }
                 },
                 "templates": {
@@ -174,6 +828,43 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ImportDef": {
+            "type": "object",
+            "properties": {
+                "module": {
+                    "description": "module path, e.g. \"go.opentelemetry.io/otel/sdk\"; defaults to Path",
+                    "type": "string"
+                },
+                "path": {
+                    "description": "import path, e.g. \"go.opentelemetry.io/otel/sdk/trace\"",
+                    "type": "string"
+                },
+                "version": {
+                    "description": "module version, e.g. \"v1.28.0\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.IndexDef": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "description": "Field names, in index order",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "description": "Optional: index name, defaults to idx_\u003ctable\u003e_\u003ccolumns\u003e",
+                    "type": "string"
+                },
+                "unique": {
+                    "description": "Unique index",
+                    "type": "boolean"
+                }
+            }
+        },
         "models.LibDef": {
             "type": "object",
             "properties": {
@@ -184,24 +875,49 @@ const docTemplate = `{
                 "config_section": {
                     "type": "string"
                 },
+                "conflicts": {
+                    "description": "libs that cannot be selected along with this one",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "display_name": {
                     "description": "e.g., \"PostgreSQL\", \"Redis\"",
                     "type": "string"
                 },
+                "group": {
+                    "description": "at most one lib of a group can be selected, e.g. \"database\"",
+                    "type": "string"
+                },
                 "icon": {
                     "description": "e.g., \"🐘\", \"🔴\"",
                     "type": "string"
                 },
-                "imports": {
+                "implies": {
+                    "description": "libs added to the selection along with this one",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "imports": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportDef"
+                    }
+                },
                 "is_radio": {
                     "description": "true for radio (mutually exclusive), false for checkbox",
                     "type": "boolean"
                 },
+                "requires": {
+                    "description": "libs that must be selected along with this one, or the framework it needs",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "templates": {
                     "type": "array",
                     "items": {
@@ -227,12 +943,54 @@ const docTemplate = `{
                 }
             }
         },
+        "models.Warning": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "e.g. UNSUPPORTED_TYPE",
+                    "type": "string"
+                },
+                "column": {
+                    "description": "column or field the warning applies to",
+                    "type": "string"
+                },
+                "line": {
+                    "description": "line of the SQL input, when the warning comes from it",
+                    "type": "integer"
+                },
+                "message": {
+                    "description": "human readable explanation",
+                    "type": "string"
+                },
+                "table": {
+                    "description": "table or entity the warning applies to",
+                    "type": "string"
+                }
+            }
+        },
         "service.GenerateRequest": {
             "type": "object",
             "properties": {
                 "architecture": {
+                    "description": "Optional: project layout (clean | hexagonal | layered | flat | modular), defaults to clean",
+                    "type": "string"
+                },
+                "config": {
+                    "description": "Optional: config values merged over the framework and lib sections",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "configFormat": {
+                    "description": "Optional: config file format (json | yaml | toml | env), defaults to json",
                     "type": "string"
                 },
+                "entities": {
+                    "description": "Optional: entities to generate layers for, replaces the User example",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.EntityDef"
+                    }
+                },
                 "framework": {
                     "type": "string"
                 },
@@ -240,6 +998,14 @@ const docTemplate = `{
                     "description": "Optional: include example code (User entity, usecase, handler)",
                     "type": "boolean"
                 },
+                "libOptions": {
+                    "description": "Optional: options per lib, checked against the lib's options schema in the manifest",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "object",
+                        "additionalProperties": true
+                    }
+                },
                 "libs": {
                     "type": "array",
                     "items": {
@@ -249,8 +1015,135 @@ const docTemplate = `{
                 "moduleName": {
                     "type": "string"
                 },
+                "openapi": {
+                    "description": "Optional: OpenAPI 3.0/3.1 document (YAML or JSON) to generate DTOs, handlers and routes from",
+                    "type": "string"
+                },
+                "projectName": {
+                    "type": "string"
+                },
+                "sql": {
+                    "description": "Optional: CREATE TABLE statements (postgres or mysql dialect) to generate entities from",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Optional: modification time of every archive entry (RFC 3339), defaults to 1980-01-01T00:00:00Z",
+                    "type": "string"
+                }
+            }
+        },
+        "service.Job": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "$ref": "#/definitions/service.JobError"
+                },
+                "expiresAt": {
+                    "description": "ExpiresAt is when a finished job, and its archive, is removed",
+                    "type": "string"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "framework": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
                 "projectName": {
                     "type": "string"
+                },
+                "stage": {
+                    "type": "string"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Warning"
+                    }
+                }
+            }
+        },
+        "service.JobError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.PreviewFile": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "description": "file contents, only for requested files",
+                    "type": "string"
+                },
+                "path": {
+                    "description": "slash-separated path relative to the project root",
+                    "type": "string"
+                },
+                "sha256": {
+                    "description": "hex-encoded SHA-256 of the contents",
+                    "type": "string"
+                },
+                "size": {
+                    "description": "size in bytes",
+                    "type": "integer"
+                }
+            }
+        },
+        "service.PreviewResult": {
+            "type": "object",
+            "properties": {
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.PreviewFile"
+                    }
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Warning"
+                    }
+                }
+            }
+        },
+        "service.ReloadResult": {
+            "type": "object",
+            "properties": {
+                "durationMs": {
+                    "type": "number"
+                },
+                "frameworks": {
+                    "type": "integer"
+                },
+                "libs": {
+                    "type": "integer"
+                },
+                "reloaded": {
+                    "description": "Reloaded is false when nothing changed since the current snapshot was loaded",
+                    "type": "boolean"
+                },
+                "templates": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "string"
                 }
             }
         }
@@ -1,5 +1,7 @@
 package constants
 
+import "time"
+
 const (
 	// Default values
 	DefaultPort         = ":8080"
@@ -13,13 +15,24 @@ const (
 	// Content types
 	ContentTypeJSON = "application/json"
 	ContentTypeZip  = "application/zip"
+	ContentTypeGzip = "application/gzip"
+	ContentTypeZstd = "application/zstd"
+	ContentTypeText = "text/plain; charset=utf-8"
+	ContentTypeSSE  = "text/event-stream"
 
 	// Headers
 	HeaderContentType        = "Content-Type"
 	HeaderContentDisposition = "Content-Disposition"
+	HeaderTrailer            = "Trailer"
 	HeaderCacheControl       = "Cache-Control"
 	HeaderPragma             = "Pragma"
 	HeaderExpires            = "Expires"
+	HeaderWarnings           = "X-Generator-Warnings"
+	HeaderContentSHA256      = "X-Content-SHA256"
+	HeaderRetryAfter         = "Retry-After"
+	HeaderLocation           = "Location"
+	HeaderAuthorization      = "Authorization"
+	HeaderWWWAuthenticate    = "WWW-Authenticate"
 
 	// Cache control values
 	NoCache = "no-cache, no-store, must-revalidate"
@@ -29,8 +42,12 @@ const (
 	ErrInvalidRequestBody  = "Invalid request body"
 	ErrValidationFailed    = "Validation error"
 	ErrGenerationFailed    = "Failed to generate project"
+	ErrGenerationCancelled = "Project generation was cancelled"
+	ErrGenerationTimeout   = "Project generation timed out"
 	ErrEncodingFailed      = "Failed to encode response"
 	ErrInternalServerError = "Internal server error"
+	ErrUnauthorized        = "Unauthorized"
+	ErrAdminDisabled       = "Admin API is disabled"
 
 	// Validation patterns
 	ProjectNamePattern = `^[a-z0-9-]+$`
@@ -42,19 +59,103 @@ const (
 	MinModuleNameLength  = 3
 	MaxModuleNameLength  = 200
 
+	// Entity schema constraints
+	EntityNamePattern = `^[A-Za-z][A-Za-z0-9_]*$`
+	EnumValuePattern  = `^[A-Za-z0-9_-]+$`
+	SQLTypePattern    = `^[A-Za-z][A-Za-z0-9 _,()\[\]]*$`
+	MaxEntities       = 50
+	MaxEntityFields   = 100
+
+	// Entity field types
+	FieldTypeString  = "string"
+	FieldTypeInt     = "int"
+	FieldTypeBool    = "bool"
+	FieldTypeTime    = "time"
+	FieldTypeUUID    = "uuid"
+	FieldTypeDecimal = "decimal"
+	FieldTypeFloat   = "float"
+	FieldTypeEnum    = "enum"
+
+	// OpenAPI input constraints
+	MaxOpenAPISize       = 1 << 20
+	MaxOpenAPIOperations = 500
+	APIModuleName        = "api"
+
+	// SQL DDL input constraints
+	MaxSQLSize = 1 << 20
+
+	// Warning codes returned with a generated project
+	WarnUnsupportedStatement  = "UNSUPPORTED_STATEMENT"
+	WarnUnsupportedType       = "UNSUPPORTED_TYPE"
+	WarnUnsupportedConstraint = "UNSUPPORTED_CONSTRAINT"
+	WarnSkippedTable          = "SKIPPED_TABLE"
+	WarnSkippedColumn         = "SKIPPED_COLUMN"
+	WarnSkippedRelation       = "SKIPPED_RELATION"
+	WarnImpliedLib            = "IMPLIED_LIB"
+	WarnUnsupportedMediaType  = "UNSUPPORTED_MEDIA_TYPE"
+	WarnUnsupportedSchema     = "UNSUPPORTED_SCHEMA"
+	MaxHeaderWarnings         = 50
+
+	// Generation deadline; it stays below the server's 15s WriteTimeout so that a timed out
+	// generation can still be reported to the client
+	DefaultGenerationTimeout = 10 * time.Second
+
+	// ProtocGenGo is the protoc plugin that compiles the Go stubs of gRPC projects, looked
+	// up in PATH unless PROTOC_GEN_GO names another executable
+	ProtocGenGo = "protoc-gen-go"
+
+	// Generation concurrency: the number of generations running at once defaults to the
+	// number of CPUs; further requests wait in a bounded queue, for at most
+	// DefaultGenerationQueueWait, and are turned away with 503 and Retry-After once it is full
+	DefaultGenerationQueue     = 64
+	DefaultGenerationQueueWait = 3 * time.Second
+	GenerationRetryAfter       = 2 * time.Second
+	ErrServerBusy              = "Server is busy, please try again later"
+
+	// Asynchronous generation jobs: finished jobs and their archives are kept for
+	// DefaultJobTTL, and at most DefaultMaxJobs are kept at a time
+	DefaultJobTTL     = 15 * time.Minute
+	DefaultJobTimeout = 5 * time.Minute
+	DefaultMaxJobs    = 100
+
+	// Reloading the manifest, metadata and templates: changes are watched, or polled every
+	// DefaultReloadInterval, and reloaded once no change came for ReloadDebounce
+	ReloadModeWatch       = "watch"
+	ReloadModePoll        = "poll"
+	ReloadModeOff         = "off"
+	DefaultReloadInterval = 2 * time.Second
+	ReloadDebounce        = 250 * time.Millisecond
+
+	// StatusClientClosedRequest is the non-standard status (used by nginx) of a request whose
+	// client went away before the response was written
+	StatusClientClosedRequest = 499
+
 	// Service constants
 	TempDirPrefix         = "gen-"
 	DirPerm               = 0755
+	FilePerm              = 0644
+	ExecPerm              = 0755
+	ScriptExtension       = ".sh"
 	TemplateExtension     = ".tmpl"
+	ConfigSectionExt      = ".json"
 	GoFileExtension       = ".go"
-	ConfigFileName        = "config.json"
+	ConfigFileBase        = "config" // config/<base>.<format>
 	GoModFileName         = "go.mod"
 	ReadmeFileName        = "README.md"
 	GitignoreFileName     = ".gitignore"
 	EnvExampleFileName    = ".env.example"
 	DockerfileName        = "Dockerfile"
+	MakefileName          = "Makefile"
+	BufFileName           = "buf.yaml"
+	BufGenFileName        = "buf.gen.yaml"
+	ChecksumsFileName     = "SHA256SUMS"
 	ModuleNamePlaceholder = "{{.ModuleName}}"
 
+	// Archive formats of a generated project
+	ArchiveFormatZip    = "zip"
+	ArchiveFormatTarGz  = "tar.gz"
+	ArchiveFormatTarZst = "tar.zst"
+
 	// Directory paths
 	DirCmd                = "cmd"
 	DirDocs               = "docs"
@@ -63,14 +164,20 @@ const (
 	DirInternalDeps       = "internal/deps"
 	DirInternalMiddleware = "internal/middleware"
 	DirConfig             = "config"
-	DirInternalDomain     = "internal/domain"
-	DirInternalErrors     = "internal/errors"
-	DirInternalUsecase    = "internal/usecase"
-	DirInternalRepo       = "internal/infrastructure/repository"
-	DirInternalRepoModels = "internal/infrastructure/repository/models"
-	DirInternalHandler    = "internal/adapter/handler"
-	DirInternalJob        = "internal/adapter/job"
-	DirInternalConsumer   = "internal/adapter/consumer"
+	DirProto              = "proto"
+	DirGen                = "gen"
+
+	// Architecture layers (directories are defined per architecture in the manifest)
+	DefaultArchitecture   = "clean"
+	ArchModulePlaceholder = "{module}"
+	LayerDomain           = "domain"
+	LayerErrors           = "errors"
+	LayerUsecase          = "usecase"
+	LayerRepository       = "repository"
+	LayerModels           = "models"
+	LayerHandler          = "handler"
+	LayerJob              = "job"
+	LayerConsumer         = "consumer"
 
 	// Template paths
 	TemplateDir              = "templates"
@@ -78,19 +185,26 @@ const (
 	TemplateDepsMeta         = "templates/deps/deps_meta.json"
 	TemplateConfigMeta       = "templates/deps/config_meta.json"
 	TemplateDockerfile       = "templates/Dockerfile.tmpl"
+	TemplateMakefile         = "templates/Makefile.tmpl"
 	TemplateGitignore        = "templates/gitignore.tmpl"
 	TemplateEnvExample       = "templates/env_example.tmpl"
 	TemplateReadme           = "templates/README.tmpl"
 	TemplateMain             = "templates/cmd/main.tmpl"
 	TemplateDocs             = "templates/docs/swagger.tmpl"
+	TemplateDocsOpenAPI      = "templates/docs/openapi.tmpl"
 	TemplateGoMod            = "templates/go_mod.tmpl"
 	TemplateDomainEntity     = "templates/domain/entity.tmpl"
+	TemplateDomainCache      = "templates/domain/cache.tmpl"
 	TemplateErrors           = "templates/errors/errors.tmpl"
-	TemplateUserModel        = "templates/infrastructure/models/user_model.tmpl"
-	TemplateUserRepo         = "templates/infrastructure/repository/user_repository.tmpl"
+	TemplateEntityModel      = "templates/infrastructure/models/entity_model.tmpl"
+	TemplateEntityRepo       = "templates/infrastructure/repository/entity_repository.tmpl"
 	TemplateCacheRepo        = "templates/infrastructure/repository/cache_repository.tmpl"
-	TemplateUserUsecase      = "templates/usecase/user_usecase.tmpl"
-	TemplateUserHandler      = "templates/handler/user_handler.tmpl"
+	TemplateEntityUsecase    = "templates/usecase/entity_usecase.tmpl"
+	TemplateEntityHandler    = "templates/handler/entity_handler.tmpl"
+	TemplateHandlerResponse  = "templates/handler/response.tmpl"
+	TemplateAPITypes         = "templates/api/types.tmpl"
+	TemplateAPIHandler       = "templates/api/handler.tmpl"
+	TemplateAPIOperations    = "templates/api/operations.tmpl"
 	TemplateExampleJob       = "templates/adapter/job/example_job.tmpl"
 	TemplateRabbitMQConsumer = "templates/adapter/consumer/rabbitmq_consumer.tmpl"
 	TemplateKafkaConsumer    = "templates/adapter/consumer/kafka_consumer.tmpl"
@@ -101,15 +215,61 @@ const (
 	TemplateRoutes           = "templates/app/routes.tmpl"
 	TemplateBootstrapSample  = "templates/app/bootstrap_sample.tmpl"
 	TemplateBootstrap        = "templates/app/bootstrap.tmpl"
+	TemplateGateway          = "templates/app/gateway.tmpl"
+	TemplateProto            = "templates/proto/service.proto.tmpl"
+	TemplateProtoGRPC        = "templates/proto/service_grpc.pb.go.tmpl"
+	TemplateBuf              = "templates/proto/buf.yaml.tmpl"
+	TemplateBufGen           = "templates/proto/buf.gen.yaml.tmpl"
 	TemplateDeps             = "templates/deps/deps.tmpl"
 	TemplateConfig           = "templates/deps/config.tmpl"
 
-	// Common dependencies
-	DepLogrus     = "github.com/sirupsen/logrus"
-	DepViper      = "github.com/spf13/viper"
-	DepGoogleUUID = "github.com/google/uuid"
+	// Config file formats of generated projects
+	ConfigFormatJSON = "json"
+	ConfigFormatYAML = "yaml"
+	ConfigFormatTOML = "toml"
+	ConfigFormatEnv  = "env"
+
+	// Lib option types
+	OptionTypeString     = "string"
+	OptionTypeInt        = "int"
+	OptionTypeBool       = "bool"
+	OptionTypeStringList = "string_list"
+	OptionTypeDuration   = "duration"
+
+	// gRPC framework and its HTTP facade
+	FrameworkGRPC  = "grpc"
+	LibGRPCGateway = "grpcgateway"
+
+	// Standard library based HTTP frameworks
+	FrameworkNetHTTP = "nethttp"
+	FrameworkChi     = "chi"
+
+	// Common dependencies and their pinned versions
+	DepLogrus              = "github.com/sirupsen/logrus"
+	DepLogrusVersion       = "v1.9.3"
+	DepViper               = "github.com/spf13/viper"
+	DepViperVersion        = "v1.19.0"
+	DepGoogleUUID          = "github.com/google/uuid"
+	DepGoogleUUIDVersion   = "v1.6.0"
+	DepMapstructure        = "github.com/mitchellh/mapstructure"
+	DepMapstructureVersion = "v1.5.0"
+	DepSwag                = "github.com/swaggo/swag"
+	DepSwagVersion         = "v1.16.3"
+	DepDecimal             = "github.com/shopspring/decimal"
+	DepDecimalVersion      = "v1.4.0"
 
 	// Default values for generated projects
 	DefaultGoVersion = "1.20"
-	DefaultPortNum   = 8080
+	// go directive of generated go.mod files; ServeMux method and wildcard
+	// patterns used by the nethttp framework need 1.22
+	GoModVersion         = "1.21"
+	GoModVersionServeMux = "1.22"
+	DefaultPortNum       = 8080
+	DefaultGRPCPort      = 9090
+)
+
+// Range of archive entry modification times; ZIP stores MS-DOS times, which cover 1980-2107
+var (
+	MinArchiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	MaxArchiveTime = time.Date(2108, 1, 1, 0, 0, 0, 0, time.UTC)
 )
@@ -57,6 +57,10 @@ const (
 	ErrCodeConfig     = "CONFIG_ERROR"
 	ErrCodeTemplate   = "TEMPLATE_ERROR"
 	ErrCodeFileSystem = "FILESYSTEM_ERROR"
+	ErrCodeCancelled  = "CANCELLED"
+	ErrCodeTimeout    = "TIMEOUT"
+	ErrCodeBusy       = "SERVER_BUSY"
+	ErrCodeConflict   = "CONFLICT"
 )
 
 // Common error constructors
@@ -87,3 +91,19 @@ func ErrTemplate(message string, internalErr error) *AppError {
 func ErrFileSystem(message string, internalErr error) *AppError {
 	return NewAppError(ErrCodeFileSystem, message, internalErr)
 }
+
+func ErrCancelled(message string, internalErr error) *AppError {
+	return NewAppError(ErrCodeCancelled, message, internalErr)
+}
+
+func ErrTimeout(message string, internalErr error) *AppError {
+	return NewAppError(ErrCodeTimeout, message, internalErr)
+}
+
+func ErrBusy(message string, internalErr error) *AppError {
+	return NewAppError(ErrCodeBusy, message, internalErr)
+}
+
+func ErrConflict(message string) *AppError {
+	return NewAppError(ErrCodeConflict, message, nil)
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/middleware"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// AdminHandler serves the admin API, authenticated with a bearer token
+type AdminHandler struct {
+	*BaseHandler
+	service *service.GeneratorService
+	token   string
+}
+
+// NewAdminHandler creates an admin handler; an empty token disables the admin API
+func NewAdminHandler(svc *service.GeneratorService, token string, logger *logrus.Logger) *AdminHandler {
+	return &AdminHandler{
+		BaseHandler: NewBaseHandler(logger),
+		service:     svc,
+		token:       token,
+	}
+}
+
+// HandleReload godoc
+// @Summary Reload the manifest and templates
+// @Description Reloads the manifest, the deps and config metadata and the templates. The new set replaces the current one only once it parses and validates; generations already running keep the set they started with. Requires the admin token as a bearer token.
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer <ADMIN_TOKEN>"
+// @Success 200 {object} service.ReloadResult
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "The admin API is disabled"
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse "The new manifest or templates are invalid; the current ones stay in use"
+// @Router /admin/reload [post]
+func (h *AdminHandler) HandleReload(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(w)
+
+	if !h.validateMethod(r, constants.MethodPOST) {
+		h.writeErrorWithID(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed, requestID)
+		return
+	}
+	if !h.authorize(w, r, requestID) {
+		return
+	}
+
+	result, err := h.service.Reload()
+	h.ReportReload(middleware.ReloadAdmin, result, err)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			h.handleAppError(w, r, appErr)
+		} else {
+			h.handleAppError(w, r, errors.ErrConfig("Failed to reload manifest", err))
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// authorize checks the bearer token of a request, writing the error response when it is
+// missing or wrong
+func (h *AdminHandler) authorize(w http.ResponseWriter, r *http.Request, requestID string) bool {
+	if h.token == "" {
+		h.writeErrorWithID(w, constants.ErrAdminDisabled, http.StatusForbidden, requestID)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get(constants.HeaderAuthorization), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		h.logger.WithFields(logrus.Fields{
+			"request_id":  requestID,
+			"remote_addr": r.RemoteAddr,
+		}).Warn("Unauthorized admin request")
+		w.Header().Set(constants.HeaderWWWAuthenticate, "Bearer")
+		h.writeErrorWithID(w, constants.ErrUnauthorized, http.StatusUnauthorized, requestID)
+		return false
+	}
+	return true
+}
+
+// ReportReload logs a reload of the manifest and templates, whatever triggered it, and
+// counts it in the metrics
+func (h *AdminHandler) ReportReload(trigger string, result *service.ReloadResult, err error) {
+	middleware.RecordReload(trigger, err)
+	if err != nil {
+		h.logger.WithError(err).WithField("trigger", trigger).
+			Error("Failed to reload manifest and templates; keeping the current ones")
+		return
+	}
+	h.logger.WithFields(logrus.Fields{
+		"trigger":     trigger,
+		"version":     result.Version,
+		"frameworks":  result.Frameworks,
+		"libs":        result.Libs,
+		"templates":   result.Templates,
+		"duration_ms": result.DurationMs,
+	}).Info("Manifest and templates reloaded")
+}
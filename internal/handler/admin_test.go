@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+func TestHandleReload(t *testing.T) {
+	svc := testService(t)
+	h := NewAdminHandler(svc, "secret", testLogger())
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		status        int
+	}{
+		{"method", http.MethodGet, "Bearer secret", http.StatusMethodNotAllowed},
+		{"no token", http.MethodPost, "", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "Bearer wrong", http.StatusUnauthorized},
+		{"not bearer", http.MethodPost, "Basic secret", http.StatusUnauthorized},
+		{"token prefix", http.MethodPost, "Bearer secre", http.StatusUnauthorized},
+		{"valid", http.MethodPost, "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/reload", nil)
+			if tt.authorization != "" {
+				req.Header.Set(constants.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.HandleReload(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get(constants.HeaderWWWAuthenticate) != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", rec.Header().Get(constants.HeaderWWWAuthenticate))
+			}
+			if rec.Code == http.StatusOK {
+				var result service.ReloadResult
+				if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result.Templates == 0 {
+					t.Errorf("reload result = %s (error %v)", rec.Body, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleReload_Disabled(t *testing.T) {
+	h := NewAdminHandler(testService(t), "", testLogger())
+
+	// Without a token the admin API refuses every request, even one with an empty bearer
+	req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+	req.Header.Set(constants.HeaderAuthorization, "Bearer ")
+	rec := httptest.NewRecorder()
+	h.HandleReload(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -66,6 +66,12 @@ func (h *BaseHandler) handleAppError(w http.ResponseWriter, r *http.Request, app
 		statusCode = http.StatusNotFound
 	case errors.ErrCodeGeneration, errors.ErrCodeConfig, errors.ErrCodeTemplate, errors.ErrCodeFileSystem:
 		statusCode = http.StatusInternalServerError
+	case errors.ErrCodeCancelled:
+		statusCode = constants.StatusClientClosedRequest
+	case errors.ErrCodeTimeout, errors.ErrCodeBusy:
+		statusCode = http.StatusServiceUnavailable
+	case errors.ErrCodeConflict:
+		statusCode = http.StatusConflict
 	}
 
 	// Log based on severity
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"io"
 	"net/http"
 	"time"
 
@@ -9,32 +13,41 @@ import (
 	"github.com/xhkzeroone/go-generator/internal/constants"
 	"github.com/xhkzeroone/go-generator/internal/errors"
 	"github.com/xhkzeroone/go-generator/internal/middleware"
+	"github.com/xhkzeroone/go-generator/internal/models"
 	"github.com/xhkzeroone/go-generator/internal/service"
 )
 
 type GenerateHandler struct {
 	*BaseHandler
 	service *service.GeneratorService
+	limiter *middleware.GenerationLimiter
 }
 
-func NewGenerateHandler(svc *service.GeneratorService, logger *logrus.Logger) *GenerateHandler {
+// NewGenerateHandler creates a generate handler. Generations run on the workers of limiter.
+func NewGenerateHandler(svc *service.GeneratorService, limiter *middleware.GenerationLimiter, logger *logrus.Logger) *GenerateHandler {
 	return &GenerateHandler{
 		BaseHandler: NewBaseHandler(logger),
 		service:     svc,
+		limiter:     limiter,
 	}
 }
 
 // HandleGenerate godoc
 // @Summary Generate a Go project scaffold
-// @Description Generates a Go project scaffold based on the provided configuration and returns it as a ZIP archive.
+// @Description Generates a Go project scaffold based on the provided configuration and streams it as a ZIP, tar.gz or tar.zst archive. Archives are reproducible: entries are sorted, stored with fixed modification times and modes (shell scripts are executable), and include a SHA256SUMS file.
 // @Tags generator
 // @Accept json
-// @Produce application/zip
+// @Produce application/zip,application/gzip,application/zstd
 // @Param request body service.GenerateRequest true "Generator configuration"
+// @Param format query string false "Archive format" Enums(zip, tar.gz, tar.zst) default(zip)
 // @Success 200 {file} file "Generated project archive"
+// @Header 200 {string} X-Generator-Warnings "JSON array of generation warnings (at most 50)"
+// @Header 200 {string} X-Content-SHA256 "Hex-encoded SHA-256 of the archive, sent as a trailer after the streamed body"
 // @Failure 400 {object} ErrorResponse
 // @Failure 405 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Generation timed out or the server is busy"
+// @Header 503 {string} Retry-After "Seconds to wait before retrying when the server is busy"
 // @Router /generate [post]
 func (h *GenerateHandler) HandleGenerate(w http.ResponseWriter, r *http.Request) {
 	requestID := middleware.GetRequestID(w)
@@ -44,55 +57,41 @@ func (h *GenerateHandler) HandleGenerate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var req service.GenerateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.WithFields(logrus.Fields{
-			"request_id": requestID,
-			"error":      err,
-		}).Warn("Invalid request body")
-		h.writeErrorWithID(w, constants.ErrInvalidRequestBody, http.StatusBadRequest, requestID)
+	req, ok := h.decodeRequest(w, r)
+	if !ok {
 		return
 	}
 
-	if err := req.Validate(); err != nil {
-		appErr := errors.ErrValidation(err.Error(), err).WithContext("project_name", req.ProjectName).
-			WithContext("module_name", req.ModuleName).WithContext("framework", req.Framework)
-		h.handleAppError(w, r, appErr)
+	format := r.URL.Query().Get("format")
+	if err := service.ValidateArchiveFormat(format); err != nil {
+		h.handleAppError(w, r, err.(*errors.AppError))
 		return
 	}
 
-	// Get trace context
-	traceCtx := middleware.GetTraceContext(r.Context())
-
 	// Start span for project generation
 	ctx, spanID, finishSpan := middleware.StartSpan(r.Context(), "generate_project")
 	defer finishSpan()
 
-	logFields := logrus.Fields{
-		"request_id":      requestID,
-		"project_name":    req.ProjectName,
-		"module_name":     req.ModuleName,
-		"framework":       req.Framework,
-		"libs":            req.Libs,
-		"include_example": req.IncludeExample,
-	}
-	if traceCtx.TraceID != "" {
-		logFields["trace_id"] = traceCtx.TraceID
-	}
-	if spanID != "" {
-		logFields["span_id"] = spanID
+	// The worker is held while the project is generated; the archive is then built as it
+	// is streamed, so a slow client does not hold the worker
+	release, ok := h.limiter.AcquireRequest(w, r)
+	if !ok {
+		return
 	}
+
+	logFields := generationLogFields(ctx, requestID, req, spanID)
+	logFields["format"] = format
 	h.logger.WithFields(logFields).Info("Generating project")
 
 	// Record generation start time
 	startTime := time.Now()
 
-	zipData, err := h.service.GenerateProject(&req)
-
-	// Record metrics
+	result, err := h.service.GenerateProject(ctx, req)
 	duration := time.Since(startTime)
-	middleware.RecordProjectGeneration(req.Framework, duration, int64(len(zipData)), err == nil)
+	release()
 	if err != nil {
+		middleware.RecordProjectGeneration(req.Framework, duration, 0, generationStatus(err))
+
 		// Check if it's already an AppError
 		if appErr, ok := err.(*errors.AppError); ok {
 			appErr.WithContext("request_id", requestID).
@@ -111,31 +110,141 @@ func (h *GenerateHandler) HandleGenerate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	w.Header().Set(constants.HeaderContentType, constants.ContentTypeZip)
-	w.Header().Set(constants.HeaderContentDisposition, "attachment; filename="+req.ProjectName+".zip")
+	digest, size, err := h.writeArchive(w, requestID, req.ProjectName, result, format)
+	status := middleware.GenerationSuccess
+	if err != nil {
+		// A write fails once the client has gone away
+		status = middleware.GenerationError
+		if ctx.Err() != nil {
+			status = middleware.GenerationCancelled
+		}
+	}
+	middleware.RecordProjectGeneration(req.Framework, duration, size, status)
+	if err != nil {
+		return
+	}
+
+	logFields = generationLogFields(ctx, requestID, req, spanID)
+	for k, v := range generationResultFields(format, size, digest, result, duration) {
+		logFields[k] = v
+	}
+	h.logger.WithFields(logFields).Info("Project generated successfully")
+}
+
+// decodeRequest decodes and validates the generate request in the body, writing the error
+// response when it is invalid
+func (h *GenerateHandler) decodeRequest(w http.ResponseWriter, r *http.Request) (*service.GenerateRequest, bool) {
+	requestID := middleware.GetRequestID(w)
+
+	var req service.GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Warn("Invalid request body")
+		h.writeErrorWithID(w, constants.ErrInvalidRequestBody, http.StatusBadRequest, requestID)
+		return nil, false
+	}
+
+	if err := req.Validate(); err != nil {
+		appErr := errors.ErrValidation(err.Error(), err).WithContext("project_name", req.ProjectName).
+			WithContext("module_name", req.ModuleName).WithContext("framework", req.Framework)
+		h.handleAppError(w, r, appErr)
+		return nil, false
+	}
+	return &req, true
+}
+
+// writeArchive streams the archive of a generated project as the response, with its file
+// name and warnings in the headers. The archive is built as it is written: its SHA-256 is
+// sent in the X-Content-SHA256 trailer. It returns the digest and the number of bytes
+// written; the response cannot report a failure once it has started.
+func (h *GenerateHandler) writeArchive(w http.ResponseWriter, requestID, projectName string, result *service.GenerateResult, format string) (string, int64, error) {
+	h.writeWarnings(w, requestID, result.Warnings)
+
+	w.Header().Set(constants.HeaderContentType, service.ArchiveContentType(format))
+	w.Header().Set(constants.HeaderContentDisposition, "attachment; filename="+service.ArchiveFileName(projectName, format))
+	w.Header().Set(constants.HeaderTrailer, constants.HeaderContentSHA256)
 	w.WriteHeader(http.StatusOK)
 
-	if _, err := w.Write(zipData); err != nil {
+	hash := sha256.New()
+	size, err := result.WriteArchive(io.MultiWriter(w, hash), format)
+	if err != nil {
 		h.logger.WithFields(logrus.Fields{
 			"request_id": requestID,
 			"error":      err,
-		}).Error("Error writing zip data")
-		return
+		}).Error("Error writing project archive")
+		return "", size, err
 	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+	w.Header().Set(constants.HeaderContentSHA256, digest)
+	return digest, size, nil
+}
 
-	logFields = logrus.Fields{
-		"request_id":   requestID,
-		"project_name": req.ProjectName,
-		"size_bytes":   len(zipData),
-		"duration_ms":  duration.Milliseconds(),
+// generationLogFields returns the log fields describing a generation request, with the
+// trace and span of ctx
+func generationLogFields(ctx context.Context, requestID string, req *service.GenerateRequest, spanID string) logrus.Fields {
+	fields := logrus.Fields{
+		"request_id":      requestID,
+		"project_name":    req.ProjectName,
+		"module_name":     req.ModuleName,
+		"framework":       req.Framework,
+		"libs":            req.Libs,
+		"include_example": req.IncludeExample,
 	}
-	if traceCtx.TraceID != "" {
-		logFields["trace_id"] = traceCtx.TraceID
+	if traceID := middleware.GetTraceID(ctx); traceID != "" {
+		fields["trace_id"] = traceID
 	}
 	if spanID != "" {
-		logFields["span_id"] = spanID
+		fields["span_id"] = spanID
 	}
-	h.logger.WithFields(logFields).Info("Project generated successfully")
+	return fields
+}
+
+// generationResultFields returns the log fields describing a generated project
+func generationResultFields(format string, size int64, digest string, result *service.GenerateResult, duration time.Duration) logrus.Fields {
+	return logrus.Fields{
+		"format":      format,
+		"size_bytes":  size,
+		"sha256":      digest,
+		"warnings":    len(result.Warnings),
+		"duration_ms": duration.Milliseconds(),
+	}
+}
+
+// generationStatus returns the project_generation_total status of a failed generation
+func generationStatus(err error) string {
+	if appErr, ok := err.(*errors.AppError); ok {
+		switch appErr.Code {
+		case errors.ErrCodeCancelled, errors.ErrCodeTimeout:
+			return middleware.GenerationCancelled
+		}
+	}
+	return middleware.GenerationError
+}
 
-	_ = ctx // Use context for future enhancements
+// writeWarnings logs the generation warnings and reports them in the X-Generator-Warnings header
+// as a JSON array, truncated to the first MaxHeaderWarnings entries
+func (h *GenerateHandler) writeWarnings(w http.ResponseWriter, requestID string, warnings []models.Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+	for _, warning := range warnings {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"code":       warning.Code,
+			"table":      warning.Table,
+			"column":     warning.Column,
+			"line":       warning.Line,
+		}).Warn(warning.Message)
+	}
+
+	if len(warnings) > constants.MaxHeaderWarnings {
+		warnings = warnings[:constants.MaxHeaderWarnings]
+	}
+	header, err := json.Marshal(warnings)
+	if err != nil {
+		return
+	}
+	w.Header().Set(constants.HeaderWarnings, string(header))
 }
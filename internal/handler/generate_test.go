@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/middleware"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// testRequest is a generate request of a small project
+const testRequest = `{"projectName": "demo", "moduleName": "example.com/demo", "framework": "gin", "libs": ["redis"]}`
+
+// TestMain runs the tests from the repository root, where the manifest and the templates are
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func testService(tb testing.TB) *service.GeneratorService {
+	tb.Helper()
+	s, err := service.NewGeneratorService(constants.DefaultManifestPath)
+	if err != nil {
+		tb.Fatalf("NewGeneratorService() error = %v", err)
+	}
+	return s
+}
+
+func testLimiter() *middleware.GenerationLimiter {
+	return middleware.NewGenerationLimiter(2, 2, time.Second, testLogger())
+}
+
+func TestHandleGenerate(t *testing.T) {
+	h := NewGenerateHandler(testService(t), testLimiter(), testLogger())
+	server := httptest.NewServer(http.HandlerFunc(h.HandleGenerate))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"?format=zip", constants.ContentTypeJSON, strings.NewReader(testRequest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// The archive is streamed: its length is unknown up front and its digest follows the body
+	if resp.ContentLength != -1 || len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("Content-Length = %d, Transfer-Encoding = %v; want a chunked body", resp.ContentLength, resp.TransferEncoding)
+	}
+	if _, ok := resp.Trailer[http.CanonicalHeaderKey(constants.HeaderContentSHA256)]; !ok {
+		t.Errorf("trailers = %v, want %s declared", resp.Trailer, constants.HeaderContentSHA256)
+	}
+	if got := resp.Header.Get(constants.HeaderContentSHA256); got != "" {
+		t.Errorf("X-Content-SHA256 header = %q, want it only as a trailer", got)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.Trailer.Get(constants.HeaderContentSHA256), fmt.Sprintf("%x", sha256.Sum256(body)); got != want {
+		t.Errorf("X-Content-SHA256 trailer = %q, want %q", got, want)
+	}
+
+	if got := resp.Header.Get(constants.HeaderContentType); got != constants.ContentTypeZip {
+		t.Errorf("Content-Type = %q, want %q", got, constants.ContentTypeZip)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("body is not a ZIP archive: %v", err)
+	}
+	found := false
+	for _, f := range zr.File {
+		found = found || f.Name == "go.mod"
+	}
+	if !found {
+		t.Error("archive has no go.mod")
+	}
+}
+
+func TestHandleGenerate_Errors(t *testing.T) {
+	h := NewGenerateHandler(testService(t), testLimiter(), testLogger())
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"method", http.MethodGet, "/generate", "", http.StatusMethodNotAllowed},
+		{"malformed body", http.MethodPost, "/generate", "{", http.StatusBadRequest},
+		{"invalid request", http.MethodPost, "/generate", `{"projectName": "demo"}`, http.StatusBadRequest},
+		{"format", http.MethodPost, "/generate?format=rar", testRequest, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandleGenerate(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
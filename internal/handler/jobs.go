@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/middleware"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// JobHandler runs generations in the background and serves their status and archives
+type JobHandler struct {
+	*GenerateHandler
+	store   *service.JobStore
+	timeout time.Duration
+
+	// ctx is cancelled by Stop, aborting the running jobs
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewJobHandler creates a job handler. Jobs run on the workers of limiter, each for at most
+// timeout (0 for no limit).
+func NewJobHandler(svc *service.GeneratorService, store *service.JobStore, limiter *middleware.GenerationLimiter, timeout time.Duration, logger *logrus.Logger) *JobHandler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobHandler{
+		GenerateHandler: NewGenerateHandler(svc, limiter, logger),
+		store:           store,
+		timeout:         timeout,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// Stop aborts the running jobs
+func (h *JobHandler) Stop() {
+	h.cancel()
+}
+
+// HandleCreateJob godoc
+// @Summary Start an asynchronous generation
+// @Description Accepts the same configuration as /generate and generates the project in the background. Poll GET /jobs/{id} for its status and download the result from GET /jobs/{id}/archive. Finished jobs are kept for a limited time.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param request body service.GenerateRequest true "Generator configuration"
+// @Success 202 {object} service.Job
+// @Header 202 {string} Location "URL of the job"
+// @Failure 400 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Too many jobs"
+// @Router /jobs [post]
+func (h *JobHandler) HandleCreateJob(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(w)
+
+	if !h.validateMethod(r, constants.MethodPOST) {
+		h.writeErrorWithID(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed, requestID)
+		return
+	}
+
+	req, ok := h.decodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.store.Create(req)
+	if err != nil {
+		h.handleAppError(w, r, err.(*errors.AppError))
+		return
+	}
+
+	// The job outlives the request but keeps its request ID and trace
+	logFields := generationLogFields(r.Context(), requestID, req, "")
+	logFields["job_id"] = job.ID
+	h.logger.WithFields(logFields).Info("Generation job created")
+	go h.runJob(job.ID, req, logFields)
+
+	w.Header().Set(constants.HeaderLocation, "/jobs/"+job.ID)
+	h.writeJSON(w, http.StatusAccepted, job)
+}
+
+// runJob waits for a generation worker and generates the project of a job
+func (h *JobHandler) runJob(id string, req *service.GenerateRequest, logFields logrus.Fields) {
+	release, err := h.limiter.AcquireJob(h.ctx)
+	if err != nil {
+		appErr := errors.ErrCancelled(constants.ErrGenerationCancelled, err)
+		middleware.RecordProjectGeneration(req.Framework, 0, 0, generationStatus(appErr))
+		h.failJob(id, appErr, logFields)
+		return
+	}
+	defer release()
+
+	ctx := service.WithProgress(h.ctx, func(event service.ProgressEvent) {
+		if event.Type == service.ProgressStart {
+			h.store.SetStage(id, event.Stage)
+		}
+	})
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	} else {
+		// JOB_TIMEOUT=0 leaves the job unbounded, not bound by the generation timeout
+		ctx = service.WithoutGenerationTimeout(ctx)
+	}
+
+	h.store.Start(id)
+	h.logger.WithFields(logFields).Info("Generating project")
+
+	startTime := time.Now()
+	result, err := h.service.GenerateProject(ctx, req)
+	duration := time.Since(startTime)
+	if err != nil {
+		middleware.RecordProjectGeneration(req.Framework, duration, 0, generationStatus(err))
+		h.failJob(id, err, logFields)
+		return
+	}
+
+	// Hash the default archive to report its size and digest like /generate does; the
+	// download streams it again
+	digest, size, err := result.ArchiveSHA256("")
+	if err != nil {
+		middleware.RecordProjectGeneration(req.Framework, duration, 0, middleware.GenerationError)
+		h.failJob(id, errors.ErrGeneration(constants.ErrGenerationFailed, err), logFields)
+		return
+	}
+	middleware.RecordProjectGeneration(req.Framework, duration, size, middleware.GenerationSuccess)
+	h.store.Succeed(id, result)
+
+	fields := logrus.Fields{}
+	for k, v := range logFields {
+		fields[k] = v
+	}
+	for k, v := range generationResultFields(constants.ArchiveFormatZip, size, digest, result, duration) {
+		fields[k] = v
+	}
+	h.logger.WithFields(fields).Info("Project generated successfully")
+}
+
+// failJob records the error of a job and logs it
+func (h *JobHandler) failJob(id string, err error, logFields logrus.Fields) {
+	h.store.Fail(id, err)
+
+	entry := h.logger.WithFields(logFields).WithField("error", err.Error())
+	if appErr, ok := err.(*errors.AppError); ok {
+		entry = entry.WithField("error_code", appErr.Code)
+		for k, v := range appErr.Context {
+			entry = entry.WithField(k, v)
+		}
+	}
+	entry.Error("Generation job failed")
+}
+
+// HandleGetJob godoc
+// @Summary Get the status of a generation job
+// @Description Reports whether the job is queued, running (with the stage it is in), succeeded or failed (with its error).
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} service.Job
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Router /jobs/{id} [get]
+func (h *JobHandler) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+	h.writeJSON(w, http.StatusOK, job)
+}
+
+// HandleJobArchive godoc
+// @Summary Download the project of a generation job
+// @Description Streams the project generated by a succeeded job as a ZIP, tar.gz or tar.zst archive, as /generate does.
+// @Tags jobs
+// @Produce application/zip,application/gzip,application/zstd
+// @Param id path string true "Job ID"
+// @Param format query string false "Archive format" Enums(zip, tar.gz, tar.zst) default(zip)
+// @Success 200 {file} file "Generated project archive"
+// @Header 200 {string} X-Generator-Warnings "JSON array of generation warnings (at most 50)"
+// @Header 200 {string} X-Content-SHA256 "Hex-encoded SHA-256 of the archive, sent as a trailer after the streamed body"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "The job has not succeeded"
+// @Failure 500 {object} ErrorResponse
+// @Router /jobs/{id}/archive [get]
+func (h *JobHandler) HandleJobArchive(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.job(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if err := service.ValidateArchiveFormat(format); err != nil {
+		h.handleAppError(w, r, err.(*errors.AppError))
+		return
+	}
+	if job.Status != service.JobSucceeded {
+		h.handleAppError(w, r, errors.ErrConflict("Job has not succeeded").
+			WithContext("job_id", job.ID).WithContext("status", job.Status))
+		return
+	}
+
+	_, _, _ = h.writeArchive(w, middleware.GetRequestID(w), job.ProjectName, job.Result, format)
+}
+
+// job looks up the job named by the path of a GET request, writing the error response when
+// there is none
+func (h *JobHandler) job(w http.ResponseWriter, r *http.Request) (*service.Job, bool) {
+	if !h.validateMethod(r, constants.MethodGET) {
+		h.writeError(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	id := r.PathValue("id")
+	job, ok := h.store.Get(id)
+	if !ok {
+		h.handleAppError(w, r, errors.ErrNotFound("job").WithContext("job_id", id))
+		return nil, false
+	}
+	return job, true
+}
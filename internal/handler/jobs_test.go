@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// jobServer serves the job routes of h as the server does
+func jobServer(t *testing.T, h *JobHandler) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", h.HandleCreateJob)
+	mux.HandleFunc("/jobs/{id}", h.HandleGetJob)
+	mux.HandleFunc("/jobs/{id}/archive", h.HandleJobArchive)
+	mux.HandleFunc("/generate/stream", h.HandleGenerateStream)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newJobHandler returns a job handler keeping finished jobs for ttl
+func newJobHandler(t *testing.T, ttl time.Duration) (*JobHandler, *service.JobStore) {
+	t.Helper()
+	store := service.NewJobStore(ttl, 10)
+	h := NewJobHandler(testService(t), store, testLimiter(), 0, testLogger())
+	t.Cleanup(func() {
+		h.Stop()
+		store.Stop()
+	})
+	return h, store
+}
+
+// getJob fetches the status of a job
+func getJob(t *testing.T, url string) (int, service.Job) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var job service.Job
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, job
+}
+
+func TestJobLifecycle(t *testing.T) {
+	h, _ := newJobHandler(t, time.Minute)
+	server := jobServer(t, h)
+
+	resp, err := http.Post(server.URL+"/jobs", constants.ContentTypeJSON, strings.NewReader(testRequest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job service.Job
+	err = json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get(constants.HeaderLocation) != "/jobs/"+job.ID {
+		t.Fatalf("status = %d, Location = %q; want 202 and /jobs/%s", resp.StatusCode, resp.Header.Get(constants.HeaderLocation), job.ID)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status != service.JobSucceeded {
+		if job.Status == service.JobFailed || time.Now().After(deadline) {
+			t.Fatalf("job status = %s, error = %v", job.Status, job.Error)
+		}
+		time.Sleep(10 * time.Millisecond)
+		_, job = getJob(t, server.URL+"/jobs/"+job.ID)
+	}
+
+	resp, err = http.Get(server.URL + "/jobs/" + job.ID + "/archive?format=tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(body) == 0 {
+		t.Fatalf("archive status = %d with %d bytes", resp.StatusCode, len(body))
+	}
+	if resp.Trailer.Get(constants.HeaderContentSHA256) == "" {
+		t.Errorf("archive has no %s trailer", constants.HeaderContentSHA256)
+	}
+}
+
+func TestJobArchive_Errors(t *testing.T) {
+	h, store := newJobHandler(t, 500*time.Millisecond)
+	server := jobServer(t, h)
+
+	pending, err := store.Create(&service.GenerateRequest{ProjectName: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, err := store.Create(&service.GenerateRequest{ProjectName: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Fail(failed.ID, errors.ErrGeneration("boom", nil))
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"unfinished", "/jobs/" + pending.ID + "/archive", http.StatusConflict},
+		{"failed", "/jobs/" + failed.ID + "/archive", http.StatusConflict},
+		{"format", "/jobs/" + pending.ID + "/archive?format=rar", http.StatusBadRequest},
+		{"unknown", "/jobs/missing/archive", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+
+	// A finished job is gone once its TTL has passed; an unfinished one is kept
+	status, job := getJob(t, server.URL+"/jobs/"+failed.ID)
+	if status != http.StatusOK || job.ExpiresAt == nil {
+		t.Fatalf("failed job status = %d, expiresAt = %v", status, job.ExpiresAt)
+	}
+	time.Sleep(time.Until(*job.ExpiresAt) + 10*time.Millisecond)
+	for _, target := range []string{"/jobs/" + failed.ID, "/jobs/" + failed.ID + "/archive"} {
+		if status, _ := getJob(t, server.URL+target); status != http.StatusNotFound {
+			t.Errorf("%s after expiry status = %d, want 404", target, status)
+		}
+	}
+	if status, _ := getJob(t, server.URL+"/jobs/"+pending.ID); status != http.StatusOK {
+		t.Errorf("unfinished job status = %d, want 200", status)
+	}
+}
@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/middleware"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+type PreviewHandler struct {
+	*GenerateHandler
+}
+
+// NewPreviewHandler creates a preview handler. Previews run on the workers of limiter, like
+// generations.
+func NewPreviewHandler(svc *service.GeneratorService, limiter *middleware.GenerationLimiter, logger *logrus.Logger) *PreviewHandler {
+	return &PreviewHandler{GenerateHandler: NewGenerateHandler(svc, limiter, logger)}
+}
+
+// HandlePreview godoc
+// @Summary Preview a generated project
+// @Description Runs the same generation as /generate and returns the generated files as JSON: path, size and SHA-256 of every file, with the contents of every file or, when path is given, of the requested files only.
+// @Tags generator
+// @Accept json
+// @Produce json
+// @Param request body service.GenerateRequest true "Generator configuration"
+// @Param path query []string false "Return contents only for these files" collectionFormat(multi)
+// @Success 200 {object} service.PreviewResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Generation timed out or the server is busy"
+// @Header 503 {string} Retry-After "Seconds to wait before retrying when the server is busy"
+// @Router /preview [post]
+func (h *PreviewHandler) HandlePreview(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(w)
+
+	if !h.validateMethod(r, constants.MethodPOST) {
+		h.writeErrorWithID(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed, requestID)
+		return
+	}
+
+	var req service.GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Warn("Invalid request body")
+		h.writeErrorWithID(w, constants.ErrInvalidRequestBody, http.StatusBadRequest, requestID)
+		return
+	}
+
+	if !h.validateRequest(w, r, &req) {
+		return
+	}
+
+	paths := r.URL.Query()["path"]
+	h.logPreview(requestID, &req, paths)
+
+	release, ok := h.limiter.AcquireRequest(w, r)
+	if !ok {
+		return
+	}
+	startTime := time.Now()
+	result, err := h.service.PreviewProject(r.Context(), &req, paths)
+	release()
+	if err != nil {
+		h.handleServiceError(w, r, &req, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id":  requestID,
+		"files":       len(result.Files),
+		"warnings":    len(result.Warnings),
+		"duration_ms": time.Since(startTime).Milliseconds(),
+	}).Info("Project previewed successfully")
+
+	h.writeWarnings(w, requestID, result.Warnings)
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// HandlePreviewFile godoc
+// @Summary Preview a single generated file
+// @Description Generates the project described by the query parameters and returns the rendered file at path. Entities, OpenAPI and SQL input, config overrides and lib options need POST /preview.
+// @Tags generator
+// @Produce plain
+// @Param path query string true "File path relative to the project root, e.g. cmd/main.go"
+// @Param projectName query string true "Project name"
+// @Param moduleName query string true "Go module path"
+// @Param framework query string true "Framework"
+// @Param architecture query string false "Project layout"
+// @Param libs query string false "Comma-separated list of libraries"
+// @Param includeExample query bool false "Include example code"
+// @Param configFormat query string false "Config file format"
+// @Success 200 {string} string "File contents"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Generation timed out or the server is busy"
+// @Header 503 {string} Retry-After "Seconds to wait before retrying when the server is busy"
+// @Router /preview/file [get]
+func (h *PreviewHandler) HandlePreviewFile(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(w)
+
+	if !h.validateMethod(r, constants.MethodGET) {
+		h.writeErrorWithID(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed, requestID)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		h.handleAppError(w, r, errors.ErrValidation("path is required", nil))
+		return
+	}
+
+	req, err := requestFromQuery(query)
+	if err != nil {
+		h.handleAppError(w, r, errors.ErrValidation(err.Error(), err))
+		return
+	}
+	if !h.validateRequest(w, r, req) {
+		return
+	}
+
+	h.logPreview(requestID, req, []string{path})
+
+	release, ok := h.limiter.AcquireRequest(w, r)
+	if !ok {
+		return
+	}
+	file, warnings, err := h.service.PreviewProjectFile(r.Context(), req, path)
+	release()
+	if err != nil {
+		h.handleServiceError(w, r, req, err)
+		return
+	}
+
+	h.writeWarnings(w, requestID, warnings)
+	w.Header().Set(constants.HeaderContentType, constants.ContentTypeText)
+	w.Header().Set(constants.HeaderContentSHA256, file.SHA256)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(file.Content)); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Error("Error writing preview file")
+	}
+}
+
+// validateRequest validates a generate request, writing the error response when it is invalid
+func (h *PreviewHandler) validateRequest(w http.ResponseWriter, r *http.Request, req *service.GenerateRequest) bool {
+	if err := req.Validate(); err != nil {
+		appErr := errors.ErrValidation(err.Error(), err).WithContext("project_name", req.ProjectName).
+			WithContext("module_name", req.ModuleName).WithContext("framework", req.Framework)
+		h.handleAppError(w, r, appErr)
+		return false
+	}
+	return true
+}
+
+// handleServiceError writes the error response of a failed preview
+func (h *PreviewHandler) handleServiceError(w http.ResponseWriter, r *http.Request, req *service.GenerateRequest, err error) {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.ErrGeneration(constants.ErrGenerationFailed, err)
+	}
+	appErr.WithContext("request_id", middleware.GetRequestID(w)).
+		WithContext("project_name", req.ProjectName).
+		WithContext("module_name", req.ModuleName)
+	h.handleAppError(w, r, appErr)
+}
+
+func (h *PreviewHandler) logPreview(requestID string, req *service.GenerateRequest, paths []string) {
+	h.logger.WithFields(logrus.Fields{
+		"request_id":   requestID,
+		"project_name": req.ProjectName,
+		"framework":    req.Framework,
+		"libs":         req.Libs,
+		"paths":        paths,
+	}).Info("Previewing project")
+}
+
+// requestFromQuery builds a generate request from the query parameters of /preview/file.
+// libs may be repeated or comma-separated.
+func requestFromQuery(query url.Values) (*service.GenerateRequest, error) {
+	req := &service.GenerateRequest{
+		ProjectName:  query.Get("projectName"),
+		ModuleName:   query.Get("moduleName"),
+		Framework:    query.Get("framework"),
+		Architecture: query.Get("architecture"),
+		ConfigFormat: query.Get("configFormat"),
+	}
+	for _, value := range query["libs"] {
+		for _, lib := range strings.Split(value, ",") {
+			if lib = strings.TrimSpace(lib); lib != "" {
+				req.Libs = append(req.Libs, lib)
+			}
+		}
+	}
+	if value := query.Get("includeExample"); value != "" {
+		includeExample, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("includeExample must be true or false")
+		}
+		req.IncludeExample = includeExample
+	}
+	return req, nil
+}
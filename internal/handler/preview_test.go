@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+func TestRequestFromQuery(t *testing.T) {
+	query, err := url.ParseQuery("projectName=demo&moduleName=example.com/demo&framework=gin&architecture=flat" +
+		"&configFormat=yaml&libs=redis,%20postgres&libs=kafka&libs=&includeExample=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := requestFromQuery(query)
+	if err != nil {
+		t.Fatalf("requestFromQuery() error = %v", err)
+	}
+	want := &service.GenerateRequest{
+		ProjectName:    "demo",
+		ModuleName:     "example.com/demo",
+		Framework:      "gin",
+		Architecture:   "flat",
+		ConfigFormat:   "yaml",
+		Libs:           []string{"redis", "postgres", "kafka"},
+		IncludeExample: true,
+	}
+	if !reflect.DeepEqual(req, want) {
+		t.Errorf("requestFromQuery() = %+v, want %+v", req, want)
+	}
+
+	if _, err := requestFromQuery(url.Values{"includeExample": {"maybe"}}); err == nil {
+		t.Error("requestFromQuery() accepted includeExample=maybe")
+	}
+}
+
+func TestHandlePreviewFile(t *testing.T) {
+	h := NewPreviewHandler(testService(t), testLimiter(), testLogger())
+	query := "projectName=demo&moduleName=example.com/demo&framework=gin&libs=redis"
+
+	rec := httptest.NewRecorder()
+	h.HandlePreviewFile(rec, httptest.NewRequest(http.MethodGet, "/preview/file?path=go.mod&"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "module example.com/demo\n") || !strings.Contains(body, "github.com/redis/go-redis") {
+		t.Errorf("go.mod = %q, want the module and its redis requirement", body)
+	}
+	if got, want := rec.Header().Get(constants.HeaderContentSHA256), fmt.Sprintf("%x", sha256.Sum256(rec.Body.Bytes())); got != want {
+		t.Errorf("X-Content-SHA256 = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"no path", "/preview/file?" + query, http.StatusBadRequest},
+		{"invalid flag", "/preview/file?path=go.mod&includeExample=maybe&" + query, http.StatusBadRequest},
+		{"invalid request", "/preview/file?path=go.mod&projectName=demo", http.StatusBadRequest},
+		{"missing file", "/preview/file?path=missing.go&" + query, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandlePreviewFile(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandlePreview(t *testing.T) {
+	h := NewPreviewHandler(testService(t), testLimiter(), testLogger())
+
+	rec := httptest.NewRecorder()
+	h.HandlePreview(rec, httptest.NewRequest(http.MethodPost, "/preview?path=go.mod", strings.NewReader(testRequest)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var result service.PreviewResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	// Every file is listed, but only the requested one carries its contents
+	for _, file := range result.Files {
+		if (file.Content != "") != (file.Path == "go.mod") {
+			t.Errorf("%s content = %d bytes", file.Path, len(file.Content))
+		}
+	}
+	if len(result.Files) < 10 {
+		t.Errorf("preview lists %d files", len(result.Files))
+	}
+}
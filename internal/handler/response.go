@@ -1,7 +1,20 @@
 package handler
 
+import "github.com/xhkzeroone/go-generator/internal/models"
+
 // ErrorResponse represents a standard error payload returned by the API.
 type ErrorResponse struct {
 	Error     string `json:"error"`
 	RequestID string `json:"request_id,omitempty"`
 }
+
+// StreamDone is the data of the done event of /generate/stream
+type StreamDone struct {
+	// Token is the job holding the generated project; its archive is downloaded from URL
+	Token      string           `json:"token"`
+	URL        string           `json:"url"`
+	SHA256     string           `json:"sha256"`
+	Size       int64            `json:"size"`
+	DurationMs float64          `json:"durationMs"`
+	Warnings   []models.Warning `json:"warnings,omitempty"`
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/middleware"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// Server-Sent Events of /generate/stream
+const (
+	eventStart  = service.ProgressStart  // a stage started
+	eventFinish = service.ProgressFinish // a stage finished
+	eventDone   = "done"                 // the project is ready to download
+	eventError  = "error"                // the generation failed
+	stageZip    = "archive"              // the stage building the archive, after the generation
+)
+
+// HandleGenerateStream godoc
+// @Summary Generate a project and stream its progress
+// @Description Runs the same generation as /generate and streams its progress as Server-Sent Events. Every stage (parse, framework, middleware, each library, config, layers, app, main, deps, docs, go.mod, project files, format and archive) sends a start event and a finish event with its duration and the number of files it wrote. A done event then carries the download token: the archive is downloaded from GET /jobs/{token}/archive. A failed generation ends with an error event instead.
+// @Tags generator
+// @Accept json
+// @Produce text/event-stream
+// @Param request body service.GenerateRequest true "Generator configuration"
+// @Param format query string false "Archive format" Enums(zip, tar.gz, tar.zst) default(zip)
+// @Success 200 {object} StreamDone "Event stream; the data of the done event"
+// @Failure 400 {object} ErrorResponse
+// @Failure 405 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Too many jobs or the server is busy"
+// @Router /generate/stream [post]
+func (h *JobHandler) HandleGenerateStream(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(w)
+
+	if !h.validateMethod(r, constants.MethodPOST) {
+		h.writeErrorWithID(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed, requestID)
+		return
+	}
+
+	req, ok := h.decodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if err := service.ValidateArchiveFormat(format); err != nil {
+		h.handleAppError(w, r, err.(*errors.AppError))
+		return
+	}
+
+	// The server's write timeout would cut the event stream off; the generation keeps its
+	// own deadline
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err,
+		}).Warn("Cannot clear the write deadline of the event stream")
+	}
+
+	// The worker is held until the archive is built; the events are written meanwhile
+	release, ok := h.limiter.AcquireRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// The generated project is kept as a job, whose ID is the download token
+	job, err := h.store.Create(req)
+	if err != nil {
+		release()
+		h.handleAppError(w, r, err.(*errors.AppError))
+		return
+	}
+
+	ctx, spanID, finishSpan := middleware.StartSpan(r.Context(), "generate_project_stream")
+	defer finishSpan()
+
+	logFields := generationLogFields(ctx, requestID, req, spanID)
+	logFields["format"] = format
+	logFields["job_id"] = job.ID
+	h.logger.WithFields(logFields).Info("Generating project")
+
+	w.Header().Set(constants.HeaderContentType, constants.ContentTypeSSE)
+	w.Header().Set(constants.HeaderCacheControl, constants.NoCache)
+	w.WriteHeader(http.StatusOK)
+	send := func(event string, data interface{}) {
+		if err := writeEvent(w, event, data); err != nil {
+			return
+		}
+		_ = rc.Flush()
+	}
+
+	ctx = service.WithProgress(ctx, func(event service.ProgressEvent) {
+		if event.Type == service.ProgressStart {
+			h.store.SetStage(job.ID, event.Stage)
+		}
+		send(event.Type, event)
+	})
+	h.store.Start(job.ID)
+
+	startTime := time.Now()
+	result, err := h.service.GenerateProject(ctx, req)
+	duration := time.Since(startTime)
+	if err != nil {
+		release()
+		middleware.RecordProjectGeneration(req.Framework, duration, 0, generationStatus(err))
+		h.failJob(job.ID, err, logFields)
+		send(eventError, service.NewJobError(err))
+		return
+	}
+
+	// The archive is hashed here to report its size and digest; the download streams it
+	// again
+	files := result.FileCount()
+	send(eventStart, service.ProgressEvent{Type: eventStart, Stage: stageZip, TotalFiles: files})
+	archiveStart := time.Now()
+	digest, size, err := result.ArchiveSHA256(format)
+	release()
+	if err != nil {
+		appErr := errors.ErrGeneration(constants.ErrGenerationFailed, err)
+		middleware.RecordProjectGeneration(req.Framework, duration, 0, middleware.GenerationError)
+		h.failJob(job.ID, appErr, logFields)
+		send(eventError, service.NewJobError(appErr))
+		return
+	}
+	send(eventFinish, service.ProgressEvent{
+		Type:       eventFinish,
+		Stage:      stageZip,
+		DurationMs: float64(time.Since(archiveStart).Microseconds()) / 1000,
+		TotalFiles: files,
+	})
+	duration = time.Since(startTime)
+
+	middleware.RecordProjectGeneration(req.Framework, duration, size, middleware.GenerationSuccess)
+	h.store.Succeed(job.ID, result)
+
+	fields := logrus.Fields{}
+	for k, v := range logFields {
+		fields[k] = v
+	}
+	for k, v := range generationResultFields(format, size, digest, result, duration) {
+		fields[k] = v
+	}
+	h.logger.WithFields(fields).Info("Project generated successfully")
+
+	downloadURL := "/jobs/" + job.ID + "/archive"
+	if format != "" {
+		downloadURL += "?format=" + url.QueryEscape(format)
+	}
+	send(eventDone, StreamDone{
+		Token:      job.ID,
+		URL:        downloadURL,
+		SHA256:     digest,
+		Size:       size,
+		DurationMs: float64(duration.Microseconds()) / 1000,
+		Warnings:   result.Warnings,
+	})
+}
+
+// writeEvent writes a Server-Sent Event with JSON data
+func writeEvent(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
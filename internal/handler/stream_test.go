@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/service"
+)
+
+// sseEvent is an event read from an event stream
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readEvents reads the events of an event stream until it ends
+func readEvents(t *testing.T, r io.Reader) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var event sseEvent
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, event)
+			event = sseEvent{}
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		default:
+			t.Fatalf("unexpected event stream line %q", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+// postStream posts a request to /generate/stream and returns its events
+func postStream(t *testing.T, url, body string) []sseEvent {
+	t.Helper()
+	resp, err := http.Post(url, constants.ContentTypeJSON, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(constants.HeaderContentType) != constants.ContentTypeSSE {
+		t.Fatalf("status = %d, Content-Type = %q; want 200 and %s", resp.StatusCode, resp.Header.Get(constants.HeaderContentType), constants.ContentTypeSSE)
+	}
+	return readEvents(t, resp.Body)
+}
+
+func TestHandleGenerateStream(t *testing.T) {
+	h, _ := newJobHandler(t, time.Minute)
+	server := jobServer(t, h)
+
+	events := postStream(t, server.URL+"/generate/stream?format=tar.gz", testRequest)
+	if len(events) < 3 {
+		t.Fatalf("got %d events", len(events))
+	}
+
+	// Every stage starts and then finishes before the next one starts; the archive is the
+	// last stage and done ends the stream
+	var stages []string
+	for i, event := range events[:len(events)-1] {
+		var progress service.ProgressEvent
+		if err := json.Unmarshal([]byte(event.data), &progress); err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		want := eventStart
+		if i%2 == 1 {
+			want = eventFinish
+			if progress.Stage != stages[len(stages)-1] {
+				t.Errorf("event %d finishes %q, want %q", i, progress.Stage, stages[len(stages)-1])
+			}
+		} else {
+			stages = append(stages, progress.Stage)
+		}
+		if event.name != want || progress.Type != want {
+			t.Errorf("event %d = %s (%s), want %s", i, event.name, progress.Type, want)
+		}
+	}
+	if stages[0] != "parse" || stages[len(stages)-1] != stageZip {
+		t.Errorf("stages = %v, want parse first and %s last", stages, stageZip)
+	}
+
+	last := events[len(events)-1]
+	if last.name != eventDone {
+		t.Fatalf("last event = %s: %s, want %s", last.name, last.data, eventDone)
+	}
+	var done StreamDone
+	if err := json.Unmarshal([]byte(last.data), &done); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/jobs/" + done.Token + "/archive?format=tar.gz"; done.Token == "" || done.URL != want {
+		t.Errorf("done url = %q with token %q, want %q", done.URL, done.Token, want)
+	}
+
+	// The token downloads the archive the done event describes
+	resp, err := http.Get(server.URL + done.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("download status = %d", resp.StatusCode)
+	}
+	if done.Size != int64(len(body)) || done.SHA256 != fmt.Sprintf("%x", sha256.Sum256(body)) {
+		t.Errorf("done = %d bytes with sha256 %s; the archive has %d bytes with sha256 %x", done.Size, done.SHA256, len(body), sha256.Sum256(body))
+	}
+}
+
+func TestHandleGenerateStream_Error(t *testing.T) {
+	h, _ := newJobHandler(t, time.Minute)
+	server := jobServer(t, h)
+
+	// The lib rules are checked by the generation, after the stream has started
+	body := `{"projectName": "demo", "moduleName": "example.com/demo", "framework": "gin", "libs": ["mysql", "postgres"]}`
+	events := postStream(t, server.URL+"/generate/stream", body)
+	last := events[len(events)-1]
+	if last.name != eventError {
+		t.Fatalf("last event = %s: %s, want %s", last.name, last.data, eventError)
+	}
+	var jobErr service.JobError
+	if err := json.Unmarshal([]byte(last.data), &jobErr); err != nil {
+		t.Fatal(err)
+	}
+	if jobErr.Code != errors.ErrCodeValidation || !strings.Contains(jobErr.Message, "only one database lib") {
+		t.Errorf("error event = %+v, want the lib rule VALIDATION_ERROR", jobErr)
+	}
+
+	// Requests that are invalid up front get a plain error response
+	resp, err := http.Post(server.URL+"/generate/stream?format=rar", constants.ContentTypeJSON, strings.NewReader(testRequest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status with an unknown format = %d, want 400", resp.StatusCode)
+	}
+}
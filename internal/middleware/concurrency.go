@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xhkzeroone/go-generator/internal/constants"
+)
+
+var (
+	// ErrQueueFull is returned by Acquire when every worker is busy and the wait queue is full
+	ErrQueueFull = errors.New("generation queue is full")
+	// ErrQueueTimeout is returned by Acquire when no worker became free within the queue wait
+	ErrQueueTimeout = errors.New("timed out waiting for a generation worker")
+)
+
+// GenerationLimiter bounds the number of generations running at once. Requests beyond the
+// limit wait in a bounded queue; once it is full, or after waiting too long, they are
+// turned away.
+type GenerationLimiter struct {
+	workers    chan struct{} // a token per running generation
+	queue      chan struct{} // a token per waiting request
+	maxWait    time.Duration // longest wait in the queue, 0 for no limit
+	retryAfter time.Duration
+	logger     *logrus.Logger
+}
+
+// NewGenerationLimiter creates a limiter running at most workers generations at once, with
+// at most queueSize requests waiting up to maxWait (0 for no limit) for a worker
+func NewGenerationLimiter(workers, queueSize int, maxWait time.Duration, logger *logrus.Logger) *GenerationLimiter {
+	return &GenerationLimiter{
+		workers:    make(chan struct{}, max(workers, 1)),
+		queue:      make(chan struct{}, max(queueSize, 0)),
+		maxWait:    maxWait,
+		retryAfter: constants.GenerationRetryAfter,
+		logger:     logger,
+	}
+}
+
+// Acquire waits for a free worker and returns the function that frees it again. It fails
+// with ErrQueueFull, ErrQueueTimeout or the error of ctx.
+func (l *GenerationLimiter) Acquire(ctx context.Context) (func(), error) {
+	// Take a free worker straight away only when nobody is queued for one
+	if len(l.queue) == 0 {
+		select {
+		case l.workers <- struct{}{}:
+			return l.started(), nil
+		default:
+		}
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return nil, ErrQueueFull
+	}
+	projectGenerationQueued.Inc()
+	defer func() {
+		<-l.queue
+		projectGenerationQueued.Dec()
+	}()
+
+	var timeout <-chan time.Time
+	if l.maxWait > 0 {
+		timer := time.NewTimer(l.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case l.workers <- struct{}{}:
+		return l.started(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timeout:
+		return nil, ErrQueueTimeout
+	}
+}
+
+// AcquireJob waits for a free worker for a background job and returns the function that
+// frees it again. Jobs are not bound by the queue size or the queue wait: they wait until a
+// worker is free or ctx is done, and fail only with the error of ctx.
+func (l *GenerationLimiter) AcquireJob(ctx context.Context) (func(), error) {
+	select {
+	case l.workers <- struct{}{}:
+		return l.started(), nil
+	default:
+	}
+
+	projectGenerationQueued.Inc()
+	defer projectGenerationQueued.Dec()
+	select {
+	case l.workers <- struct{}{}:
+		return l.started(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// started records a running generation and returns the function that ends it
+func (l *GenerationLimiter) started() func() {
+	projectGenerationInFlight.Inc()
+	return func() {
+		projectGenerationInFlight.Dec()
+		<-l.workers
+	}
+}
+
+// AcquireRequest waits for a free worker for an HTTP request and returns the function that
+// frees it again. Handlers hold the worker while they generate, not while they write the
+// response. Requests that find the queue full or wait too long get 503 Service Unavailable
+// with a Retry-After header; once it fails, the response has been written.
+func (l *GenerationLimiter) AcquireRequest(w http.ResponseWriter, r *http.Request) (func(), bool) {
+	release, err := l.Acquire(r.Context())
+	if err == nil {
+		return release, true
+	}
+
+	if r.Context().Err() != nil {
+		// The client went away while queued; nobody reads the response
+		w.WriteHeader(constants.StatusClientClosedRequest)
+		return nil, false
+	}
+
+	l.logger.WithFields(logrus.Fields{
+		"request_id": GetRequestID(w),
+		"client_ip":  getClientIP(r),
+		"path":       r.URL.Path,
+		"error":      err,
+	}).Warn("Generation rejected")
+
+	w.Header().Set(constants.HeaderContentType, constants.ContentTypeJSON)
+	w.Header().Set(constants.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(l.retryAfter.Seconds()))))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":      constants.ErrServerBusy,
+		"request_id": GetRequestID(w),
+	})
+	return nil, false
+}
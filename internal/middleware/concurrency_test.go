@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestGenerationLimiterAcquire(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	l := NewGenerationLimiter(1, 1, 50*time.Millisecond, logger)
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// The second request queues until the first releases its worker
+	acquired := make(chan error)
+	go func() {
+		release, err := l.Acquire(context.Background())
+		if err == nil {
+			defer release()
+		}
+		acquired <- err
+	}()
+	for len(l.queue) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := l.Acquire(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Acquire() with a full queue error = %v, want %v", err, ErrQueueFull)
+	}
+	release()
+	if err := <-acquired; err != nil {
+		t.Errorf("queued Acquire() error = %v", err)
+	}
+
+	release, err = l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer release()
+	if _, err := l.Acquire(context.Background()); !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("Acquire() past the queue wait error = %v, want %v", err, ErrQueueTimeout)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+	if len(l.queue) != 0 {
+		t.Errorf("%d requests left in the queue", len(l.queue))
+	}
+}
+
+func TestGenerationLimiterAcquireJob(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	l := NewGenerationLimiter(1, 0, time.Millisecond, logger)
+
+	release, err := l.AcquireJob(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireJob() error = %v", err)
+	}
+
+	// A job waits past the queue size and the queue wait until the worker is released
+	acquired := make(chan error)
+	go func() {
+		release, err := l.AcquireJob(context.Background())
+		if err == nil {
+			defer release()
+		}
+		acquired <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case err := <-acquired:
+		t.Fatalf("AcquireJob() returned %v with every worker busy", err)
+	default:
+	}
+	release()
+	if err := <-acquired; err != nil {
+		t.Errorf("waiting AcquireJob() error = %v", err)
+	}
+
+	release, err = l.AcquireJob(context.Background())
+	if err != nil {
+		t.Fatalf("AcquireJob() error = %v", err)
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.AcquireJob(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AcquireJob() past the context deadline error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestGenerationLimiterAcquireRequest(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	l := NewGenerationLimiter(1, 0, 0, logger)
+
+	rec := httptest.NewRecorder()
+	release, ok := l.AcquireRequest(rec, httptest.NewRequest(http.MethodPost, "/generate", nil))
+	if !ok {
+		t.Fatalf("AcquireRequest() failed with status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	if _, ok := l.AcquireRequest(rec, httptest.NewRequest(http.MethodPost, "/generate", nil)); ok {
+		t.Fatal("AcquireRequest() succeeded with every worker busy")
+	}
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("busy status = %d with Retry-After %q, want 503 with 2", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	release()
+	rec = httptest.NewRecorder()
+	release, ok = l.AcquireRequest(rec, httptest.NewRequest(http.MethodPost, "/generate", nil))
+	if !ok {
+		t.Fatalf("AcquireRequest() after release failed with status %d", rec.Code)
+	}
+	release()
+}
@@ -100,6 +100,11 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush it
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // GetRequestID extracts request ID from response headers (for logging in handlers)
 func GetRequestID(w http.ResponseWriter) string {
 	return w.Header().Get(RequestIDHeader)
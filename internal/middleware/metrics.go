@@ -72,6 +72,30 @@ var (
 		},
 		[]string{"framework"},
 	)
+
+	// Generation concurrency metrics
+	projectGenerationInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "project_generation_in_flight",
+			Help: "Number of project generations currently running",
+		},
+	)
+
+	projectGenerationQueued = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "project_generation_queued",
+			Help: "Number of project generations waiting for a free worker",
+		},
+	)
+
+	// Reload metrics
+	manifestReloadsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "manifest_reloads_total",
+			Help: "Total number of reloads of the manifest, metadata and templates",
+		},
+		[]string{"trigger", "status"},
+	)
 )
 
 // MetricsMiddleware collects Prometheus metrics for HTTP requests
@@ -127,19 +151,45 @@ func (rw *metricsResponseWriter) Write(b []byte) (int, error) {
 	return size, err
 }
 
-// RecordProjectGeneration records metrics for project generation
-func RecordProjectGeneration(framework string, duration time.Duration, size int64, success bool) {
-	status := "success"
-	if !success {
-		status = "error"
-	}
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush it
+func (rw *metricsResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Status labels of project_generation_total
+const (
+	GenerationSuccess   = "success"
+	GenerationError     = "error"
+	GenerationCancelled = "cancelled" // the client went away or the generation deadline passed
+)
+
+// RecordProjectGeneration records metrics for project generation; duration and size are
+// only observed for successful generations
+func RecordProjectGeneration(framework string, duration time.Duration, size int64, status string) {
 	projectGenerationTotal.WithLabelValues(framework, status).Inc()
-	if success {
+	if status == GenerationSuccess {
 		projectGenerationDuration.WithLabelValues(framework).Observe(duration.Seconds())
 		projectGenerationSize.WithLabelValues(framework).Observe(float64(size))
 	}
 }
 
+// Trigger labels of manifest_reloads_total
+const (
+	ReloadWatch  = "watch"  // a watched file changed
+	ReloadPoll   = "poll"   // polling found a change
+	ReloadSignal = "signal" // SIGHUP
+	ReloadAdmin  = "admin"  // POST /admin/reload
+)
+
+// RecordReload records a reload of the manifest, metadata and templates
+func RecordReload(trigger string, err error) {
+	status := GenerationSuccess
+	if err != nil {
+		status = GenerationError
+	}
+	manifestReloadsTotal.WithLabelValues(trigger, status).Inc()
+}
+
 // sanitizePath sanitizes the path for metrics (removes dynamic parts)
 func sanitizePath(path string) string {
 	// Replace common dynamic parts
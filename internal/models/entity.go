@@ -0,0 +1,312 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+)
+
+// EntityDef describes an entity of the generated project.
+// Every entity gets an implicit int64 "id" primary key unless a field is marked primaryKey.
+type EntityDef struct {
+	Name    string     `json:"name"`            // Entity name, e.g. "Product" or "order_item"
+	Table   string     `json:"table,omitempty"` // Optional: database table, defaults to the snake_case plural of name
+	Fields  []FieldDef `json:"fields"`
+	Indexes []IndexDef `json:"indexes,omitempty"` // Optional: composite indexes
+}
+
+// FieldDef describes a single field of an entity
+type FieldDef struct {
+	Name          string   `json:"name"`                    // Field name, e.g. "price" or "createdAt"
+	Type          string   `json:"type"`                    // string | int | bool | time | uuid | decimal | float | enum
+	Values        []string `json:"values,omitempty"`        // Allowed values (enum only)
+	Required      bool     `json:"required,omitempty"`      // NOT NULL column, required in create requests
+	Nullable      bool     `json:"nullable,omitempty"`      // Nullable column, mapped to a pointer
+	Unique        bool     `json:"unique,omitempty"`        // Unique index
+	Indexed       bool     `json:"indexed,omitempty"`       // Non-unique index
+	Validate      string   `json:"validate,omitempty"`      // Optional: extra validator rules, e.g. "email" or "min=2,max=100"
+	Example       string   `json:"example,omitempty"`       // Optional: example value for Swagger docs
+	PrimaryKey    bool     `json:"primaryKey,omitempty"`    // Use this field as the primary key instead of the implicit id (int, uuid or string)
+	AutoIncrement bool     `json:"autoIncrement,omitempty"` // Primary key values are generated by the database (int keys only)
+	Column        string   `json:"column,omitempty"`        // Optional: database column, defaults to the snake_case name
+	SQLType       string   `json:"sqlType,omitempty"`       // Optional: column type, e.g. "varchar(100)" or "timestamptz"
+	Default       string   `json:"default,omitempty"`       // Optional: SQL default expression, e.g. "now()" or "'draft'"
+	References    string   `json:"references,omitempty"`    // Optional: name of the entity this field is a foreign key to
+	OnDelete      string   `json:"onDelete,omitempty"`      // Optional: CASCADE | SET NULL | SET DEFAULT | RESTRICT | NO ACTION
+	OnUpdate      string   `json:"onUpdate,omitempty"`      // Optional: same actions as onDelete
+}
+
+// IndexDef describes an index over one or more fields
+type IndexDef struct {
+	Name   string   `json:"name,omitempty"`   // Optional: index name, defaults to idx_<table>_<columns>
+	Fields []string `json:"fields"`           // Field names, in index order
+	Unique bool     `json:"unique,omitempty"` // Unique index
+}
+
+// reservedEntityNames would collide with identifiers the generator emits itself
+var reservedEntityNames = map[string]bool{
+	"cache": true,
+	"error": true,
+}
+
+// referentialActions are the allowed onDelete/onUpdate actions of a foreign key
+var referentialActions = map[string]bool{
+	"CASCADE": true, "SET NULL": true, "SET DEFAULT": true, "RESTRICT": true, "NO ACTION": true,
+}
+
+// primaryKeyTypes are the field types that can back a primary key
+var primaryKeyTypes = map[string]bool{
+	constants.FieldTypeInt:    true,
+	constants.FieldTypeUUID:   true,
+	constants.FieldTypeString: true,
+}
+
+// ExampleEntity returns the User entity rendered when example code is requested without entities
+func ExampleEntity() EntityDef {
+	return EntityDef{
+		Name: "User",
+		Fields: []FieldDef{
+			{Name: "name", Type: constants.FieldTypeString, Required: true, Validate: "min=2,max=100", Example: "John Doe"},
+			{Name: "email", Type: constants.FieldTypeString, Required: true, Unique: true, Validate: "email", Example: "john@example.com"},
+		},
+	}
+}
+
+// ResolvedEntities returns the entities to render: the requested ones, or the
+// User example when includeExample is set without entities
+func (r *GenerateRequest) ResolvedEntities() []EntityDef {
+	if len(r.Entities) > 0 {
+		return r.Entities
+	}
+	if r.IncludeExample {
+		return []EntityDef{ExampleEntity()}
+	}
+	return nil
+}
+
+func (r *GenerateRequest) validateEntities() error {
+	if len(r.Entities) > constants.MaxEntities {
+		return fmt.Errorf("at most %d entities are allowed", constants.MaxEntities)
+	}
+
+	seen := make(map[string]bool)
+	for i, e := range r.Entities {
+		if err := e.validate(); err != nil {
+			return fmt.Errorf("entities[%d]: %w", i, err)
+		}
+		key := identifierKey(e.Name)
+		if seen[key] {
+			return fmt.Errorf("entities[%d]: duplicate entity name %s", i, e.Name)
+		}
+		if key == constants.APIModuleName && r.OpenAPI != "" {
+			return fmt.Errorf("entities[%d]: name %s is reserved for the OpenAPI handlers", i, e.Name)
+		}
+		seen[key] = true
+	}
+
+	// Foreign keys must point at another entity of the request, using the type of its primary key
+	byName := make(map[string]*EntityDef, len(r.Entities))
+	for i := range r.Entities {
+		byName[identifierKey(r.Entities[i].Name)] = &r.Entities[i]
+	}
+	for i, e := range r.Entities {
+		for j, f := range e.Fields {
+			if f.References == "" {
+				continue
+			}
+			target, ok := byName[identifierKey(f.References)]
+			if !ok {
+				return fmt.Errorf("entities[%d].fields[%d]: references unknown entity %s", i, j, f.References)
+			}
+			if pkType := target.PrimaryKeyType(); f.Type != pkType {
+				return fmt.Errorf("entities[%d].fields[%d]: type %s does not match the %s primary key of %s", i, j, f.Type, pkType, target.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// PrimaryKeyType returns the field type of the entity's primary key
+func (e *EntityDef) PrimaryKeyType() string {
+	for _, f := range e.Fields {
+		if f.PrimaryKey {
+			return f.Type
+		}
+	}
+	return constants.FieldTypeInt
+}
+
+func (e *EntityDef) validate() error {
+	matched, err := regexp.MatchString(constants.EntityNamePattern, e.Name)
+	if err != nil {
+		return fmt.Errorf("failed to validate entity name: %w", err)
+	}
+	if !matched {
+		return fmt.Errorf("name must start with a letter and contain only letters, digits and underscores")
+	}
+	if reservedEntityNames[identifierKey(e.Name)] {
+		return fmt.Errorf("name %s is reserved", e.Name)
+	}
+
+	if e.Table != "" {
+		if matched, _ := regexp.MatchString(constants.EntityNamePattern, e.Table); !matched {
+			return fmt.Errorf("table must start with a letter and contain only letters, digits and underscores")
+		}
+	}
+
+	if len(e.Fields) == 0 {
+		return fmt.Errorf("entity %s must have at least one field", e.Name)
+	}
+	if len(e.Fields) > constants.MaxEntityFields {
+		return fmt.Errorf("entity %s has more than %d fields", e.Name, constants.MaxEntityFields)
+	}
+
+	seen := make(map[string]bool)
+	columns := make(map[string]bool)
+	primaryKeys := 0
+	for i, f := range e.Fields {
+		if err := f.validate(); err != nil {
+			return fmt.Errorf("fields[%d]: %w", i, err)
+		}
+		key := identifierKey(f.Name)
+		if key == "id" && !f.PrimaryKey {
+			return fmt.Errorf("fields[%d]: id is generated for every entity and can only be declared as the primary key", i)
+		}
+		if seen[key] {
+			return fmt.Errorf("fields[%d]: duplicate field name %s", i, f.Name)
+		}
+		seen[key] = true
+
+		column := key
+		if f.Column != "" {
+			column = identifierKey(f.Column)
+		}
+		if columns[column] || (column == "id" && !f.PrimaryKey) {
+			return fmt.Errorf("fields[%d]: duplicate column for field %s", i, f.Name)
+		}
+		columns[column] = true
+
+		if f.PrimaryKey {
+			primaryKeys++
+		}
+	}
+	if primaryKeys > 1 {
+		return fmt.Errorf("entity %s declares more than one primary key field", e.Name)
+	}
+
+	for i, idx := range e.Indexes {
+		if idx.Name != "" {
+			if matched, _ := regexp.MatchString(constants.EntityNamePattern, idx.Name); !matched {
+				return fmt.Errorf("indexes[%d]: name must start with a letter and contain only letters, digits and underscores", i)
+			}
+		}
+		if len(idx.Fields) == 0 {
+			return fmt.Errorf("indexes[%d]: at least one field is required", i)
+		}
+		for _, name := range idx.Fields {
+			if !seen[identifierKey(name)] {
+				return fmt.Errorf("indexes[%d]: unknown field %s", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *FieldDef) validate() error {
+	matched, err := regexp.MatchString(constants.EntityNamePattern, f.Name)
+	if err != nil {
+		return fmt.Errorf("failed to validate field name: %w", err)
+	}
+	if !matched {
+		return fmt.Errorf("name must start with a letter and contain only letters, digits and underscores")
+	}
+
+	switch f.Type {
+	case constants.FieldTypeString, constants.FieldTypeInt, constants.FieldTypeBool,
+		constants.FieldTypeTime, constants.FieldTypeUUID, constants.FieldTypeDecimal, constants.FieldTypeFloat:
+		if len(f.Values) > 0 {
+			return fmt.Errorf("field %s: values are only allowed for enum fields", f.Name)
+		}
+	case constants.FieldTypeEnum:
+		if len(f.Values) == 0 {
+			return fmt.Errorf("field %s: enum fields require values", f.Name)
+		}
+		seen := make(map[string]bool)
+		for _, v := range f.Values {
+			if matched, _ := regexp.MatchString(constants.EnumValuePattern, v); !matched {
+				return fmt.Errorf("field %s: enum value %q must contain only letters, digits, hyphens and underscores", f.Name, v)
+			}
+			if seen[identifierKey(v)] {
+				return fmt.Errorf("field %s: duplicate enum value %s", f.Name, v)
+			}
+			seen[identifierKey(v)] = true
+		}
+	case "":
+		return fmt.Errorf("field %s: type is required", f.Name)
+	default:
+		return fmt.Errorf("field %s: type must be one of: string, int, bool, time, uuid, decimal, float, enum", f.Name)
+	}
+
+	// Rules and examples end up inside Go struct tags
+	if strings.ContainsAny(f.Validate, "`\"\n") {
+		return fmt.Errorf("field %s: validate contains invalid characters", f.Name)
+	}
+	if strings.ContainsAny(f.Example, "`\"\n") {
+		return fmt.Errorf("field %s: example contains invalid characters", f.Name)
+	}
+
+	if f.Required && f.Nullable {
+		return fmt.Errorf("field %s: required fields cannot be nullable", f.Name)
+	}
+	if f.PrimaryKey {
+		if !primaryKeyTypes[f.Type] {
+			return fmt.Errorf("field %s: primary keys must be of type int, uuid or string", f.Name)
+		}
+		if f.Nullable {
+			return fmt.Errorf("field %s: primary keys cannot be nullable", f.Name)
+		}
+	}
+	if f.AutoIncrement && (!f.PrimaryKey || f.Type != constants.FieldTypeInt) {
+		return fmt.Errorf("field %s: autoIncrement is only allowed on int primary keys", f.Name)
+	}
+
+	// Column options end up inside the gorm struct tag
+	if f.Column != "" {
+		if matched, _ := regexp.MatchString(constants.EntityNamePattern, f.Column); !matched {
+			return fmt.Errorf("field %s: column must start with a letter and contain only letters, digits and underscores", f.Name)
+		}
+	}
+	if f.SQLType != "" {
+		if matched, _ := regexp.MatchString(constants.SQLTypePattern, f.SQLType); !matched {
+			return fmt.Errorf("field %s: sqlType contains invalid characters", f.Name)
+		}
+	}
+	if strings.ContainsAny(f.Default, "`\";\n") {
+		return fmt.Errorf("field %s: default contains invalid characters", f.Name)
+	}
+
+	if f.References != "" {
+		if matched, _ := regexp.MatchString(constants.EntityNamePattern, f.References); !matched {
+			return fmt.Errorf("field %s: references must be an entity name", f.Name)
+		}
+	}
+	for _, action := range []string{f.OnDelete, f.OnUpdate} {
+		if action == "" {
+			continue
+		}
+		if f.References == "" {
+			return fmt.Errorf("field %s: onDelete and onUpdate require references", f.Name)
+		}
+		if !referentialActions[strings.ToUpper(action)] {
+			return fmt.Errorf("field %s: referential action must be one of: CASCADE, SET NULL, SET DEFAULT, RESTRICT, NO ACTION", f.Name)
+		}
+	}
+	return nil
+}
+
+// identifierKey normalizes a name so that "order_item", "orderItem" and "OrderItem" compare equal
+func identifierKey(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
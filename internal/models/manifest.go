@@ -1,25 +1,67 @@
 package models
 
 type Manifest struct {
-	Version    string                  `json:"version"`
-	Libs       map[string]LibDef       `json:"libs"`
-	Frameworks map[string]FrameworkDef `json:"frameworks"`
+	Version       string                     `json:"version"`
+	Libs          map[string]LibDef          `json:"libs"`
+	Frameworks    map[string]FrameworkDef    `json:"frameworks"`
+	Architectures map[string]ArchitectureDef `json:"architectures"`
 }
 
 type LibDef struct {
-	Imports       []string `json:"imports"`
-	ConfigSection string   `json:"config_section"`
-	Templates     []string `json:"templates"`
-	Category      string   `json:"category,omitempty"`     // e.g., "database", "caching", "utilities"
-	DisplayName   string   `json:"display_name,omitempty"` // e.g., "PostgreSQL", "Redis"
-	Icon          string   `json:"icon,omitempty"`         // e.g., "🐘", "🔴"
-	IsRadio       bool     `json:"is_radio,omitempty"`     // true for radio (mutually exclusive), false for checkbox
+	Imports       []ImportDef `json:"imports"`
+	ConfigSection string      `json:"config_section"`
+	Templates     []string    `json:"templates"`
+	Category      string      `json:"category,omitempty"`     // e.g., "database", "caching", "utilities"
+	DisplayName   string      `json:"display_name,omitempty"` // e.g., "PostgreSQL", "Redis"
+	Icon          string      `json:"icon,omitempty"`         // e.g., "🐘", "🔴"
+	IsRadio       bool        `json:"is_radio,omitempty"`     // true for radio (mutually exclusive), false for checkbox
+
+	// Selection rules, enforced when a project is generated
+	Requires  []string `json:"requires,omitempty"`  // libs that must be selected along with this one, or the framework it needs
+	Conflicts []string `json:"conflicts,omitempty"` // libs that cannot be selected along with this one
+	Implies   []string `json:"implies,omitempty"`   // libs added to the selection along with this one
+	Group     string   `json:"group,omitempty"`     // at most one lib of a group can be selected, e.g. "database"
+
+	Options map[string]LibOptionDef `json:"options,omitempty"` // options accepted in the request's libOptions
+}
+
+// ImportDef is a package imported by the code of a framework or lib, and the pinned module
+// that provides it
+type ImportDef struct {
+	Path    string `json:"path"`             // import path, e.g. "go.opentelemetry.io/otel/sdk/trace"
+	Module  string `json:"module,omitempty"` // module path, e.g. "go.opentelemetry.io/otel/sdk"; defaults to Path
+	Version string `json:"version"`          // module version, e.g. "v1.28.0"
+}
+
+// ModulePath returns the path of the module that provides the package
+func (d ImportDef) ModulePath() string {
+	if d.Module != "" {
+		return d.Module
+	}
+	return d.Path
+}
+
+// LibOptionDef describes an option of a lib
+type LibOptionDef struct {
+	Type        string      `json:"type"`                  // string | int | bool | string_list | duration
+	Description string      `json:"description,omitempty"` // e.g., "Consumer group ID"
+	Default     interface{} `json:"default,omitempty"`     // value used when the option is not supplied
+	Config      string      `json:"config,omitempty"`      // config key set by the option, e.g. "kafka.groupId"
+	Enum        []string    `json:"enum,omitempty"`        // allowed values of a string option
+	Min         *int64      `json:"min,omitempty"`         // lower bound of an int option
+	Max         *int64      `json:"max,omitempty"`         // upper bound of an int option
 }
 
 type FrameworkDef struct {
-	Imports       []string `json:"imports"`
-	ConfigSection string   `json:"config_section,omitempty"`
-	Templates     []string `json:"templates"`
-	DisplayName   string   `json:"display_name,omitempty"` // e.g., "Gin", "Echo"
-	Icon          string   `json:"icon,omitempty"`         // e.g., "🍸", "🔊"
+	Imports       []ImportDef `json:"imports"`
+	ConfigSection string      `json:"config_section,omitempty"`
+	Templates     []string    `json:"templates"`
+	DisplayName   string      `json:"display_name,omitempty"` // e.g., "Gin", "Echo"
+	Icon          string      `json:"icon,omitempty"`         // e.g., "🍸", "🔊"
+}
+
+type ArchitectureDef struct {
+	Layers      map[string]string `json:"layers"`                 // layer name -> directory, e.g. "usecase": "internal/usecase"
+	DisplayName string            `json:"display_name,omitempty"` // e.g., "Clean Architecture"
+	Description string            `json:"description,omitempty"`
 }
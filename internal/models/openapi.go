@@ -0,0 +1,182 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// OpenAPIDoc is the subset of an OpenAPI 3.0/3.1 document the generator reads
+type OpenAPIDoc struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       OpenAPIInfo                `json:"info"`
+	Servers    []OpenAPIServer            `json:"servers,omitempty"`
+	Paths      map[string]OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents          `json:"components"`
+}
+
+// OpenAPIInfo holds the document metadata
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIServer is an entry of the servers list
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+// OpenAPIComponents holds the reusable objects referenced with $ref
+type OpenAPIComponents struct {
+	Schemas       map[string]*OpenAPISchema      `json:"schemas,omitempty"`
+	Parameters    map[string]*OpenAPIParameter   `json:"parameters,omitempty"`
+	RequestBodies map[string]*OpenAPIRequestBody `json:"requestBodies,omitempty"`
+	Responses     map[string]*OpenAPIResponse    `json:"responses,omitempty"`
+}
+
+// OpenAPIPathItem holds the operations of a single path
+type OpenAPIPathItem struct {
+	Parameters []*OpenAPIParameter `json:"parameters,omitempty"`
+	Get        *OpenAPIOperation   `json:"get,omitempty"`
+	Put        *OpenAPIOperation   `json:"put,omitempty"`
+	Post       *OpenAPIOperation   `json:"post,omitempty"`
+	Delete     *OpenAPIOperation   `json:"delete,omitempty"`
+	Options    *OpenAPIOperation   `json:"options,omitempty"`
+	Head       *OpenAPIOperation   `json:"head,omitempty"`
+	Patch      *OpenAPIOperation   `json:"patch,omitempty"`
+}
+
+// Operations returns the operations of the path item keyed by upper-case HTTP method
+func (p *OpenAPIPathItem) Operations() map[string]*OpenAPIOperation {
+	ops := make(map[string]*OpenAPIOperation)
+	for method, op := range map[string]*OpenAPIOperation{
+		"GET": p.Get, "PUT": p.Put, "POST": p.Post, "DELETE": p.Delete,
+		"OPTIONS": p.Options, "HEAD": p.Head, "PATCH": p.Patch,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+// OpenAPIOperation describes a single API operation on a path
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses,omitempty"`
+}
+
+// OpenAPIParameter describes a path, query, header or cookie parameter
+type OpenAPIParameter struct {
+	Ref         string         `json:"$ref,omitempty"`
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody describes the body of an operation
+type OpenAPIRequestBody struct {
+	Ref         string                       `json:"$ref,omitempty"`
+	Description string                       `json:"description,omitempty"`
+	Required    bool                         `json:"required,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIResponse describes a single response of an operation
+type OpenAPIResponse struct {
+	Ref         string                       `json:"$ref,omitempty"`
+	Description string                       `json:"description,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType holds the schema of one content type
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema,omitempty"`
+}
+
+// OpenAPISchema is the subset of a JSON schema used to derive Go types and validation rules
+type OpenAPISchema struct {
+	Ref         string   `json:"$ref,omitempty"`
+	Type        TypeList `json:"type,omitempty"`
+	Format      string   `json:"format,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Nullable    bool     `json:"nullable,omitempty"` // OpenAPI 3.0; 3.1 uses a "null" type instead
+
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *AdditionalProperties     `json:"additionalProperties,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	AllOf                []*OpenAPISchema          `json:"allOf,omitempty"`
+	OneOf                []*OpenAPISchema          `json:"oneOf,omitempty"`
+	AnyOf                []*OpenAPISchema          `json:"anyOf,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+
+	Minimum          *float64       `json:"minimum,omitempty"`
+	Maximum          *float64       `json:"maximum,omitempty"`
+	ExclusiveMinimum ExclusiveBound `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum ExclusiveBound `json:"exclusiveMaximum,omitempty"`
+	MinLength        *int           `json:"minLength,omitempty"`
+	MaxLength        *int           `json:"maxLength,omitempty"`
+	MinItems         *int           `json:"minItems,omitempty"`
+	MaxItems         *int           `json:"maxItems,omitempty"`
+	Pattern          string         `json:"pattern,omitempty"`
+}
+
+// TypeList is a schema type: a single string in OpenAPI 3.0, a string or a list in 3.1
+type TypeList []string
+
+// UnmarshalJSON accepts both "string" and ["string", "null"]
+func (t *TypeList) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var list []string
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		*t = list
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = TypeList{single}
+	return nil
+}
+
+// AdditionalProperties is either a boolean or a schema
+type AdditionalProperties struct {
+	Allowed bool
+	Schema  *OpenAPISchema
+}
+
+// UnmarshalJSON accepts both true/false and a schema object
+func (a *AdditionalProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// ExclusiveBound is a boolean flag in OpenAPI 3.0 and the bound itself in 3.1
+type ExclusiveBound struct {
+	Set   bool     // 3.0: the minimum/maximum is exclusive
+	Value *float64 // 3.1: the exclusive bound
+}
+
+// UnmarshalJSON accepts both a boolean and a number
+func (b *ExclusiveBound) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &b.Set); err == nil {
+		return nil
+	}
+	b.Set = true
+	return json.Unmarshal(data, &b.Value)
+}
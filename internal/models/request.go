@@ -4,19 +4,33 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/xhkzeroone/go-generator/internal/constants"
 )
 
 type GenerateRequest struct {
-	ProjectName    string   `json:"projectName"`
-	ModuleName     string   `json:"moduleName"`
-	Framework      string   `json:"framework"`
-	Architecture   string   `json:"architecture"`
-	Libs           []string `json:"libs"`
-	IncludeExample bool     `json:"includeExample,omitempty"` // Optional: include example code (User entity, usecase, handler)
+	ProjectName    string      `json:"projectName"`
+	ModuleName     string      `json:"moduleName"`
+	Framework      string      `json:"framework"`
+	Architecture   string      `json:"architecture,omitempty"` // Optional: project layout (clean | hexagonal | layered | flat | modular), defaults to clean
+	Libs           []string    `json:"libs"`
+	IncludeExample bool        `json:"includeExample,omitempty"` // Optional: include example code (User entity, usecase, handler)
+	Entities       []EntityDef `json:"entities,omitempty"`       // Optional: entities to generate layers for, replaces the User example
+	OpenAPI        string      `json:"openapi,omitempty"`        // Optional: OpenAPI 3.0/3.1 document (YAML or JSON) to generate DTOs, handlers and routes from
+	SQL            string      `json:"sql,omitempty"`            // Optional: CREATE TABLE statements (postgres or mysql dialect) to generate entities from
+	ConfigFormat   string      `json:"configFormat,omitempty"`   // Optional: config file format (json | yaml | toml | env), defaults to json
+	Config         ConfigTree  `json:"config,omitempty"`         // Optional: config values merged over the framework and lib sections
+	LibOptions     LibOptions  `json:"libOptions,omitempty"`     // Optional: options per lib, checked against the lib's options schema in the manifest
+	Timestamp      *time.Time  `json:"timestamp,omitempty"`      // Optional: modification time of every archive entry (RFC 3339), defaults to 1980-01-01T00:00:00Z
 }
 
+// ConfigTree is a config tree keyed by section, e.g. {"redis": {"addr": "redis:6379"}}
+type ConfigTree map[string]interface{}
+
+// LibOptions maps a lib to its option values, e.g. {"kafka": {"groupId": "orders"}}
+type LibOptions map[string]map[string]interface{}
+
 func (r *GenerateRequest) Validate() error {
 	if err := r.validateProjectName(); err != nil {
 		return err
@@ -27,9 +41,124 @@ func (r *GenerateRequest) Validate() error {
 	if r.Framework == "" {
 		return fmt.Errorf("framework is required")
 	}
+	if err := r.validateEntities(); err != nil {
+		return err
+	}
+	if len(r.OpenAPI) > constants.MaxOpenAPISize {
+		return fmt.Errorf("openapi document must be at most %d bytes", constants.MaxOpenAPISize)
+	}
+	if err := r.validateSQL(); err != nil {
+		return err
+	}
+	if err := r.validateConfigFormat(); err != nil {
+		return err
+	}
+	if err := r.validateConfig(); err != nil {
+		return err
+	}
+	if err := r.validateLibOptions(); err != nil {
+		return err
+	}
+	if err := r.validateTimestamp(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *GenerateRequest) validateTimestamp() error {
+	if r.Timestamp == nil {
+		return nil
+	}
+	if r.Timestamp.Before(constants.MinArchiveTime) || !r.Timestamp.Before(constants.MaxArchiveTime) {
+		return fmt.Errorf("timestamp must be between %s and %s",
+			constants.MinArchiveTime.Format(time.RFC3339), constants.MaxArchiveTime.Format(time.RFC3339))
+	}
 	return nil
 }
 
+// ArchiveTime returns the modification time of the archive entries: the request's timestamp
+// truncated to seconds, or MinArchiveTime so that the same request yields the same archive
+func (r *GenerateRequest) ArchiveTime() time.Time {
+	if r.Timestamp == nil {
+		return constants.MinArchiveTime
+	}
+	return r.Timestamp.UTC().Truncate(time.Second)
+}
+
+func (r *GenerateRequest) validateConfig() error {
+	for section, value := range r.Config {
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("config.%s must be an object", section)
+		}
+	}
+	return nil
+}
+
+func (r *GenerateRequest) validateLibOptions() error {
+	for lib := range r.LibOptions {
+		if !r.HasLib(lib) {
+			return fmt.Errorf("libOptions.%s requires the %s lib", lib, lib)
+		}
+	}
+	return nil
+}
+
+// HasLib reports whether the request includes a lib
+func (r *GenerateRequest) HasLib(lib string) bool {
+	for _, l := range r.Libs {
+		if l == lib {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *GenerateRequest) validateConfigFormat() error {
+	switch r.ConfigFormat {
+	case "", constants.ConfigFormatJSON, constants.ConfigFormatYAML, constants.ConfigFormatTOML, constants.ConfigFormatEnv:
+		return nil
+	}
+	return fmt.Errorf("configFormat must be one of: json, yaml, toml, env")
+}
+
+// ConfigFileFormat returns the config file format of the generated project, json by default
+func (r *GenerateRequest) ConfigFileFormat() string {
+	if r.ConfigFormat == "" {
+		return constants.ConfigFormatJSON
+	}
+	return r.ConfigFormat
+}
+
+func (r *GenerateRequest) validateSQL() error {
+	if r.SQL == "" {
+		return nil
+	}
+	if len(r.SQL) > constants.MaxSQLSize {
+		return fmt.Errorf("sql must be at most %d bytes", constants.MaxSQLSize)
+	}
+	if len(r.Entities) > 0 {
+		return fmt.Errorf("sql and entities cannot be combined")
+	}
+	if r.SQLDialect() == "" {
+		return fmt.Errorf("sql requires the postgres or mysql lib, which selects the dialect")
+	}
+	return nil
+}
+
+// SQLDialect returns the database dialect selected by the libs: postgres, mysql or ""
+func (r *GenerateRequest) SQLDialect() string {
+	dialect := ""
+	for _, lib := range r.Libs {
+		switch lib {
+		case "postgres":
+			return lib
+		case "mysql":
+			dialect = lib
+		}
+	}
+	return dialect
+}
+
 func (r *GenerateRequest) validateProjectName() error {
 	if r.ProjectName == "" {
 		return fmt.Errorf("projectName is required")
@@ -2,6 +2,9 @@ package models
 
 import (
 	"testing"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
 )
 
 func TestGenerateRequest_Validate(t *testing.T) {
@@ -119,6 +122,83 @@ func TestGenerateRequest_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "at most",
 		},
+		{
+			name: "yaml config format",
+			req: GenerateRequest{
+				ProjectName:  "my-project",
+				ModuleName:   "github.com/user/my-project",
+				Framework:    "gin",
+				ConfigFormat: "yaml",
+			},
+			wantErr: false,
+		},
+		{
+			name: "config overrides and lib options",
+			req: GenerateRequest{
+				ProjectName: "my-project",
+				ModuleName:  "github.com/user/my-project",
+				Framework:   "gin",
+				Libs:        []string{"redis"},
+				Config:      ConfigTree{"redis": map[string]interface{}{"addr": "redis.dev:6379"}},
+				LibOptions:  LibOptions{"redis": {"db": 2}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "config section is not an object",
+			req: GenerateRequest{
+				ProjectName: "my-project",
+				ModuleName:  "github.com/user/my-project",
+				Framework:   "gin",
+				Config:      ConfigTree{"redis": "redis.dev:6379"},
+			},
+			wantErr: true,
+			errMsg:  "config.redis must be an object",
+		},
+		{
+			name: "options of a lib not in libs",
+			req: GenerateRequest{
+				ProjectName: "my-project",
+				ModuleName:  "github.com/user/my-project",
+				Framework:   "gin",
+				Libs:        []string{"redis"},
+				LibOptions:  LibOptions{"kafka": {"groupId": "orders"}},
+			},
+			wantErr: true,
+			errMsg:  "requires the kafka lib",
+		},
+		{
+			name: "unknown config format",
+			req: GenerateRequest{
+				ProjectName:  "my-project",
+				ModuleName:   "github.com/user/my-project",
+				Framework:    "gin",
+				ConfigFormat: "ini",
+			},
+			wantErr: true,
+			errMsg:  "configFormat must be one of",
+		},
+		{
+			name: "archive timestamp",
+			req: GenerateRequest{
+				ProjectName: "my-project",
+				ModuleName:  "github.com/user/my-project",
+				Framework:   "gin",
+				Timestamp:   timePtr(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+			},
+			wantErr: false,
+		},
+		{
+			name: "archive timestamp before 1980",
+			req: GenerateRequest{
+				ProjectName: "my-project",
+				ModuleName:  "github.com/user/my-project",
+				Framework:   "gin",
+				Timestamp:   timePtr(time.Unix(0, 0)),
+			},
+			wantErr: true,
+			errMsg:  "timestamp must be between",
+		},
 	}
 
 	for _, tt := range tests {
@@ -252,6 +332,161 @@ func TestGenerateRequest_validateModuleName(t *testing.T) {
 	}
 }
 
+func TestGenerateRequest_validateEntities(t *testing.T) {
+	product := func(fields ...FieldDef) EntityDef {
+		return EntityDef{Name: "Product", Fields: fields}
+	}
+	name := FieldDef{Name: "name", Type: "string", Required: true}
+
+	tests := []struct {
+		name     string
+		entities []EntityDef
+		wantErr  bool
+		errMsg   string
+	}{
+		{
+			name:     "no entities",
+			entities: nil,
+			wantErr:  false,
+		},
+		{
+			name: "valid entities",
+			entities: []EntityDef{
+				product(name, FieldDef{Name: "price", Type: "decimal"}, FieldDef{Name: "status", Type: "enum", Values: []string{"draft", "published"}}),
+				{Name: "order_item", Table: "line_items", Fields: []FieldDef{{Name: "quantity", Type: "int", Validate: "min=1"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name:     "invalid entity name",
+			entities: []EntityDef{{Name: "1Product", Fields: []FieldDef{name}}},
+			wantErr:  true,
+			errMsg:   "must start with a letter",
+		},
+		{
+			name:     "reserved entity name",
+			entities: []EntityDef{{Name: "Cache", Fields: []FieldDef{name}}},
+			wantErr:  true,
+			errMsg:   "reserved",
+		},
+		{
+			name:     "duplicate entity names",
+			entities: []EntityDef{{Name: "OrderItem", Fields: []FieldDef{name}}, {Name: "order_item", Fields: []FieldDef{name}}},
+			wantErr:  true,
+			errMsg:   "entities[1]: duplicate entity name",
+		},
+		{
+			name:     "entity without fields",
+			entities: []EntityDef{product()},
+			wantErr:  true,
+			errMsg:   "at least one field",
+		},
+		{
+			name:     "declared id field",
+			entities: []EntityDef{product(FieldDef{Name: "ID", Type: "int"})},
+			wantErr:  true,
+			errMsg:   "fields[0]: id is generated",
+		},
+		{
+			name: "primary key and reference",
+			entities: []EntityDef{
+				{Name: "customer", Fields: []FieldDef{{Name: "id", Type: "uuid", PrimaryKey: true}}},
+				product(name, FieldDef{Name: "customer_id", Type: "uuid", Nullable: true, References: "customer", OnDelete: "SET NULL"}),
+			},
+			wantErr: false,
+		},
+		{
+			name:     "two primary keys",
+			entities: []EntityDef{product(FieldDef{Name: "code", Type: "string", PrimaryKey: true}, FieldDef{Name: "sku", Type: "string", PrimaryKey: true})},
+			wantErr:  true,
+			errMsg:   "more than one primary key",
+		},
+		{
+			name:     "auto-increment string key",
+			entities: []EntityDef{product(FieldDef{Name: "code", Type: "string", PrimaryKey: true, AutoIncrement: true})},
+			wantErr:  true,
+			errMsg:   "autoIncrement is only allowed",
+		},
+		{
+			name:     "required and nullable",
+			entities: []EntityDef{product(FieldDef{Name: "name", Type: "string", Required: true, Nullable: true})},
+			wantErr:  true,
+			errMsg:   "cannot be nullable",
+		},
+		{
+			name:     "reference to unknown entity",
+			entities: []EntityDef{product(FieldDef{Name: "customer_id", Type: "int", References: "customer"})},
+			wantErr:  true,
+			errMsg:   "references unknown entity customer",
+		},
+		{
+			name: "reference type mismatch",
+			entities: []EntityDef{
+				{Name: "customer", Fields: []FieldDef{name}},
+				product(FieldDef{Name: "customer_id", Type: "uuid", References: "customer"}),
+			},
+			wantErr: true,
+			errMsg:  "does not match the int primary key",
+		},
+		{
+			name:     "index on unknown field",
+			entities: []EntityDef{{Name: "Product", Fields: []FieldDef{name}, Indexes: []IndexDef{{Fields: []string{"name", "sku"}}}}},
+			wantErr:  true,
+			errMsg:   "unknown field sku",
+		},
+		{
+			name:     "default breaking the struct tag",
+			entities: []EntityDef{product(FieldDef{Name: "name", Type: "string", Default: "'a';DROP"})},
+			wantErr:  true,
+			errMsg:   "default contains invalid characters",
+		},
+		{
+			name:     "duplicate field names",
+			entities: []EntityDef{product(name, FieldDef{Name: "Name", Type: "string"})},
+			wantErr:  true,
+			errMsg:   "duplicate field name",
+		},
+		{
+			name:     "unknown field type",
+			entities: []EntityDef{product(FieldDef{Name: "price", Type: "money"})},
+			wantErr:  true,
+			errMsg:   "type must be one of",
+		},
+		{
+			name:     "enum without values",
+			entities: []EntityDef{product(FieldDef{Name: "status", Type: "enum"})},
+			wantErr:  true,
+			errMsg:   "require values",
+		},
+		{
+			name:     "values on non-enum field",
+			entities: []EntityDef{product(FieldDef{Name: "status", Type: "string", Values: []string{"a"}})},
+			wantErr:  true,
+			errMsg:   "only allowed for enum",
+		},
+		{
+			name:     "validate rule breaking the struct tag",
+			entities: []EntityDef{product(FieldDef{Name: "name", Type: "string", Validate: "min=1`"})},
+			wantErr:  true,
+			errMsg:   "invalid characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := GenerateRequest{Entities: tt.entities}
+			err := req.validateEntities()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateEntities() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !contains(err.Error(), tt.errMsg) {
+				t.Errorf("validateEntities() error message = %q, want containing %q", err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	if len(substr) == 0 {
@@ -267,3 +502,20 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestGenerateRequest_ArchiveTime(t *testing.T) {
+	req := GenerateRequest{}
+	if got := req.ArchiveTime(); !got.Equal(constants.MinArchiveTime) {
+		t.Errorf("ArchiveTime() = %v, want %v", got, constants.MinArchiveTime)
+	}
+
+	local := time.Date(2024, 5, 1, 14, 0, 0, 500, time.FixedZone("CEST", 2*3600))
+	req.Timestamp = &local
+	if got, want := req.ArchiveTime(), time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC); got != want {
+		t.Errorf("ArchiveTime() = %v, want %v", got, want)
+	}
+}
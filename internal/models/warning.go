@@ -0,0 +1,28 @@
+package models
+
+import "fmt"
+
+// Warning reports part of the input that was accepted but could not be generated as written,
+// e.g. a SQL column type or constraint the generator does not support
+type Warning struct {
+	Code    string `json:"code"`             // e.g. UNSUPPORTED_TYPE
+	Message string `json:"message"`          // human readable explanation
+	Line    int    `json:"line,omitempty"`   // line of the SQL input, when the warning comes from it
+	Table   string `json:"table,omitempty"`  // table or entity the warning applies to
+	Column  string `json:"column,omitempty"` // column or field the warning applies to
+}
+
+// String formats the warning for logs and terminal output
+func (w Warning) String() string {
+	location := ""
+	if w.Line > 0 {
+		location = fmt.Sprintf("line %d: ", w.Line)
+	}
+	switch {
+	case w.Table != "" && w.Column != "":
+		location += w.Table + "." + w.Column + ": "
+	case w.Table != "":
+		location += w.Table + ": "
+	}
+	return fmt.Sprintf("%s%s (%s)", location, w.Message, w.Code)
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// architectureLayers lists every layer an architecture must place
+var architectureLayers = []string{
+	constants.LayerDomain,
+	constants.LayerErrors,
+	constants.LayerUsecase,
+	constants.LayerRepository,
+	constants.LayerModels,
+	constants.LayerHandler,
+	constants.LayerJob,
+	constants.LayerConsumer,
+}
+
+// LayerRef describes how a generated file refers to the package of a layer
+type LayerRef struct {
+	Dir     string // directory relative to the project root
+	Package string // Go package name
+	Import  string // import spec, e.g. "github.com/user/app/internal/domain", possibly aliased
+	Qual    string // identifier qualifier, e.g. "domain." (empty inside the same package)
+}
+
+// LayerRefs maps layer names to their references as seen from one generated file
+type LayerRefs map[string]LayerRef
+
+// architectureName returns the requested architecture, falling back to the default
+func architectureName(req *GenerateRequest) string {
+	if req.Architecture == "" {
+		return constants.DefaultArchitecture
+	}
+	return req.Architecture
+}
+
+// validateArchitecture checks that the requested architecture is defined in the manifest
+func (s *GeneratorService) validateArchitecture(req *GenerateRequest) error {
+	name := architectureName(req)
+	if _, ok := s.manifest.Architectures[name]; ok {
+		return nil
+	}
+
+	valid := make([]string, 0, len(s.manifest.Architectures))
+	for n := range s.manifest.Architectures {
+		valid = append(valid, n)
+	}
+	sort.Strings(valid)
+	return errors.ErrValidation(
+		fmt.Sprintf("architecture must be one of: %s", strings.Join(valid, ", ")), nil,
+	).WithContext("architecture", name)
+}
+
+// layerDir returns the directory of a layer for the request's architecture.
+// module fills the module placeholder used by modular architectures.
+func (s *GeneratorService) layerDir(req *GenerateRequest, layer, module string) string {
+	arch := s.manifest.Architectures[architectureName(req)]
+	return strings.ReplaceAll(arch.Layers[layer], constants.ArchModulePlaceholder, module)
+}
+
+// isModuleLayer reports whether the layer lives in a per-module directory
+func (s *GeneratorService) isModuleLayer(req *GenerateRequest, layer string) bool {
+	arch := s.manifest.Architectures[architectureName(req)]
+	return strings.Contains(arch.Layers[layer], constants.ArchModulePlaceholder)
+}
+
+// layerRefs resolves every layer of a module relative to the file being rendered in the current layer.
+// current is empty for files outside the architecture layers (e.g. internal/app); those files
+// import the packages of several modules, so per-module packages get an alias such as "userdomain".
+func (s *GeneratorService) layerRefs(req *GenerateRequest, current, module string) LayerRefs {
+	currentDir := ""
+	if current != "" {
+		currentDir = s.layerDir(req, current, module)
+	}
+
+	refs := make(LayerRefs, len(architectureLayers))
+	for _, layer := range architectureLayers {
+		dir := s.layerDir(req, layer, module)
+		ref := LayerRef{
+			Dir:     dir,
+			Package: path.Base(dir),
+		}
+		if dir != currentDir {
+			name := ref.Package
+			ref.Import = fmt.Sprintf("%q", req.ModuleName+"/"+dir)
+			if current == "" && s.isModuleLayer(req, layer) {
+				name = strings.ReplaceAll(module, "_", "") + ref.Package
+				ref.Import = name + " " + ref.Import
+			}
+			ref.Qual = name + "."
+		}
+		refs[layer] = ref
+	}
+	return refs
+}
+
+// importsFor returns the de-duplicated import specs needed to reference the given layers
+func (r LayerRefs) importsFor(layers ...string) []string {
+	seen := make(map[string]bool)
+	var imports []string
+	for _, layer := range layers {
+		imp := r[layer].Import
+		if imp == "" || seen[imp] {
+			continue
+		}
+		seen[imp] = true
+		imports = append(imports, imp)
+	}
+	return imports
+}
+
+// validateArchitectureDef validates an architecture definition from the manifest
+func validateArchitectureDef(name string, a models.ArchitectureDef) error {
+	if name == "" {
+		return fmt.Errorf("architecture name cannot be empty")
+	}
+
+	for _, layer := range architectureLayers {
+		dir, ok := a.Layers[layer]
+		if !ok || dir == "" {
+			return fmt.Errorf("architecture must define a directory for layer %s", layer)
+		}
+		if path.IsAbs(dir) || strings.Contains(dir, "..") || path.Clean(dir) != dir {
+			return fmt.Errorf("layer %s directory must be a clean relative path: %s", layer, dir)
+		}
+		if !strings.HasPrefix(dir, "internal/") {
+			return fmt.Errorf("layer %s directory must be under internal/: %s", layer, dir)
+		}
+		if !isGoIdentifier(path.Base(dir)) {
+			return fmt.Errorf("layer %s directory must end in a valid Go package name: %s", layer, dir)
+		}
+	}
+
+	for layer := range a.Layers {
+		if !isArchitectureLayer(layer) {
+			return fmt.Errorf("unknown layer: %s", layer)
+		}
+	}
+
+	return nil
+}
+
+func isArchitectureLayer(layer string) bool {
+	for _, l := range architectureLayers {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// isGoIdentifier reports whether s is a lowercase identifier usable as a package name
+func isGoIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r >= 'a' && r <= 'z' || r == '_' || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+// archiveContentTypes maps every supported archive format to its media type
+var archiveContentTypes = map[string]string{
+	constants.ArchiveFormatZip:    constants.ContentTypeZip,
+	constants.ArchiveFormatTarGz:  constants.ContentTypeGzip,
+	constants.ArchiveFormatTarZst: constants.ContentTypeZstd,
+}
+
+// ValidateArchiveFormat checks an archive format; the empty format is zip
+func ValidateArchiveFormat(format string) error {
+	if _, ok := archiveContentTypes[archiveFormat(format)]; !ok {
+		return errors.ErrValidation(fmt.Sprintf("format must be one of: %s, %s, %s",
+			constants.ArchiveFormatZip, constants.ArchiveFormatTarGz, constants.ArchiveFormatTarZst), nil).
+			WithContext("format", format)
+	}
+	return nil
+}
+
+// ArchiveContentType returns the media type of an archive format
+func ArchiveContentType(format string) string {
+	return archiveContentTypes[archiveFormat(format)]
+}
+
+// ArchiveFileName returns the file name of a project archive, e.g. my-api.tar.gz
+func ArchiveFileName(projectName, format string) string {
+	return projectName + "." + archiveFormat(format)
+}
+
+func archiveFormat(format string) string {
+	if format == "" {
+		return constants.ArchiveFormatZip
+	}
+	return format
+}
+
+// WriteArchive streams the generated project to w as an archive in the given format and
+// returns the number of bytes written. Archives are reproducible: the same request yields
+// the same bytes.
+func (r *GenerateResult) WriteArchive(w io.Writer, format string) (int64, error) {
+	if err := ValidateArchiveFormat(format); err != nil {
+		return 0, err
+	}
+	cw := &countingWriter{w: w}
+	if err := writeArchive(cw, r.files, archiveFormat(format), r.modTime); err != nil {
+		return cw.n, errors.ErrFileSystem("Failed to write project archive", err).
+			WithContext("format", archiveFormat(format))
+	}
+	return cw.n, nil
+}
+
+// ArchiveSHA256 returns the hex-encoded SHA-256 and the size of the archive WriteArchive
+// writes. The archive is written through the hash only, so reporting its digest before a
+// download does not hold it in memory.
+func (r *GenerateResult) ArchiveSHA256(format string) (string, int64, error) {
+	h := sha256.New()
+	size, err := r.WriteArchive(h, format)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// archiveEntry is a file of a project archive
+type archiveEntry struct {
+	name string
+	data []byte
+}
+
+// archiveEntries returns the generated files and the SHA256SUMS file listing their digests,
+// in path order
+func archiveEntries(out outputSink) ([]archiveEntry, error) {
+	names, err := out.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	var sums bytes.Buffer
+	entries := make([]archiveEntry, 0, len(names)+1)
+	for _, name := range names {
+		data, err := out.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&sums, "%x  %s\n", sha256.Sum256(data), name)
+		entries = append(entries, archiveEntry{name: name, data: data})
+	}
+	entries = append(entries, archiveEntry{name: constants.ChecksumsFileName, data: sums.Bytes()})
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries, nil
+}
+
+// writeArchive writes the generated files to w as an archive in a validated format, with
+// every entry modified at modTime
+func writeArchive(w io.Writer, out outputSink, format string, modTime time.Time) error {
+	entries, err := archiveEntries(out)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case constants.ArchiveFormatTarGz:
+		zw := gzip.NewWriter(w)
+		if err := writeTar(zw, entries, modTime); err != nil {
+			return err
+		}
+		return zw.Close()
+	case constants.ArchiveFormatTarZst:
+		// A single encoder goroutine keeps the output independent of the number of CPUs
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		if err := writeTar(zw, entries, modTime); err != nil {
+			zw.Close()
+			return err
+		}
+		return zw.Close()
+	default:
+		return writeZip(w, entries, modTime)
+	}
+}
+
+// writeZip writes archive entries as a ZIP archive
+func writeZip(w io.Writer, entries []archiveEntry, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: modTime}
+		header.SetMode(fileMode(entry.name))
+		f, err := zw.CreateHeader(header)
+		if err != nil {
+			zw.Close()
+			return err
+		}
+		if _, err := f.Write(entry.data); err != nil {
+			zw.Close()
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeTar writes archive entries as an uncompressed tar stream
+func writeTar(w io.Writer, entries []archiveEntry, modTime time.Time) error {
+	tw := tar.NewWriter(w)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     entry.name,
+			Mode:     int64(fileMode(entry.name)),
+			Size:     int64(len(entry.data)),
+			ModTime:  modTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// fileMode returns the Unix mode of a generated file: shell scripts are executable
+func fileMode(name string) fs.FileMode {
+	if strings.HasSuffix(name, constants.ScriptExtension) {
+		return constants.ExecPerm
+	}
+	return constants.FilePerm
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
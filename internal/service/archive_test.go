@@ -0,0 +1,179 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+)
+
+func TestWriteArchive(t *testing.T) {
+	files := map[string]string{
+		"Makefile":           "build:\n\tgo build ./...\n",
+		"cmd/main.go":        "package main\n",
+		"scripts/migrate.sh": "#!/bin/sh\n",
+	}
+	out := newMemoryOutput()
+	for name, content := range files {
+		if err := out.WriteFile(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTime := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	result := &GenerateResult{files: out, modTime: modTime}
+
+	var sums strings.Builder
+	for _, name := range sortedKeys(files) {
+		fmt.Fprintf(&sums, "%x  %s\n", sha256.Sum256([]byte(files[name])), name)
+	}
+	files[constants.ChecksumsFileName] = sums.String()
+	wantOrder := []string{"Makefile", constants.ChecksumsFileName, "cmd/main.go", "scripts/migrate.sh"}
+
+	tests := []struct {
+		format string
+		read   func(t *testing.T, data []byte) []archivedFile
+	}{
+		{format: "", read: readZip},
+		{format: constants.ArchiveFormatZip, read: readZip},
+		{format: constants.ArchiveFormatTarGz, read: func(t *testing.T, data []byte) []archivedFile {
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return readTar(t, zr)
+		}},
+		{format: constants.ArchiveFormatTarZst, read: func(t *testing.T, data []byte) []archivedFile {
+			zr, err := zstd.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+			return readTar(t, zr)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(ArchiveFileName("demo", tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			size, err := result.WriteArchive(&buf, tt.format)
+			if err != nil {
+				t.Fatalf("WriteArchive() error = %v", err)
+			}
+			if size != int64(buf.Len()) {
+				t.Errorf("WriteArchive() size = %d, wrote %d bytes", size, buf.Len())
+			}
+
+			got := tt.read(t, buf.Bytes())
+			if len(got) != len(wantOrder) {
+				t.Fatalf("archive has %d files, want %d", len(got), len(wantOrder))
+			}
+			for i, file := range got {
+				if file.name != wantOrder[i] {
+					t.Errorf("entry %d = %s, want %s", i, file.name, wantOrder[i])
+				}
+				if file.content != files[file.name] {
+					t.Errorf("%s: content = %q, want %q", file.name, file.content, files[file.name])
+				}
+				wantMode := fs.FileMode(constants.FilePerm)
+				if file.name == "scripts/migrate.sh" {
+					wantMode = constants.ExecPerm
+				}
+				if file.mode != wantMode {
+					t.Errorf("%s: mode = %v, want %v", file.name, file.mode, wantMode)
+				}
+				if !file.modTime.Equal(modTime) {
+					t.Errorf("%s: modified %v, want %v", file.name, file.modTime, modTime)
+				}
+			}
+
+			// The same project yields the same bytes, whose digest is known up front
+			var again bytes.Buffer
+			if _, err := result.WriteArchive(&again, tt.format); err != nil {
+				t.Fatalf("WriteArchive() error = %v", err)
+			}
+			if !bytes.Equal(again.Bytes(), buf.Bytes()) {
+				t.Error("WriteArchive() is not reproducible")
+			}
+			digest, size, err := result.ArchiveSHA256(tt.format)
+			if err != nil {
+				t.Fatalf("ArchiveSHA256() error = %v", err)
+			}
+			if want := fmt.Sprintf("%x", sha256.Sum256(buf.Bytes())); digest != want {
+				t.Errorf("ArchiveSHA256() = %s, want %s", digest, want)
+			}
+			if size != int64(buf.Len()) {
+				t.Errorf("ArchiveSHA256() size = %d, want %d", size, buf.Len())
+			}
+
+			later := &GenerateResult{files: out, modTime: modTime.Add(time.Hour)}
+			if other, _, _ := later.ArchiveSHA256(tt.format); other == digest {
+				t.Error("ArchiveSHA256() ignores the modification time")
+			}
+		})
+	}
+
+	if _, err := result.WriteArchive(io.Discard, "rar"); err == nil {
+		t.Error("WriteArchive() with format rar error = nil")
+	}
+	if _, _, err := result.ArchiveSHA256("rar"); err == nil {
+		t.Error("ArchiveSHA256() with format rar error = nil")
+	}
+}
+
+type archivedFile struct {
+	name    string
+	content string
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func readZip(t *testing.T, data []byte) []archivedFile {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []archivedFile
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, archivedFile{name: f.Name, content: string(content), mode: f.Mode(), modTime: f.Modified})
+	}
+	return files
+}
+
+func readTar(t *testing.T, r io.Reader) []archivedFile {
+	tr := tar.NewReader(r)
+	var files []archivedFile
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, archivedFile{name: header.Name, content: string(content), mode: header.FileInfo().Mode(), modTime: header.ModTime})
+	}
+}
@@ -1,50 +1,482 @@
 package service
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
-	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v2"
 
 	"github.com/xhkzeroone/go-generator/internal/constants"
 	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
 )
 
-// mergeConfigSection merges a config section from a JSON file into the merged config
-func (s *GeneratorService) mergeConfigSection(configPath string, mergedConfig map[string]interface{}) error {
+// EnvVar is a config key as an environment variable, e.g. DEMO_REDIS_ADDR=localhost:6379
+type EnvVar struct {
+	Key   string // config key, e.g. "redis.addr"
+	Name  string // environment variable name
+	Value string // default value, quoted for dotenv files when needed
+}
+
+// EnvSection groups the environment variables of one top-level config section
+type EnvSection struct {
+	Name  string // section key, e.g. "redis"
+	Title string // display name, e.g. "Redis"
+	Vars  []EnvVar
+}
+
+// Config layer precedence. A layer overrides the values of lower levels; two layers
+// of the same level that set a key to different values conflict.
+const (
+	configLevelDefaults = iota // built-in defaults, e.g. log.level
+	configLevelManifest        // framework and lib config sections
+	configLevelRequest         // per-request overrides
+)
+
+// configLayer is a config tree and where it came from
+type configLayer struct {
+	Source string // e.g. "framework gin", "lib redis"
+	Level  int
+	Tree   map[string]interface{}
+}
+
+// configOrigin records the layer that set a merged config value
+type configOrigin struct {
+	source string
+	level  int
+}
+
+// configLayers returns the config layers of a request in precedence order:
+// defaults, framework, libs, lib options, request config
+func (s *GeneratorService) configLayers(req *GenerateRequest) ([]configLayer, error) {
+	layers := []configLayer{{
+		Source: "defaults",
+		Level:  configLevelDefaults,
+		Tree: map[string]interface{}{
+			"log": map[string]interface{}{"level": "info"},
+		},
+	}}
+
+	if fdef := s.manifest.Frameworks[req.Framework]; fdef.ConfigSection != "" {
+		tree := s.configSections[fdef.ConfigSection]
+		layers = append(layers, configLayer{Source: "framework " + req.Framework, Level: configLevelManifest, Tree: tree})
+	}
+
+	for _, lib := range req.Libs {
+		ldef := s.manifest.Libs[lib]
+		if ldef.ConfigSection == "" {
+			continue
+		}
+		tree := s.configSections[ldef.ConfigSection]
+		layers = append(layers, configLayer{Source: "lib " + lib, Level: configLevelManifest, Tree: tree})
+	}
+
+	for _, lib := range req.Libs {
+		if layer, ok := s.libOptionsLayer(req, lib); ok {
+			layers = append(layers, layer)
+		}
+	}
+
+	if len(req.Config) > 0 {
+		// Re-decode the overrides so numbers get the same types as the sections
+		data, err := json.Marshal(req.Config)
+		if err != nil {
+			return nil, errors.ErrValidation("config is not a valid JSON tree", err)
+		}
+		tree, err := decodeConfigTree(data)
+		if err != nil {
+			return nil, errors.ErrValidation("config is not a valid JSON tree", err)
+		}
+		layers = append(layers, configLayer{Source: "config", Level: configLevelRequest, Tree: tree})
+	}
+
+	return layers, nil
+}
+
+// loadConfigSections reads and parses the config sections of the frameworks and libs of a
+// manifest, keyed by path. Layers share them, so they are never modified.
+func loadConfigSections(manifest *models.Manifest) (map[string]map[string]interface{}, error) {
+	sections := make(map[string]map[string]interface{})
+	load := func(configPath, kind, name string) error {
+		if configPath == "" || sections[configPath] != nil {
+			return nil
+		}
+		tree, err := loadConfigSection(configPath)
+		if err != nil {
+			return errors.ErrConfig("Failed to load "+kind+" configuration", err).
+				WithContext(kind, name)
+		}
+		sections[configPath] = tree
+		return nil
+	}
+	for _, name := range sortedKeys(manifest.Frameworks) {
+		if err := load(manifest.Frameworks[name].ConfigSection, "framework", name); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range sortedKeys(manifest.Libs) {
+		if err := load(manifest.Libs[name].ConfigSection, "library", name); err != nil {
+			return nil, err
+		}
+	}
+	return sections, nil
+}
+
+// loadConfigSection reads a config section from a JSON file
+func loadConfigSection(configPath string) (map[string]interface{}, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		return errors.ErrFileSystem("Failed to read config section file", err).
+		return nil, errors.ErrFileSystem("Failed to read config section file", err).
 			WithContext("config_path", configPath)
 	}
 
-	var section map[string]interface{}
-	if err := json.Unmarshal(data, &section); err != nil {
-		return errors.ErrConfig("Failed to parse config section", err).
+	section, err := decodeConfigTree(data)
+	if err != nil {
+		return nil, errors.ErrConfig("Failed to parse config section", err).
 			WithContext("config_path", configPath)
 	}
+	return section, nil
+}
 
-	for k, v := range section {
-		mergedConfig[k] = v
+// decodeConfigTree decodes a JSON config tree. Numbers keep their integer or float type,
+// so YAML and TOML files render 8080 rather than 8080.0.
+func decodeConfigTree(data []byte) (map[string]interface{}, error) {
+	var tree map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
 	}
+	return normalizeConfigValue(tree).(map[string]interface{}), nil
+}
+
+// mergeConfigLayers deep-merges config layers in order. Maps are merged key by key;
+// scalars and lists set by a layer of a higher level win. A value set to two
+// different values by layers of the same level is a conflict naming both sources:
+// a generation error for manifest sections, a validation error for request values.
+func mergeConfigLayers(layers []configLayer) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	origins := make(map[string]configOrigin)
+	for _, layer := range layers {
+		origin := configOrigin{source: layer.Source, level: layer.Level}
+		if err := mergeConfigTree(merged, layer.Tree, "", origin, origins); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
 
+// mergeConfigTree merges src into dst. origins maps the dotted key of every merged
+// value, maps included, to the layer that set it.
+func mergeConfigTree(dst, src map[string]interface{}, prefix string, origin configOrigin, origins map[string]configOrigin) error {
+	for _, k := range sortedKeys(src) {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		value := src[k]
+
+		existing, ok := dst[k]
+		if !ok {
+			dst[k] = copyConfigValue(value)
+			setConfigOrigin(origins, key, value, origin)
+			continue
+		}
+
+		existingTree, existingIsMap := existing.(map[string]interface{})
+		valueTree, valueIsMap := value.(map[string]interface{})
+		if existingIsMap && valueIsMap {
+			if err := mergeConfigTree(existingTree, valueTree, key, origin, origins); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if reflect.DeepEqual(existing, value) {
+			continue
+		}
+
+		prev := origins[key]
+		if origin.level < prev.level {
+			continue
+		}
+		if origin.level == prev.level {
+			msg := fmt.Sprintf("Conflicting config values: %s sets %s to %s but %s sets it to %s",
+				prev.source, key, describeConfigValue(existing), origin.source, describeConfigValue(value))
+			// Conflicts between the request's own values are the caller's to fix
+			conflict := errors.ErrGeneration(msg, nil)
+			if prev.level == configLevelRequest {
+				conflict = errors.ErrValidation(msg, nil)
+			}
+			return conflict.
+				WithContext("key", key).
+				WithContext("sources", []string{prev.source, origin.source})
+		}
+
+		for sub := range origins {
+			if strings.HasPrefix(sub, key+".") {
+				delete(origins, sub)
+			}
+		}
+		dst[k] = copyConfigValue(value)
+		setConfigOrigin(origins, key, value, origin)
+	}
 	return nil
 }
 
-// writeConfigFile writes the merged configuration to a JSON file
-func (s *GeneratorService) writeConfigFile(tmp string, config map[string]interface{}) error {
-	cfgPath := filepath.Join(tmp, constants.DirConfig, constants.ConfigFileName)
-	cfgFile, err := os.Create(cfgPath)
+// setConfigOrigin records origin for key and, when value is a map, for every key below it
+func setConfigOrigin(origins map[string]configOrigin, key string, value interface{}, origin configOrigin) {
+	origins[key] = origin
+	if tree, ok := value.(map[string]interface{}); ok {
+		for k, v := range tree {
+			setConfigOrigin(origins, key+"."+k, v, origin)
+		}
+	}
+}
+
+// copyConfigValue deep-copies the maps and lists of a config value, so merging never
+// modifies a layer
+func copyConfigValue(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(typed))
+		for k, item := range typed {
+			out[k] = copyConfigValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(typed))
+		for i, item := range typed {
+			out[i] = copyConfigValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+// describeConfigValue formats a config value for a conflict message
+func describeConfigValue(v interface{}) string {
+	if _, ok := v.(map[string]interface{}); ok {
+		return "a section"
+	}
+	data, err := json.Marshal(v)
 	if err != nil {
-		return errors.ErrFileSystem("Failed to create config file", err).
-			WithContext("config_path", cfgPath)
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// normalizeConfigValue converts the json.Number values of a decoded config tree to int64 or float64
+func normalizeConfigValue(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case json.Number:
+		if i, err := typed.Int64(); err == nil {
+			return i
+		}
+		f, _ := typed.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range typed {
+			typed[k] = normalizeConfigValue(item)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = normalizeConfigValue(item)
+		}
+	}
+	return v
+}
+
+// configFileName returns the name of the generated config file, e.g. config.yaml
+func configFileName(req *GenerateRequest) string {
+	return constants.ConfigFileBase + "." + req.ConfigFileFormat()
+}
+
+// configFilePath returns the path of the generated config file relative to the project root
+func configFilePath(req *GenerateRequest) string {
+	return constants.DirConfig + "/" + configFileName(req)
+}
+
+// writeConfigFile writes the merged configuration in the format selected by the request
+func (s *GeneratorService) writeConfigFile(out outputSink, req *GenerateRequest, config map[string]interface{}) error {
+	cfgPath := configFilePath(req)
+
+	var data []byte
+	var err error
+	switch req.ConfigFileFormat() {
+	case constants.ConfigFormatYAML:
+		data, err = yaml.Marshal(config)
+	case constants.ConfigFormatTOML:
+		data, err = toml.Marshal(config)
+	case constants.ConfigFormatEnv:
+		var buf bytes.Buffer
+		for i, section := range envSections(req, config, s.configTitles(req)) {
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+			fmt.Fprintf(&buf, "# %s configuration\n", section.Title)
+			for _, v := range section.Vars {
+				fmt.Fprintf(&buf, "%s=%s\n", v.Name, v.Value)
+			}
+		}
+		data = buf.Bytes()
+	default:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(config)
+		data = buf.Bytes()
+	}
+	if err != nil {
+		return errors.ErrConfig("Failed to encode config file", err).
+			WithContext("config_path", cfgPath).
+			WithContext("format", req.ConfigFileFormat())
 	}
-	defer cfgFile.Close()
 
-	enc := json.NewEncoder(cfgFile)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(config); err != nil {
+	if err := out.WriteFile(cfgPath, data); err != nil {
 		return errors.ErrFileSystem("Failed to write config file", err).
 			WithContext("config_path", cfgPath)
 	}
 	return nil
 }
+
+// envPrefix returns the environment variable prefix of a project, e.g. MY_SERVICE for my-service
+func envPrefix(projectName string) string {
+	prefix := strings.ToUpper(strings.ReplaceAll(projectName, "-", "_"))
+	if prefix == "" || (prefix[0] >= '0' && prefix[0] <= '9') {
+		// Environment variable names cannot start with a digit
+		prefix = "APP_" + prefix
+	}
+	return prefix
+}
+
+// envVarName returns the environment variable bound to a config key, e.g. DEMO_REDIS_ADDR for redis.addr
+func envVarName(prefix, key string) string {
+	return prefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configKeys returns the dotted keys of every leaf of a config tree in sorted order.
+// Lists are leaves: they are bound to a single key.
+func configKeys(config map[string]interface{}) []string {
+	var keys []string
+	var walk func(prefix string, tree map[string]interface{})
+	walk = func(prefix string, tree map[string]interface{}) {
+		for k, v := range tree {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			if sub, ok := v.(map[string]interface{}); ok {
+				walk(key, sub)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	walk("", config)
+	sort.Strings(keys)
+	return keys
+}
+
+// envSections lists the environment variables of a config tree by top-level section.
+// titles maps section keys to display names; sections without one use their key.
+func envSections(req *GenerateRequest, config map[string]interface{}, titles map[string]string) []EnvSection {
+	prefix := envPrefix(req.ProjectName)
+	sections := make([]EnvSection, 0, len(config))
+	for _, name := range sortedKeys(config) {
+		section := EnvSection{Name: name, Title: name}
+		if title, ok := titles[name]; ok && title != "" {
+			section.Title = title
+		}
+
+		tree := map[string]interface{}{name: config[name]}
+		for _, key := range configKeys(tree) {
+			section.Vars = append(section.Vars, EnvVar{
+				Key:   key,
+				Name:  envVarName(prefix, key),
+				Value: envValue(lookupConfigKey(tree, key)),
+			})
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// lookupConfigKey returns the value of a dotted key in a config tree
+func lookupConfigKey(config map[string]interface{}, key string) interface{} {
+	var v interface{} = config
+	for _, part := range strings.Split(key, ".") {
+		tree, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = tree[part]
+	}
+	return v
+}
+
+// envValue formats a config value for a dotenv file. Lists of scalars are joined with
+// commas; other lists are written as JSON. Values that a shell or dotenv parser would
+// interpret are quoted.
+func envValue(v interface{}) string {
+	var s string
+	switch typed := v.(type) {
+	case nil:
+		s = ""
+	case string:
+		s = typed
+	case float64:
+		s = strconv.FormatFloat(typed, 'f', -1, 64)
+	case []interface{}:
+		if isScalarList(typed) {
+			items := make([]string, len(typed))
+			for i, item := range typed {
+				items[i] = fmt.Sprint(item)
+			}
+			s = strings.Join(items, ",")
+		} else {
+			data, _ := json.Marshal(typed)
+			s = string(data)
+		}
+	default:
+		s = fmt.Sprint(typed)
+	}
+
+	if !strings.ContainsAny(s, " \t#\"'\\$`") {
+		return s
+	}
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	return strconv.Quote(s)
+}
+
+// isScalarList reports whether a list holds no maps or lists
+func isScalarList(list []interface{}) bool {
+	for _, item := range list {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+// configTitles maps the top-level config sections to the display names of their framework or lib
+func (s *GeneratorService) configTitles(req *GenerateRequest) map[string]string {
+	titles := map[string]string{"log": "Log"}
+	if fdef, ok := s.manifest.Frameworks[req.Framework]; ok {
+		titles[req.Framework] = fdef.DisplayName
+	}
+	for _, lib := range req.Libs {
+		titles[lib] = s.manifest.Libs[lib].DisplayName
+	}
+	return titles
+}
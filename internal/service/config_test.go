@@ -0,0 +1,241 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+func TestMergeConfigLayers(t *testing.T) {
+	layers := []configLayer{
+		{Source: "defaults", Level: configLevelDefaults, Tree: map[string]interface{}{
+			"log": map[string]interface{}{"level": "info"},
+		}},
+		{Source: "framework gin", Level: configLevelManifest, Tree: map[string]interface{}{
+			"gin":  map[string]interface{}{"host": "0.0.0.0", "port": int64(8080)},
+			"log":  map[string]interface{}{"level": "debug", "format": "json"},
+			"cors": map[string]interface{}{"origins": []interface{}{"*"}},
+		}},
+		{Source: "lib redis", Level: configLevelManifest, Tree: map[string]interface{}{
+			"redis": map[string]interface{}{"addr": "localhost:6379"},
+			"gin":   map[string]interface{}{"port": int64(8080), "mode": "debug"},
+			"cors":  map[string]interface{}{"origins": []interface{}{"*"}},
+		}},
+		{Source: "request overrides", Level: configLevelRequest, Tree: map[string]interface{}{
+			"redis": map[string]interface{}{"addr": "redis:6379"},
+		}},
+	}
+
+	merged, err := mergeConfigLayers(layers)
+	if err != nil {
+		t.Fatalf("mergeConfigLayers() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"log":   map[string]interface{}{"level": "debug", "format": "json"},
+		"gin":   map[string]interface{}{"host": "0.0.0.0", "port": int64(8080), "mode": "debug"},
+		"redis": map[string]interface{}{"addr": "redis:6379"},
+		"cors":  map[string]interface{}{"origins": []interface{}{"*"}},
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("merged = %v, want %v", merged, want)
+	}
+
+	// Merging must not modify the layers
+	if addr := layers[2].Tree["redis"].(map[string]interface{})["addr"]; addr != "localhost:6379" {
+		t.Errorf("lib redis layer modified: addr = %v", addr)
+	}
+}
+
+func TestMergeConfigLayers_Conflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers []configLayer
+		key    string
+	}{
+		{
+			name: "scalar set by framework and lib",
+			layers: []configLayer{
+				{Source: "framework grpc", Level: configLevelManifest, Tree: map[string]interface{}{
+					"grpc": map[string]interface{}{"port": int64(9090)},
+				}},
+				{Source: "lib grpcgateway", Level: configLevelManifest, Tree: map[string]interface{}{
+					"grpc": map[string]interface{}{"port": int64(8080)},
+				}},
+			},
+			key: "grpc.port",
+		},
+		{
+			name: "section replaced by a scalar",
+			layers: []configLayer{
+				{Source: "lib kafka", Level: configLevelManifest, Tree: map[string]interface{}{
+					"broker": map[string]interface{}{"addr": "localhost:9092"},
+				}},
+				{Source: "lib activemq", Level: configLevelManifest, Tree: map[string]interface{}{
+					"broker": "localhost:61613",
+				}},
+			},
+			key: "broker",
+		},
+		{
+			name: "lists differ",
+			layers: []configLayer{
+				{Source: "lib kafka", Level: configLevelManifest, Tree: map[string]interface{}{
+					"brokers": []interface{}{"a"},
+				}},
+				{Source: "lib rabbitmq", Level: configLevelManifest, Tree: map[string]interface{}{
+					"brokers": []interface{}{"b"},
+				}},
+			},
+			key: "brokers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := mergeConfigLayers(tt.layers)
+			if err == nil {
+				t.Fatal("mergeConfigLayers() error = nil, want a conflict")
+			}
+			appErr, ok := err.(*errors.AppError)
+			if !ok || appErr.Code != errors.ErrCodeGeneration {
+				t.Fatalf("error = %#v, want a generation error", err)
+			}
+			if appErr.Context["key"] != tt.key {
+				t.Errorf("conflict key = %v, want %s", appErr.Context["key"], tt.key)
+			}
+			for _, layer := range tt.layers {
+				if !strings.Contains(err.Error(), layer.Source) {
+					t.Errorf("error %q does not name %s", err.Error(), layer.Source)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeConfigLayers_RequestWins(t *testing.T) {
+	merged, err := mergeConfigLayers([]configLayer{
+		{Source: "lib cron", Level: configLevelManifest, Tree: map[string]interface{}{
+			"cron": map[string]interface{}{"jobs": map[string]interface{}{"cleanup": "@daily"}},
+		}},
+		{Source: "config", Level: configLevelRequest, Tree: map[string]interface{}{
+			"cron": map[string]interface{}{"jobs": "disabled"},
+		}},
+		{Source: "lib other", Level: configLevelManifest, Tree: map[string]interface{}{
+			"cron": map[string]interface{}{"jobs": map[string]interface{}{"cleanup": "@hourly"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("mergeConfigLayers() error = %v", err)
+	}
+	if jobs := merged["cron"].(map[string]interface{})["jobs"]; jobs != "disabled" {
+		t.Errorf("cron.jobs = %v, want disabled", jobs)
+	}
+}
+
+func TestMergeConfigLayers_RequestConflict(t *testing.T) {
+	_, err := mergeConfigLayers([]configLayer{
+		{Source: "libOptions.redis", Level: configLevelRequest, Tree: map[string]interface{}{
+			"redis": map[string]interface{}{"db": int64(2)},
+		}},
+		{Source: "config", Level: configLevelRequest, Tree: map[string]interface{}{
+			"redis": map[string]interface{}{"db": int64(3)},
+		}},
+	})
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != errors.ErrCodeValidation {
+		t.Fatalf("error = %#v, want a validation error", err)
+	}
+	if !strings.Contains(appErr.Message, "libOptions.redis") || !strings.Contains(appErr.Message, "config") {
+		t.Errorf("message %q does not name both sources", appErr.Message)
+	}
+}
+
+func TestLibOptionValue(t *testing.T) {
+	min, max := int64(0), int64(15)
+	tests := []struct {
+		name    string
+		def     models.LibOptionDef
+		value   interface{}
+		want    interface{}
+		wantErr string
+	}{
+		{name: "int from JSON", def: models.LibOptionDef{Type: "int", Min: &min, Max: &max}, value: float64(3), want: int64(3)},
+		{name: "int fraction", def: models.LibOptionDef{Type: "int"}, value: 2.5, wantErr: "integer"},
+		{name: "int above max", def: models.LibOptionDef{Type: "int", Min: &min, Max: &max}, value: float64(16), wantErr: "at most 15"},
+		{name: "enum", def: models.LibOptionDef{Type: "string", Enum: []string{"roundrobin", "hash"}}, value: "hash", want: "hash"},
+		{name: "enum mismatch", def: models.LibOptionDef{Type: "string", Enum: []string{"roundrobin", "hash"}}, value: "random", wantErr: "one of"},
+		{name: "string list", def: models.LibOptionDef{Type: "string_list"}, value: []interface{}{"orders", "payments"}, want: []interface{}{"orders", "payments"}},
+		{name: "string list with number", def: models.LibOptionDef{Type: "string_list"}, value: []interface{}{"orders", 1.0}, wantErr: "list of non-empty strings"},
+		{name: "duration", def: models.LibOptionDef{Type: "duration"}, value: "30m", want: "30m"},
+		{name: "bad duration", def: models.LibOptionDef{Type: "duration"}, value: "soon", wantErr: "duration"},
+		{name: "bool as string", def: models.LibOptionDef{Type: "bool"}, value: "true", wantErr: "boolean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := libOptionValue(tt.def, tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("libOptionValue() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("libOptionValue() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("libOptionValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigLayers_RequestValues(t *testing.T) {
+	s := &GeneratorService{snapshot: &snapshot{manifest: &models.Manifest{
+		Frameworks: map[string]models.FrameworkDef{"gin": {}},
+		Libs: map[string]models.LibDef{
+			"kafka": {Options: map[string]models.LibOptionDef{
+				"groupId": {Type: "string", Config: "kafka.groupId"},
+				"topics":  {Type: "string_list", Config: "kafka.topics"},
+			}},
+		},
+	}}}
+	req := &GenerateRequest{
+		Framework:  "gin",
+		Libs:       []string{"kafka"},
+		LibOptions: models.LibOptions{"kafka": {"groupId": "orders", "topics": []interface{}{"orders.created"}}},
+		Config:     models.ConfigTree{"kafka": map[string]interface{}{"brokers": []interface{}{"kafka.dev:9092"}}, "log": map[string]interface{}{"level": "debug"}},
+	}
+	if err := s.validateLibOptions(req); err != nil {
+		t.Fatalf("validateLibOptions() error = %v", err)
+	}
+
+	layers, err := s.configLayers(req)
+	if err != nil {
+		t.Fatalf("configLayers() error = %v", err)
+	}
+	merged, err := mergeConfigLayers(layers)
+	if err != nil {
+		t.Fatalf("mergeConfigLayers() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"log": map[string]interface{}{"level": "debug"},
+		"kafka": map[string]interface{}{
+			"groupId": "orders",
+			"topics":  []interface{}{"orders.created"},
+			"brokers": []interface{}{"kafka.dev:9092"},
+		},
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("merged = %v, want %v", merged, want)
+	}
+
+	req.LibOptions["kafka"]["partitions"] = 3.0
+	if err := s.validateLibOptions(req); err == nil || !strings.Contains(err.Error(), "not an option of kafka") {
+		t.Errorf("validateLibOptions() error = %v, want unknown option", err)
+	}
+}
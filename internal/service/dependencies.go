@@ -2,19 +2,33 @@ package service
 
 import (
 	"sort"
-	"strings"
+
+	"golang.org/x/mod/semver"
 
 	"github.com/xhkzeroone/go-generator/internal/constants"
 )
 
-// collectDependencies collects all module dependencies from framework and libraries
-func (s *GeneratorService) collectDependencies(req *GenerateRequest) []string {
-	moduleMap := make(map[string]bool)
+// ModuleRequirement is a module required by the generated go.mod, at its pinned version
+type ModuleRequirement struct {
+	Path    string
+	Version string
+}
+
+// collectDependencies collects the modules the generated code imports from the framework,
+// the libraries and the common dependencies, sorted by path. A module required at several
+// versions is required at the highest.
+func (s *GeneratorService) collectDependencies(req *GenerateRequest) []ModuleRequirement {
+	versions := make(map[string]string)
+	require := func(path, version string) {
+		if current, ok := versions[path]; !ok || semver.Compare(version, current) > 0 {
+			versions[path] = version
+		}
+	}
 
 	// Add framework imports
 	if fdef, ok := s.manifest.Frameworks[req.Framework]; ok {
 		for _, imp := range fdef.Imports {
-			moduleMap[extractModulePath(imp)] = true
+			require(imp.ModulePath(), imp.Version)
 		}
 	}
 
@@ -22,70 +36,32 @@ func (s *GeneratorService) collectDependencies(req *GenerateRequest) []string {
 	for _, lib := range req.Libs {
 		if ldef, ok := s.manifest.Libs[lib]; ok {
 			for _, imp := range ldef.Imports {
-				moduleMap[extractModulePath(imp)] = true
+				require(imp.ModulePath(), imp.Version)
 			}
 		}
 	}
 
 	// Always add common dependencies
-	moduleMap[constants.DepLogrus] = true
-	moduleMap[constants.DepViper] = true
-	moduleMap[constants.DepGoogleUUID] = true
+	require(constants.DepLogrus, constants.DepLogrusVersion)
+	require(constants.DepViper, constants.DepViperVersion)
+	require(constants.DepGoogleUUID, constants.DepGoogleUUIDVersion)
+	require(constants.DepMapstructure, constants.DepMapstructureVersion)
 
-	// Convert to sorted slice
-	var modules []string
-	for mod := range moduleMap {
-		modules = append(modules, mod)
+	// The Swagger docs package; gRPC projects have none
+	if req.Framework != constants.FrameworkGRPC {
+		require(constants.DepSwag, constants.DepSwagVersion)
 	}
-	sort.Strings(modules)
-
-	return modules
-}
-
-// extractModulePath extracts the module path from an import path
-// e.g., "github.com/gofiber/fiber/v2" -> "github.com/gofiber/fiber/v2"
-// e.g., "gorm.io/driver/postgres" -> "gorm.io/driver/postgres"
-// e.g., "gorm.io/gorm" -> "gorm.io/gorm"
-func extractModulePath(importPath string) string {
-	// Remove version suffix if present (e.g., /v2, /v3)
-	// But keep it if it's part of the module path
-	parts := strings.Split(importPath, "/")
 
-	// Check if last part is a version (v1, v2, etc.)
-	if len(parts) > 0 {
-		last := parts[len(parts)-1]
-		if strings.HasPrefix(last, "v") && len(last) > 1 {
-			// Check if it's a valid version number
-			isVersion := true
-			for _, r := range last[1:] {
-				if r < '0' || r > '9' {
-					isVersion = false
-					break
-				}
-			}
-			if isVersion {
-				// Keep the version in the module path
-				return importPath
-			}
-		}
+	// Entity field types backed by third-party packages
+	if usesFieldType(req, constants.FieldTypeDecimal) {
+		require(constants.DepDecimal, constants.DepDecimalVersion)
 	}
 
-	// For paths like "gorm.io/driver/postgres", return the first two parts
-	// For paths like "github.com/user/repo", return the first three parts
-	if strings.Contains(importPath, ".") {
-		// Count the number of parts before the first dot
-		parts := strings.Split(importPath, "/")
-		if len(parts) >= 2 {
-			return strings.Join(parts[:min(len(parts), 3)], "/")
-		}
+	modules := make([]ModuleRequirement, 0, len(versions))
+	for path, version := range versions {
+		modules = append(modules, ModuleRequirement{Path: path, Version: version})
 	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
 
-	return importPath
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+	return modules
 }
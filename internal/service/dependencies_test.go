@@ -0,0 +1,86 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+func TestCollectDependencies(t *testing.T) {
+	s := &GeneratorService{snapshot: &snapshot{manifest: &models.Manifest{
+		Frameworks: map[string]models.FrameworkDef{
+			"grpc": {Imports: []models.ImportDef{{Path: "google.golang.org/grpc", Version: "v1.65.0"}}},
+		},
+		Libs: map[string]models.LibDef{
+			"postgres": {Imports: []models.ImportDef{
+				{Path: "gorm.io/driver/postgres", Version: "v1.5.9"},
+				{Path: "gorm.io/gorm", Version: "v1.25.10"},
+			}},
+			"audit": {Imports: []models.ImportDef{
+				{Path: "gorm.io/gorm", Version: "v1.25.12"},
+				{Path: "github.com/mitchellh/mapstructure", Version: "v1.4.3"},
+			}},
+			"tracing": {Imports: []models.ImportDef{
+				{Path: "go.opentelemetry.io/otel/sdk/trace", Module: "go.opentelemetry.io/otel/sdk", Version: "v1.28.0"},
+				{Path: "go.opentelemetry.io/otel/semconv/v1.17.0", Module: "go.opentelemetry.io/otel", Version: "v1.28.0"},
+			}},
+		},
+	}}}
+
+	got := s.collectDependencies(&GenerateRequest{Framework: "grpc", Libs: []string{"postgres", "audit", "tracing"}})
+	want := []ModuleRequirement{
+		{constants.DepGoogleUUID, constants.DepGoogleUUIDVersion},
+		{constants.DepMapstructure, constants.DepMapstructureVersion}, // above audit's v1.4.3
+		{constants.DepLogrus, constants.DepLogrusVersion},
+		{constants.DepViper, constants.DepViperVersion},
+		{"go.opentelemetry.io/otel", "v1.28.0"},
+		{"go.opentelemetry.io/otel/sdk", "v1.28.0"},
+		{"google.golang.org/grpc", "v1.65.0"},
+		{"gorm.io/driver/postgres", "v1.5.9"},
+		{"gorm.io/gorm", "v1.25.12"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collectDependencies() =\n%v\nwant\n%v", got, want)
+	}
+
+	// Only HTTP frameworks get the Swagger docs package
+	got = s.collectDependencies(&GenerateRequest{Framework: "gin"})
+	if got[len(got)-1].Path != constants.DepSwag {
+		t.Errorf("collectDependencies() for gin = %v, want %s", got, constants.DepSwag)
+	}
+}
+
+func TestValidateImports(t *testing.T) {
+	tests := []struct {
+		name string
+		imp  models.ImportDef
+		want string
+	}{
+		{"module root", models.ImportDef{Path: "github.com/gin-gonic/gin", Version: "v1.10.0"}, ""},
+		{"package of a module", models.ImportDef{Path: "go.opentelemetry.io/otel/sdk/trace", Module: "go.opentelemetry.io/otel/sdk", Version: "v1.28.0"}, ""},
+		{"incompatible", models.ImportDef{Path: "github.com/go-stomp/stomp", Version: "v2.1.4+incompatible"}, ""},
+		{"no version", models.ImportDef{Path: "github.com/gin-gonic/gin"}, "not a semantic version"},
+		{"no v prefix", models.ImportDef{Path: "github.com/gin-gonic/gin", Version: "1.10.0"}, "not a semantic version"},
+		{"branch", models.ImportDef{Path: "github.com/gin-gonic/gin", Version: "latest"}, "not a semantic version"},
+		{"not canonical", models.ImportDef{Path: "github.com/gin-gonic/gin", Version: "v1.10"}, "invalid module"},
+		{"major version", models.ImportDef{Path: "github.com/labstack/echo/v4", Version: "v3.3.10"}, "invalid module"},
+		{"outside the module", models.ImportDef{Path: "gorm.io/driver/postgres", Module: "gorm.io/gorm", Version: "v1.25.12"}, "is not in module"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateImports([]models.ImportDef{tt.imp})
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("validateImports() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("validateImports() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,26 +1,21 @@
 package service
 
 import (
-	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/xhkzeroone/go-generator/internal/constants"
-	"github.com/xhkzeroone/go-generator/internal/errors"
 )
 
 // renderDepsPackage renders the deps package using metadata
-func (s *GeneratorService) renderDepsPackage(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	// Load metadata
-	depsMeta, err := s.loadDepsMetadata()
-	if err != nil {
-		return err
-	}
-
-	// Replace {{.ModuleName}} in imports and remove duplicates across all depsMeta
+func (s *GeneratorService) renderDepsPackage(out outputSink, req *GenerateRequest, includes map[string]bool, config map[string]interface{}) error {
+	// Replace {{.ModuleName}} in imports and remove duplicates across all depsMeta; the
+	// snapshot's metadata is shared, so the result goes to a new map
+	depsMeta := make(map[string]DepMetadata, len(s.depsMeta))
 	globalSeen := make(map[string]struct{})
 
-	for key, meta := range depsMeta {
+	for key, meta := range s.depsMeta {
 		uniqueImports := make([]string, 0, len(meta.Imports))
 		for _, imp := range meta.Imports {
 			imp = strings.ReplaceAll(imp, constants.ModuleNamePlaceholder, req.ModuleName)
@@ -33,22 +28,16 @@ func (s *GeneratorService) renderDepsPackage(tmp string, req *GenerateRequest, i
 		depsMeta[key] = meta
 	}
 
-	// Create deps directory
-	depsDir := filepath.Join(tmp, constants.DirInternalDeps)
-	if err := os.MkdirAll(depsDir, constants.DirPerm); err != nil {
-		return errors.ErrFileSystem("Failed to create deps directory", err).
-			WithContext("directory", depsDir)
-	}
-
 	// Render main deps.go
-	depsPath := filepath.Join(depsDir, "deps.go")
+	depsDir := constants.DirInternalDeps
+	depsPath := path.Join(depsDir, "deps.go")
 	data := map[string]interface{}{
 		"ModuleName": req.ModuleName,
 		"Includes":   includes,
 		"DepsMeta":   depsMeta,
 	}
 
-	if err := s.renderTemplate(constants.TemplateDeps, depsPath, data); err != nil {
+	if err := s.renderTemplate(out, constants.TemplateDeps, depsPath, data); err != nil {
 		return err
 	}
 
@@ -66,7 +55,7 @@ func (s *GeneratorService) renderDepsPackage(tmp string, req *GenerateRequest, i
 		// Render each helper file
 		for _, helperFile := range meta.HelperFiles {
 			templatePath := filepath.Join(constants.TemplateDepsDir, filepath.Base(helperFile))
-			outputPath := filepath.Join(depsDir, filepath.Base(helperFile))
+			outputPath := path.Join(depsDir, filepath.Base(helperFile))
 
 			// Remove .tmpl extension from output
 			outputPath = strings.TrimSuffix(outputPath, constants.TemplateExtension) + constants.GoFileExtension
@@ -77,28 +66,25 @@ func (s *GeneratorService) renderDepsPackage(tmp string, req *GenerateRequest, i
 				"Includes":   includes,
 			}
 
-			if err := s.renderTemplate(templatePath, outputPath, helperData); err != nil {
+			if err := s.renderTemplate(out, templatePath, outputPath, helperData); err != nil {
 				return err
 			}
 		}
 	}
 
-	// Load config metadata
-	configMeta, err := s.loadConfigMetadata()
-	if err != nil {
-		return err
-	}
-
 	// Replace {{.ModuleName}} with actual module name
-	configMeta = applyModuleName(configMeta, req.ModuleName)
+	configMeta := applyModuleName(s.configMeta, req.ModuleName)
 
 	// Render config.go
-	configPath := filepath.Join(tmp, constants.DirInternalDeps, "config.go")
+	configPath := path.Join(constants.DirInternalDeps, "config.go")
 	configData := map[string]interface{}{
-		"ModuleName": req.ModuleName,
-		"Includes":   includes,
-		"Framework":  req.Framework,
-		"Meta":       configMeta,
+		"ModuleName":   req.ModuleName,
+		"Includes":     includes,
+		"Framework":    req.Framework,
+		"Meta":         configMeta,
+		"ConfigFormat": req.ConfigFileFormat(),
+		"EnvPrefix":    envPrefix(req.ProjectName),
+		"ConfigKeys":   configKeys(config),
 	}
-	return s.renderTemplate(constants.TemplateConfig, configPath, configData)
+	return s.renderTemplate(out, constants.TemplateConfig, configPath, configData)
 }
@@ -0,0 +1,458 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// EntityView is the template-facing description of an entity
+type EntityView struct {
+	Name      string         // Go type name, e.g. "OrderItem"
+	Var       string         // lower camel case, used as a prefix for local identifiers, e.g. "orderItem"
+	Snake     string         // snake case, used in log fields and event names, e.g. "order_item"
+	Label     string         // human readable name, e.g. "order item"
+	Path      string         // URL path segment, e.g. "order-items"
+	Table     string         // database table name, e.g. "order_items"
+	Module    string         // module name in modular architectures
+	ID        IDView         // primary key, exposed as the ID field
+	Fields    []FieldView    // declared fields other than the primary key
+	Relations []RelationView // belongs-to associations of the database model
+	RPC       *RPCView       // gRPC service of the entity, nil unless the framework is grpc
+	Imports   []string       // import specs required by the field types
+	Layers    LayerRefs      // layer references as seen from internal/app
+}
+
+// IDView describes the primary key of an entity: the implicit int64 id or a field marked primaryKey
+type IDView struct {
+	GoType    string // int64, uuid.UUID or string
+	Kind      string // entity field type of the key: int, uuid or string
+	Column    string // database column
+	GormTag   string // gorm struct tag value
+	Generated bool   // values are assigned by the database, so create requests don't carry them
+	Zero      string // zero value literal
+	Args      string // arguments selecting one row by the id variable in First and Delete
+	Swagger   string // Swagger type of the path parameter
+	Import    string // import spec of the Go type, "" for builtin types
+}
+
+// Attribute returns an OpenTelemetry attribute holding the id expression
+func (v IDView) Attribute(key, expr string) string {
+	switch v.Kind {
+	case constants.FieldTypeUUID:
+		return fmt.Sprintf("attribute.String(%q, %s.String())", key, expr)
+	case constants.FieldTypeString:
+		return fmt.Sprintf("attribute.String(%q, %s)", key, expr)
+	default:
+		return fmt.Sprintf("attribute.Int64(%q, %s)", key, expr)
+	}
+}
+
+// RelationView is a belongs-to association from a foreign key field to another entity's model
+type RelationView struct {
+	Name    string // association field, e.g. "Customer"
+	Target  string // Go name of the referenced entity
+	Module  string // module of the referenced entity
+	Qual    string // package qualifier of the referenced model, "" in the same package
+	Import  string // import spec of the referenced model package, "" in the same package
+	GormTag string // gorm struct tag value
+}
+
+// FieldView is the template-facing description of an entity field
+type FieldView struct {
+	Name           string      // Go field name, e.g. "CreatedAt"
+	JSON           string      // JSON key, e.g. "createdAt"
+	Column         string      // database column, e.g. "created_at"
+	GoType         string      // Go type; enum types are declared in the domain package, nullable fields are pointers
+	Kind           string      // entity field type, e.g. "decimal"
+	Enum           bool        // whether GoType is a domain enum type
+	Nullable       bool        // whether GoType is a pointer
+	Values         []EnumValue // enum constants
+	GormTag        string      // gorm struct tag value
+	Validate       string      // validator rules for create requests
+	UpdateValidate string      // validator rules for update requests
+	Example        string      // Swagger example value
+}
+
+// EnumValue is one constant of an enum field
+type EnumValue struct {
+	Const string // Go constant name, e.g. "OrderStatusPending"
+	Value string // stored value, e.g. "pending"
+}
+
+// fieldGoTypes maps schema field types to Go types and the import they need
+var fieldGoTypes = map[string]struct{ goType, importPath string }{
+	constants.FieldTypeString:  {"string", ""},
+	constants.FieldTypeInt:     {"int64", ""},
+	constants.FieldTypeBool:    {"bool", ""},
+	constants.FieldTypeTime:    {"time.Time", "time"},
+	constants.FieldTypeUUID:    {"uuid.UUID", constants.DepGoogleUUID},
+	constants.FieldTypeDecimal: {"decimal.Decimal", constants.DepDecimal},
+	constants.FieldTypeFloat:   {"float64", ""},
+}
+
+// entityViews builds the template views of every entity the request renders.
+// Relations that cannot be generated are dropped and reported as warnings.
+func (s *GeneratorService) entityViews(req *GenerateRequest, includes map[string]bool) ([]EntityView, []models.Warning) {
+	defs := req.ResolvedEntities()
+	views := make([]EntityView, 0, len(defs))
+	for _, def := range defs {
+		views = append(views, s.entityView(req, def, sqlDialect(includes)))
+	}
+	warnings := s.resolveRelations(req, views)
+	return views, warnings
+}
+
+// resolveRelations qualifies the models referenced by relations. When the models of each module
+// live in their own package, a relation that would make two packages import each other is dropped:
+// the foreign key column is kept, only the association field is not generated.
+func (s *GeneratorService) resolveRelations(req *GenerateRequest, views []EntityView) []models.Warning {
+	var warnings []models.Warning
+	imports := make(map[string]map[string]bool) // models directory -> directories it imports
+
+	// reaches reports whether the package in dir imports target, directly or not
+	var reaches func(dir, target string, seen map[string]bool) bool
+	reaches = func(dir, target string, seen map[string]bool) bool {
+		if dir == target {
+			return true
+		}
+		seen[dir] = true
+		for next := range imports[dir] {
+			if !seen[next] && reaches(next, target, seen) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := range views {
+		view := &views[i]
+		dir := s.layerDir(req, constants.LayerModels, view.Module)
+		kept := view.Relations[:0]
+		for _, rel := range view.Relations {
+			targetDir := s.layerDir(req, constants.LayerModels, rel.Module)
+			if targetDir != dir {
+				if reaches(targetDir, dir, make(map[string]bool)) {
+					warnings = append(warnings, models.Warning{
+						Code:    constants.WarnSkippedRelation,
+						Message: fmt.Sprintf("association %s.%s -> %s would create an import cycle between module packages", view.Name, rel.Name, rel.Target),
+						Table:   view.Table,
+					})
+					continue
+				}
+				if imports[dir] == nil {
+					imports[dir] = make(map[string]bool)
+				}
+				imports[dir][targetDir] = true
+
+				alias := strings.ReplaceAll(rel.Module, "_", "") + path.Base(targetDir)
+				rel.Import = fmt.Sprintf("%s %q", alias, req.ModuleName+"/"+targetDir)
+				rel.Qual = alias + "."
+			}
+			kept = append(kept, rel)
+		}
+		view.Relations = kept
+	}
+	return warnings
+}
+
+// RelationImports returns the de-duplicated import specs of the models referenced by relations
+func (v EntityView) RelationImports() []string {
+	seen := make(map[string]bool)
+	var imports []string
+	for _, rel := range v.Relations {
+		if rel.Import == "" || seen[rel.Import] {
+			continue
+		}
+		seen[rel.Import] = true
+		imports = append(imports, rel.Import)
+	}
+	return imports
+}
+
+// entityView converts an entity definition into its template view
+func (s *GeneratorService) entityView(req *GenerateRequest, def models.EntityDef, dialect string) EntityView {
+	name := pascalCase(def.Name)
+	snake := snakeCase(def.Name)
+	words := splitWords(def.Name)
+	last := len(words) - 1
+
+	view := EntityView{
+		Name:   name,
+		Var:    camelCase(def.Name),
+		Snake:  snake,
+		Label:  strings.Join(words, " "),
+		Path:   strings.Join(append(words[:last:last], plural(words[last])), "-"),
+		Table:  def.Table,
+		Module: snake,
+		ID:     implicitID(),
+	}
+	if view.Table == "" {
+		view.Table = strings.Join(append(words[:last:last], plural(words[last])), "_")
+	}
+
+	seenImports := make(map[string]bool)
+	used := map[string]bool{"ID": true}
+	for _, f := range def.Fields {
+		if imp := fieldGoTypes[f.Type].importPath; imp != "" && !seenImports[imp] {
+			seenImports[imp] = true
+			view.Imports = append(view.Imports, fmt.Sprintf("%q", imp))
+		}
+		if f.PrimaryKey {
+			view.ID = primaryKeyView(f, dialect)
+			continue
+		}
+
+		field := fieldView(name, f, dialect)
+		field.GormTag += indexTags(def, f, view.Table)
+		view.Fields = append(view.Fields, field)
+		used[field.Name] = true
+	}
+
+	// Associations are named after the foreign key, e.g. customer_id -> Customer
+	for _, f := range def.Fields {
+		if f.References == "" {
+			continue
+		}
+		target := pascalCase(f.References)
+		fkWords := splitWords(f.Name)
+		relation := pascalCase(f.Name) + target
+		if n := len(fkWords); n > 1 && fkWords[n-1] == "id" {
+			relation = pascalCase(strings.Join(fkWords[:n-1], "_"))
+		}
+		for used[relation] {
+			relation += "Ref"
+		}
+		used[relation] = true
+
+		tag := "foreignKey:" + pascalCase(f.Name) + ";references:ID"
+		var actions []string
+		if f.OnDelete != "" {
+			actions = append(actions, "OnDelete:"+strings.ToUpper(f.OnDelete))
+		}
+		if f.OnUpdate != "" {
+			actions = append(actions, "OnUpdate:"+strings.ToUpper(f.OnUpdate))
+		}
+		if len(actions) > 0 {
+			tag += ";constraint:" + strings.Join(actions, ",")
+		}
+		view.Relations = append(view.Relations, RelationView{
+			Name:    relation,
+			Target:  target,
+			Module:  snakeCase(f.References),
+			GormTag: tag,
+		})
+	}
+
+	// Standard library imports first, as goimports would group them
+	sort.SliceStable(view.Imports, func(i, j int) bool {
+		return !strings.Contains(view.Imports[i], ".") && strings.Contains(view.Imports[j], ".")
+	})
+
+	view.Layers = s.layerRefs(req, "", view.Module)
+	return view
+}
+
+// implicitID returns the auto-increment int64 id every entity has unless it declares a primary key
+func implicitID() IDView {
+	return IDView{
+		GoType:    "int64",
+		Kind:      constants.FieldTypeInt,
+		Column:    "id",
+		GormTag:   "primaryKey;autoIncrement",
+		Generated: true,
+		Zero:      "0",
+		Args:      "id",
+		Swagger:   "int",
+	}
+}
+
+// primaryKeyView converts a field marked primaryKey into the entity's ID
+func primaryKeyView(f models.FieldDef, dialect string) IDView {
+	column := f.Column
+	if column == "" {
+		column = snakeCase(f.Name)
+	}
+	id := IDView{
+		GoType:    fieldGoTypes[f.Type].goType,
+		Kind:      f.Type,
+		Column:    column,
+		Generated: f.AutoIncrement || f.Default != "",
+		Args:      fmt.Sprintf("%q, id", column+" = ?"),
+		Swagger:   "string",
+	}
+
+	parts := []string{"column:" + column, "primaryKey"}
+	switch f.Type {
+	case constants.FieldTypeInt:
+		id.Zero = "0"
+		id.Args = "id"
+		id.Swagger = "int"
+		if f.AutoIncrement {
+			parts = append(parts, "autoIncrement")
+		}
+	case constants.FieldTypeUUID:
+		id.Zero = "uuid.Nil"
+		id.Import = fmt.Sprintf("%q", constants.DepGoogleUUID)
+	default:
+		id.Zero = `""`
+	}
+	if sqlType := columnType(f, dialect); sqlType != "" {
+		parts = append(parts, "type:"+sqlType)
+	}
+	if f.Default != "" {
+		parts = append(parts, "default:"+f.Default)
+	}
+	id.GormTag = strings.Join(parts, ";")
+	return id
+}
+
+// fieldView converts a field definition into its template view
+func fieldView(entity string, f models.FieldDef, dialect string) FieldView {
+	field := FieldView{
+		Name:     pascalCase(f.Name),
+		JSON:     camelCase(f.Name),
+		Column:   f.Column,
+		GoType:   fieldGoTypes[f.Type].goType,
+		Kind:     f.Type,
+		Nullable: f.Nullable,
+		Example:  f.Example,
+	}
+	if field.Column == "" {
+		field.Column = snakeCase(f.Name)
+	}
+
+	// Nullable enums stay plain strings: the oneof rule below still restricts their values
+	if f.Type == constants.FieldTypeEnum && !f.Nullable {
+		field.Enum = true
+		field.GoType = entity + field.Name
+		for _, v := range f.Values {
+			field.Values = append(field.Values, EnumValue{Const: field.GoType + pascalCase(v), Value: v})
+		}
+	} else if f.Type == constants.FieldTypeEnum {
+		field.GoType = "string"
+	}
+	if f.Type == constants.FieldTypeEnum && field.Example == "" {
+		field.Example = f.Values[0]
+	}
+	if f.Nullable {
+		field.GoType = "*" + field.GoType
+	}
+
+	// Validation rules: required first, then the enum constraint, then custom rules
+	var rules []string
+	if f.Type == constants.FieldTypeEnum {
+		rules = append(rules, "oneof="+strings.Join(f.Values, " "))
+	}
+	if f.Validate != "" {
+		rules = append(rules, f.Validate)
+	}
+	if len(rules) > 0 {
+		field.UpdateValidate = "omitempty," + strings.Join(rules, ",")
+	}
+	switch {
+	// A required bool would reject false, so NOT NULL booleans only get the column constraint
+	case f.Required && f.Type != constants.FieldTypeBool:
+		rules = append([]string{"required"}, rules...)
+	case f.Nullable && len(rules) > 0:
+		rules = append([]string{"omitempty"}, rules...)
+	}
+	field.Validate = strings.Join(rules, ",")
+
+	field.GormTag = gormTag(f, field.Column, dialect)
+	return field
+}
+
+// columnType returns the column type of a field: the declared sqlType or the default for its type
+func columnType(f models.FieldDef, dialect string) string {
+	if f.SQLType != "" {
+		return f.SQLType
+	}
+	switch f.Type {
+	case constants.FieldTypeString:
+		return "varchar(255)"
+	case constants.FieldTypeEnum:
+		return "varchar(64)"
+	case constants.FieldTypeDecimal:
+		return "decimal(20,8)"
+	case constants.FieldTypeUUID:
+		if dialect == "mysql" {
+			return "char(36)"
+		}
+		return "uuid"
+	}
+	return ""
+}
+
+// gormTag builds the gorm struct tag for a field
+func gormTag(f models.FieldDef, column, dialect string) string {
+	parts := []string{"column:" + column}
+	if sqlType := columnType(f, dialect); sqlType != "" {
+		parts = append(parts, "type:"+sqlType)
+	}
+	if f.Unique {
+		parts = append(parts, "uniqueIndex")
+	} else if f.Indexed {
+		parts = append(parts, "index")
+	}
+	if f.Required || (f.Default != "" && !f.Nullable) {
+		parts = append(parts, "not null")
+	}
+	if f.Default != "" {
+		parts = append(parts, "default:"+f.Default)
+	}
+	return strings.Join(parts, ";")
+}
+
+// indexTags returns the gorm index settings of the composite indexes that cover a field
+func indexTags(def models.EntityDef, f models.FieldDef, table string) string {
+	var tags strings.Builder
+	for _, idx := range def.Indexes {
+		for i, name := range idx.Fields {
+			if snakeCase(name) != snakeCase(f.Name) {
+				continue
+			}
+			indexName := idx.Name
+			if indexName == "" {
+				columns := make([]string, len(idx.Fields))
+				for j, n := range idx.Fields {
+					columns[j] = snakeCase(n)
+				}
+				indexName = "idx_" + table + "_" + strings.Join(columns, "_")
+			}
+			kind := "index"
+			if idx.Unique {
+				kind = "uniqueIndex"
+			}
+			fmt.Fprintf(&tags, ";%s:%s,priority:%d", kind, indexName, i+1)
+		}
+	}
+	return tags.String()
+}
+
+// sqlDialect returns the database the generated models target
+func sqlDialect(includes map[string]bool) string {
+	if includes["postgres"] {
+		return "postgres"
+	}
+	if includes["mysql"] {
+		return "mysql"
+	}
+	return ""
+}
+
+// usesFieldType reports whether any entity of the request declares a field of the given type
+func usesFieldType(req *GenerateRequest, fieldType string) bool {
+	for _, e := range req.ResolvedEntities() {
+		for _, f := range e.Fields {
+			if f.Type == fieldType {
+				return true
+			}
+		}
+	}
+	return false
+}
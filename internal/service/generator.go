@@ -1,7 +1,8 @@
 package service
 
 import (
-	"os"
+	"context"
+	"time"
 
 	"github.com/xhkzeroone/go-generator/internal/constants"
 	"github.com/xhkzeroone/go-generator/internal/errors"
@@ -9,186 +10,356 @@ import (
 )
 
 type GeneratorService struct {
-	manifest *models.Manifest
+	// snapshot is the manifest, metadata and templates this value generates with. Exported
+	// methods pin the current snapshot first, so a reload does not change a generation that
+	// has already started.
+	*snapshot
+
+	sources *snapshotSource
+
+	// timeout bounds each generation; zero leaves only the caller's deadline
+	timeout time.Duration
+
+	// protocGenGo is the protoc-gen-go plugin that compiles the stubs of gRPC projects
+	protocGenGo string
 }
 
 func NewGeneratorService(manifestPath string) (*GeneratorService, error) {
-	manifest, err := loadManifest(manifestPath)
+	sources := &snapshotSource{manifestPath: manifestPath}
+	snap, err := sources.load()
 	if err != nil {
-		return nil, errors.ErrConfig("Failed to load manifest", err).
-			WithContext("manifest_path", manifestPath)
+		return nil, err
 	}
-	return &GeneratorService{manifest: manifest}, nil
+	sources.current.Store(snap)
+	return &GeneratorService{
+		snapshot:    snap,
+		sources:     sources,
+		timeout:     constants.DefaultGenerationTimeout,
+		protocGenGo: constants.ProtocGenGo,
+	}, nil
 }
 
-func (s *GeneratorService) GenerateProject(req *GenerateRequest) ([]byte, error) {
-	// Validate framework exists
-	if _, ok := s.manifest.Frameworks[req.Framework]; !ok {
-		return nil, errors.ErrNotFound("framework").
-			WithContext("framework", req.Framework)
+// GenerateResult is a generated project, written out with WriteArchive, and the warnings
+// raised while generating it
+type GenerateResult struct {
+	Warnings []models.Warning
+	files    outputSink
+	modTime  time.Time
+}
+
+// SetGenerationTimeout sets the deadline of each generation, measured from its start; zero
+// disables it. A context that carries its own deadline keeps it instead.
+func (s *GeneratorService) SetGenerationTimeout(timeout time.Duration) {
+	s.timeout = timeout
+}
+
+type noTimeoutKey struct{}
+
+// WithoutGenerationTimeout returns a context whose generations skip the generation timeout,
+// leaving only the deadline of ctx, if any
+func WithoutGenerationTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTimeoutKey{}, true)
+}
+
+// SetProtocGenGo sets the protoc-gen-go plugin run for gRPC projects: an executable path,
+// or a name looked up in PATH
+func (s *GeneratorService) SetProtocGenGo(plugin string) {
+	s.protocGenGo = plugin
+}
+
+// GenerateProject generates the project of a request in memory. It stops between render
+// stages once ctx is done or the generation timeout has passed, returning a CANCELLED or
+// TIMEOUT error. See WithoutGenerationTimeout for generations bounded only by ctx.
+func (s *GeneratorService) GenerateProject(ctx context.Context, req *GenerateRequest) (*GenerateResult, error) {
+	out := newMemoryOutput()
+	warnings, err := s.generate(ctx, req, out)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate all libs exist
-	for _, lib := range req.Libs {
-		if _, ok := s.manifest.Libs[lib]; !ok {
-			return nil, errors.ErrNotFound("library").
-				WithContext("library", lib)
-		}
+	return &GenerateResult{Warnings: warnings, files: out, modTime: req.ArchiveTime()}, nil
+}
+
+// FileCount returns the number of generated files
+func (r *GenerateResult) FileCount() int {
+	names, _ := r.files.Files()
+	return len(names)
+}
+
+// generate renders the project of a request into out
+func (s *GeneratorService) generate(ctx context.Context, req *GenerateRequest, out outputSink) ([]models.Warning, error) {
+	s = s.pin()
+	if _, ok := ctx.Deadline(); !ok && s.timeout > 0 && ctx.Value(noTimeoutKey{}) == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
 	}
 
-	// Create temp directory
-	tmp, err := os.MkdirTemp("", constants.TempDirPrefix+req.ProjectName+"-*")
+	// Add the libs the selected ones imply, then validate the request
+	req, warnings, err := s.resolveRequest(req)
 	if err != nil {
-		return nil, errors.ErrFileSystem("Failed to create temporary directory", err).
-			WithContext("project_name", req.ProjectName)
+		return nil, err
 	}
-	defer os.RemoveAll(tmp)
 
-	// Create base structure
-	if err := s.createBaseStructure(tmp, req.IncludeExample, req.Framework); err != nil {
-		return nil, errors.ErrFileSystem("Failed to create project structure", err).
-			WithContext("project_name", req.ProjectName)
+	stages := newStageTracker(ctx, out)
+	if err := stages.Start("parse"); err != nil {
+		return nil, err
+	}
+
+	// Turn the SQL schema (if any) into entity definitions
+	req, sqlWarnings, err := s.importSQL(req)
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(warnings, sqlWarnings...)
+
+	// Entity files must not land on each other or on the shared files of a layer
+	if err := s.validateEntityFiles(req); err != nil {
+		return nil, err
+	}
+
+	// Parse the OpenAPI document (if any) before writing anything
+	api, apiWarnings, err := s.apiView(req)
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(warnings, apiWarnings...)
+
+	// Merge the defaults, framework and lib config sections and the request's overrides
+	layers, err := s.configLayers(req)
+	if err != nil {
+		return nil, err
+	}
+	mergedConfig, err := mergeConfigLayers(layers)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := stages.Start("framework"); err != nil {
+		return nil, err
 	}
 
 	// Collect dependencies
-	allImports := s.collectDependencies(req)
+	requires := s.collectDependencies(req)
 
 	// Get framework definition
 	fdef := s.manifest.Frameworks[req.Framework]
 
 	// Render framework templates
-	if err := s.renderFrameworkTemplates(tmp, req, fdef); err != nil {
+	if err := s.renderFrameworkTemplates(out, req, fdef); err != nil {
 		return nil, errors.ErrTemplate("Failed to render framework templates", err).
 			WithContext("framework", req.Framework)
 	}
 
+	if err := stages.Start("middleware"); err != nil {
+		return nil, err
+	}
+
 	// Render middleware templates (always included for logging, tracing, rate limiting)
-	if err := s.renderMiddlewareTemplates(tmp, req); err != nil {
+	if err := s.renderMiddlewareTemplates(out, req); err != nil {
 		return nil, errors.ErrTemplate("Failed to render middleware templates", err).
 			WithContext("framework", req.Framework)
 	}
 
-	// Render library templates and merge configs
+	// Render library templates
 	includes := make(map[string]bool)
-	mergedConfig := make(map[string]interface{})
-
-	// Add default log config section (always included)
-	mergedConfig["log"] = map[string]interface{}{
-		"level": "info",
-	}
-
-	// Merge framework config section first
-	if fdef.ConfigSection != "" {
-		if err := s.mergeConfigSection(fdef.ConfigSection, mergedConfig); err != nil {
-			return nil, errors.ErrConfig("Failed to merge framework configuration", err).
-				WithContext("framework", req.Framework)
-		}
-	}
-
-	// Merge library config sections
 	for _, lib := range req.Libs {
-		ldef := s.manifest.Libs[lib]
 		includes[lib] = true
 
-		if err := s.renderLibTemplates(tmp, req, lib, ldef); err != nil {
+		if err := stages.Start("library " + lib); err != nil {
+			return nil, err
+		}
+
+		if err := s.renderLibTemplates(out, req, lib, s.manifest.Libs[lib]); err != nil {
 			return nil, errors.ErrTemplate("Failed to render library templates", err).
 				WithContext("library", lib)
 		}
+	}
 
-		// Merge config section
-		if ldef.ConfigSection != "" {
-			if err := s.mergeConfigSection(ldef.ConfigSection, mergedConfig); err != nil {
-				return nil, errors.ErrConfig("Failed to merge library configuration", err).
-					WithContext("library", lib)
-			}
-		}
+	if err := stages.Start("config"); err != nil {
+		return nil, err
 	}
 
 	// Write config file
-	if err := s.writeConfigFile(tmp, mergedConfig); err != nil {
+	if err := s.writeConfigFile(out, req, mergedConfig); err != nil {
 		return nil, errors.ErrFileSystem("Failed to write configuration file", err)
 	}
 
-	// Render Clean Architecture layers only if IncludeExample is true
-	if req.IncludeExample {
-		if err := s.renderDomainLayer(tmp, req, includes); err != nil {
+	// Render architecture layers only for the requested entities (or the User example)
+	entities, relationWarnings := s.entityViews(req, includes)
+	warnings = append(warnings, relationWarnings...)
+
+	if err := stages.Start("layers"); err != nil {
+		return nil, err
+	}
+
+	// gRPC projects serve the entities through services described by a proto file
+	var proto *ProtoView
+	if req.Framework == constants.FrameworkGRPC && len(entities) > 0 {
+		if proto, err = s.protoView(ctx, req, entities); err != nil {
+			return nil, errors.ErrGeneration("Failed to compile proto file", err)
+		}
+		if err := s.renderProtoLayer(out, req, proto); err != nil {
+			return nil, errors.ErrTemplate("Failed to render proto layer", err)
+		}
+	}
+
+	if len(entities) > 0 {
+		if err := s.renderDomainLayer(out, req, entities, includes); err != nil {
 			return nil, errors.ErrTemplate("Failed to render domain layer", err)
 		}
 
-		// Render errors package (always included with examples)
-		if err := s.renderErrorsLayer(tmp, req); err != nil {
+		// Render errors package (always included with entities)
+		if err := s.renderErrorsLayer(out, req, entities); err != nil {
 			return nil, errors.ErrTemplate("Failed to render errors layer", err)
 		}
 
 		// Render database models (infrastructure layer)
-		if err := s.renderModelsLayer(tmp, req, includes); err != nil {
+		if err := s.renderModelsLayer(out, req, entities, includes); err != nil {
 			return nil, errors.ErrTemplate("Failed to render models layer", err)
 		}
 
-		if err := s.renderRepositoryLayer(tmp, req, includes); err != nil {
+		if err := s.renderRepositoryLayer(out, req, entities, includes); err != nil {
 			return nil, errors.ErrTemplate("Failed to render repository layer", err)
 		}
 
-		if err := s.renderUsecaseLayer(tmp, req, includes); err != nil {
+		if err := s.renderUsecaseLayer(out, req, entities, includes); err != nil {
 			return nil, errors.ErrTemplate("Failed to render usecase layer", err)
 		}
 
-		if err := s.renderHandlerLayer(tmp, req, includes); err != nil {
+		if err := s.renderHandlerLayer(out, req, entities, includes); err != nil {
 			return nil, errors.ErrTemplate("Failed to render handler layer", err)
 		}
 
 		// Jobs layer (only if cron is included)
 		if includes["cron"] {
-			if err := s.renderJobsLayer(tmp, req, includes); err != nil {
+			if err := s.renderJobsLayer(out, req, entities[0], includes); err != nil {
 				return nil, errors.ErrTemplate("Failed to render jobs layer", err)
 			}
 		}
 
 		// Consumers layer (if RabbitMQ, Kafka, or ActiveMQ is included)
 		if includes["rabbitmq"] || includes["kafka"] || includes["activemq"] {
-			if err := s.renderConsumersLayer(tmp, req, includes); err != nil {
+			if err := s.renderConsumersLayer(out, req, entities[0], includes); err != nil {
 				return nil, errors.ErrTemplate("Failed to render consumers layer", err)
 			}
 		}
 	}
 
-	// App server (always render, but with or without example routes)
-	if err := s.renderAppServer(tmp, req, includes); err != nil {
+	if err := stages.Start("api"); err != nil {
+		return nil, err
+	}
+
+	// API package generated from the OpenAPI document
+	if api != nil {
+		if err := s.renderAPILayer(out, req, api, includes); err != nil {
+			return nil, errors.ErrTemplate("Failed to render API layer", err)
+		}
+	}
+
+	if err := stages.Start("app"); err != nil {
+		return nil, err
+	}
+
+	// App server (always render, but with or without entity and API routes)
+	if err := s.renderAppServer(out, req, entities, api, proto, includes); err != nil {
 		return nil, errors.ErrTemplate("Failed to render app server", err)
 	}
 
+	if err := stages.Start("main"); err != nil {
+		return nil, err
+	}
+
 	// Write main.go
-	if err := s.renderMainFile(tmp, req, includes); err != nil {
+	if err := s.renderMainFile(out, req, includes); err != nil {
 		return nil, errors.ErrTemplate("Failed to render main file", err)
 	}
 
+	if err := stages.Start("deps"); err != nil {
+		return nil, err
+	}
+
 	// Write deps package
-	if err := s.renderDepsPackage(tmp, req, includes); err != nil {
+	if err := s.renderDepsPackage(out, req, includes, mergedConfig); err != nil {
 		return nil, errors.ErrTemplate("Failed to render dependencies package", err)
 	}
 
-	// Render Swagger docs stub
-	if err := s.renderDocsStub(tmp, req); err != nil {
-		return nil, errors.ErrTemplate("Failed to render documentation", err)
+	if err := stages.Start("docs"); err != nil {
+		return nil, err
+	}
+
+	// Render Swagger docs (the OpenAPI document or a stub); gRPC projects document the proto file instead
+	if req.Framework != constants.FrameworkGRPC {
+		if err := s.renderDocs(out, req, api); err != nil {
+			return nil, errors.ErrTemplate("Failed to render documentation", err)
+		}
+	}
+
+	if err := stages.Start("go.mod"); err != nil {
+		return nil, err
 	}
 
 	// Write go.mod with all dependencies
-	if err := s.renderGoMod(tmp, req, allImports); err != nil {
+	if err := s.renderGoMod(out, req, requires); err != nil {
 		return nil, errors.ErrTemplate("Failed to render go.mod file", err)
 	}
 
+	if err := stages.Start("project files"); err != nil {
+		return nil, err
+	}
+
 	// Render project files (Dockerfile, .gitignore, .env.example, README.md)
-	if err := s.renderProjectFiles(tmp, req, includes); err != nil {
+	if err := s.renderProjectFiles(out, req, entities, api, proto, includes, mergedConfig); err != nil {
 		return nil, errors.ErrTemplate("Failed to render project files", err)
 	}
 
-	// Create zip file
-	zipData, err := s.createZip(tmp)
-	if err != nil {
-		return nil, errors.ErrFileSystem("Failed to create project archive", err)
+	if err := stages.Start("format"); err != nil {
+		return nil, err
+	}
+
+	// Format the Go files and fix their imports
+	if err := formatGoFiles(out); err != nil {
+		return nil, err
+	}
+	stages.Finish()
+
+	return warnings, nil
+}
+
+// validateRequest checks the framework, libs, lib options and architecture of a request
+// against the manifest
+func (s *GeneratorService) validateRequest(req *GenerateRequest) error {
+	// Validate framework exists
+	if _, ok := s.manifest.Frameworks[req.Framework]; !ok {
+		return errors.ErrNotFound("framework").
+			WithContext("framework", req.Framework)
+	}
+
+	// Validate all libs exist
+	for _, lib := range req.Libs {
+		if _, ok := s.manifest.Libs[lib]; !ok {
+			return errors.ErrNotFound("library").
+				WithContext("library", lib)
+		}
+	}
+
+	// Validate the selection against the requires, conflicts and group rules of the libs
+	if err := s.validateLibRules(req); err != nil {
+		return err
+	}
+
+	// Validate lib options against the options schema of each lib
+	if err := s.validateLibOptions(req); err != nil {
+		return err
+	}
+
+	// Validate architecture exists
+	if err := s.validateArchitecture(req); err != nil {
+		return err
 	}
 
-	return zipData, nil
+	// Validate the gRPC framework and gateway combination
+	return validateGRPC(req)
 }
 
 type GenerateRequest = models.GenerateRequest
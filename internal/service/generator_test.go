@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// cancellingOutput cancels a context once the first file has been written
+type cancellingOutput struct {
+	*memoryOutput
+	cancel context.CancelFunc
+}
+
+func (o *cancellingOutput) WriteFile(name string, data []byte) error {
+	o.cancel()
+	return o.memoryOutput.WriteFile(name, data)
+}
+
+func TestGenerateContext(t *testing.T) {
+	s := repoService(t)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		timeout   time.Duration
+		wantCode  string
+		wantStage string
+	}{
+		{"cancelled", cancelled, 0, errors.ErrCodeCancelled, "parse"},
+		{"deadline exceeded", expired, 0, errors.ErrCodeTimeout, "parse"},
+		{"generation timeout", context.Background(), time.Nanosecond, errors.ErrCodeTimeout, "parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.SetGenerationTimeout(tt.timeout)
+			defer s.SetGenerationTimeout(0)
+
+			result, err := s.GenerateProject(tt.ctx, benchmarkRequest())
+			if result != nil {
+				t.Errorf("GenerateProject() returned a result")
+			}
+			appErr, ok := err.(*errors.AppError)
+			if !ok {
+				t.Fatalf("GenerateProject() error = %v, want an AppError", err)
+			}
+			if appErr.Code != tt.wantCode || appErr.Context["stage"] != tt.wantStage {
+				t.Errorf("GenerateProject() error = %s at %v, want %s at %s", appErr.Code, appErr.Context["stage"], tt.wantCode, tt.wantStage)
+			}
+		})
+	}
+
+	t.Run("cancelled while rendering", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		out := &cancellingOutput{memoryOutput: newMemoryOutput(), cancel: cancel}
+
+		_, err := s.generate(ctx, benchmarkRequest(), out)
+		appErr, ok := err.(*errors.AppError)
+		if !ok || appErr.Code != errors.ErrCodeCancelled {
+			t.Fatalf("generate() error = %v, want a CANCELLED error", err)
+		}
+		// The framework templates are written before the middleware stage starts
+		if appErr.Context["stage"] != "middleware" {
+			t.Errorf("generate() stopped at %v, want middleware", appErr.Context["stage"])
+		}
+		if names, _ := out.Files(); len(names) == 0 || len(names) > 20 {
+			t.Errorf("generate() wrote %d files before stopping", len(names))
+		}
+	})
+
+	t.Run("without generation timeout", func(t *testing.T) {
+		s.SetGenerationTimeout(time.Nanosecond)
+		defer s.SetGenerationTimeout(0)
+
+		if _, err := s.GenerateProject(WithoutGenerationTimeout(context.Background()), benchmarkRequest()); err != nil {
+			t.Errorf("GenerateProject() error = %v, want no generation timeout", err)
+		}
+	})
+}
+
+func TestGenerateProgress(t *testing.T) {
+	s := repoService(t)
+
+	var events []ProgressEvent
+	ctx := WithProgress(context.Background(), func(event ProgressEvent) {
+		events = append(events, event)
+	})
+	result, err := s.GenerateProject(ctx, benchmarkRequest())
+	if err != nil {
+		t.Fatalf("GenerateProject() error = %v", err)
+	}
+
+	// Every stage starts and then finishes before the next one starts
+	var stages []string
+	files := 0
+	for i := 0; i+1 < len(events); i += 2 {
+		start, finish := events[i], events[i+1]
+		if start.Type != ProgressStart || finish.Type != ProgressFinish || start.Stage != finish.Stage {
+			t.Fatalf("events %d and %d = %+v, %+v, want the start and finish of a stage", i, i+1, start, finish)
+		}
+		if start.TotalFiles != files || finish.TotalFiles != files+finish.Files || finish.DurationMs < 0 {
+			t.Errorf("stage %s counts = %+v, %+v after %d files", start.Stage, start, finish, files)
+		}
+		files = finish.TotalFiles
+		stages = append(stages, start.Stage)
+	}
+	if len(events)%2 != 0 {
+		t.Errorf("%d events, want start and finish pairs", len(events))
+	}
+
+	names, _ := result.files.Files()
+	if files != len(names) {
+		t.Errorf("stages wrote %d files, want %d", files, len(names))
+	}
+	for _, want := range []string{"parse", "framework", "library kafka", "layers", "app", "go.mod", "format"} {
+		if !containsString(stages, want) {
+			t.Errorf("stages %v miss %s", stages, want)
+		}
+	}
+}
+
+func TestGenerateEntityFiles(t *testing.T) {
+	s := repoService(t)
+
+	entity := func(name string) models.EntityDef {
+		return models.EntityDef{Name: name, Fields: []models.FieldDef{{Name: "title", Type: constants.FieldTypeString}}}
+	}
+	tests := []struct {
+		name         string
+		architecture string
+		entities     []string
+		want         string
+	}{
+		{"response in clean", "clean", []string{"Response"}, ""},
+		{"response in modular", "modular", []string{"Response"}, ""},
+		{"response in flat", "flat", []string{"Response"}, "internal/service/response.go is also written for the handler layer"},
+		{"example job in flat", "flat", []string{"ExampleJob"}, "internal/service/example_job.go is also written for the job layer"},
+		{"entity files in flat", "flat", []string{"User", "UserModel"}, "internal/service/user_model.go is also written for entity User"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := benchmarkRequest()
+			req.Architecture = tt.architecture
+			req.Entities = nil
+			for _, name := range tt.entities {
+				req.Entities = append(req.Entities, entity(name))
+			}
+
+			_, err := s.GenerateProject(context.Background(), req)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("GenerateProject() error = %v", err)
+				}
+				return
+			}
+			appErr, ok := err.(*errors.AppError)
+			if !ok || appErr.Code != errors.ErrCodeValidation || !strings.Contains(appErr.Message, tt.want) {
+				t.Errorf("GenerateProject() error = %v, want a validation error containing %q", err, tt.want)
+			}
+		})
+	}
+}
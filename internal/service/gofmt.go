@@ -0,0 +1,316 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+// stdlibImports maps the package names that generated code refers to onto the standard
+// library import paths added when a file lacks them. A missing import only leaves its
+// package name to go on, so the paths are listed; which imports are standard library is
+// told by isStdlib. Names shared by several packages (rand, template, ...) are left out: a
+// template using them must import the one it means.
+var stdlibImports = map[string]string{
+	"bufio":    "bufio",
+	"bytes":    "bytes",
+	"context":  "context",
+	"errors":   "errors",
+	"fmt":      "fmt",
+	"io":       "io",
+	"log":      "log",
+	"math":     "math",
+	"net":      "net",
+	"os":       "os",
+	"path":     "path",
+	"reflect":  "reflect",
+	"regexp":   "regexp",
+	"runtime":  "runtime",
+	"sort":     "sort",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"sync":     "sync",
+	"syscall":  "syscall",
+	"time":     "time",
+	"unicode":  "unicode",
+	"atomic":   "sync/atomic",
+	"base64":   "encoding/base64",
+	"debug":    "runtime/debug",
+	"embed":    "embed",
+	"filepath": "path/filepath",
+	"fs":       "io/fs",
+	"hex":      "encoding/hex",
+	"http":     "net/http",
+	"httptest": "net/http/httptest",
+	"json":     "encoding/json",
+	"sha256":   "crypto/sha256",
+	"signal":   "os/signal",
+	"slog":     "log/slog",
+	"sql":      "database/sql",
+	"url":      "net/url",
+	"utf8":     "unicode/utf8",
+}
+
+// formatGoFiles runs every generated Go file through a goimports-style pass: imports
+// nothing refers to are dropped, missing standard library imports are added, standard
+// library imports are grouped apart from the others and the file is printed the way gofmt
+// prints it. A file that does not parse is a template bug.
+func formatGoFiles(out outputSink) error {
+	names, err := out.Files()
+	if err != nil {
+		return errors.ErrFileSystem("Failed to list generated files", err)
+	}
+
+	fset := token.NewFileSet()
+	files := make(map[string]*ast.File)
+	// Names declared at package level, per directory; a file may use those of its siblings
+	declared := make(map[string]map[string]bool)
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		src, err := out.ReadFile(name)
+		if err != nil {
+			return errors.ErrFileSystem("Failed to read generated file", err).
+				WithContext("path", name)
+		}
+		f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if err != nil {
+			return parseError(name, err)
+		}
+		files[name] = f
+
+		dir := path.Dir(name)
+		if declared[dir] == nil {
+			declared[dir] = make(map[string]bool)
+		}
+		for _, obj := range f.Scope.Objects {
+			declared[dir][obj.Name] = true
+		}
+	}
+
+	for _, name := range sortedKeys(files) {
+		f := files[name]
+		fixImports(fset, f, declared[path.Dir(name)])
+
+		var buf bytes.Buffer
+		if err := format.Node(&buf, fset, f); err != nil {
+			return errors.ErrTemplate("Failed to format generated file", err).
+				WithContext("path", name)
+		}
+		// Code generated by tools such as protoc-gen-go keeps the imports the tool wrote
+		src := buf.Bytes()
+		if !ast.IsGenerated(f) {
+			if src, err = groupImports(src); err != nil {
+				return errors.ErrTemplate("Failed to format generated file", err).
+					WithContext("path", name)
+			}
+		}
+		if err := out.ReplaceFile(name, src); err != nil {
+			return errors.ErrFileSystem("Failed to write generated file", err).
+				WithContext("path", name)
+		}
+	}
+	return nil
+}
+
+// parseError reports the first syntax error of a generated file with its position
+func parseError(name string, err error) error {
+	line, msg := 0, err.Error()
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		line, msg = list[0].Pos.Line, list[0].Msg
+	}
+	return errors.ErrTemplate(fmt.Sprintf("Generated file %s does not parse at line %d: %s", name, line, msg), err).
+		WithContext("path", name).
+		WithContext("line", line)
+}
+
+// fixImports removes the unused imports of f and adds the standard library ones it lacks.
+// declared holds the package-level names of f's package, which shadow package names.
+func fixImports(fset *token.FileSet, f *ast.File, declared map[string]bool) {
+	refs := packageRefs(f)
+
+	imported := make(map[string]bool)
+	// DeleteNamedImport edits f.Imports, so range over a copy
+	for _, imp := range append([]*ast.ImportSpec(nil), f.Imports...) {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		alias := ""
+		if imp.Name != nil {
+			alias = imp.Name.Name
+		}
+		// Blank and dot imports are kept for their side effects
+		if alias == "_" || alias == "." {
+			continue
+		}
+		name := alias
+		if name == "" {
+			name = importPathToName(importPath)
+		}
+		if refs[name] {
+			imported[name] = true
+			continue
+		}
+		astutil.DeleteNamedImport(fset, f, alias, importPath)
+	}
+
+	for _, name := range sortedKeys(refs) {
+		if imported[name] || declared[name] {
+			continue
+		}
+		if importPath, ok := stdlibImports[name]; ok {
+			astutil.AddImport(fset, f, importPath)
+		}
+	}
+}
+
+// groupImports moves the standard library imports of a formatted file into a group of
+// their own, ahead of the groups of the other imports, the way goimports lays them out.
+// Files whose imports are grouped that way already are returned as they are.
+func groupImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	var decl *ast.GenDecl
+	for _, d := range f.Decls {
+		if gen, ok := d.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			if decl != nil {
+				return src, nil // several import declarations are left as written
+			}
+			decl = gen
+		}
+	}
+	if decl == nil || !decl.Lparen.IsValid() {
+		return src, nil
+	}
+
+	// Specs separated by a blank line are in different groups
+	var current [][]string
+	var std []string
+	others := [][]string{nil}
+	lastLine, comments := 0, 0
+	for _, spec := range decl.Specs {
+		imp := spec.(*ast.ImportSpec)
+		start, end := imp.Pos(), imp.End()
+		if imp.Doc != nil {
+			start = imp.Doc.Pos()
+			comments++
+		}
+		if imp.Comment != nil {
+			end = imp.Comment.End()
+			comments++
+		}
+		if len(current) == 0 || fset.Position(start).Line > lastLine+1 {
+			current = append(current, nil)
+			if len(others[len(others)-1]) > 0 {
+				others = append(others, nil)
+			}
+		}
+		lastLine = fset.Position(end).Line
+
+		text := string(src[fset.Position(start).Offset:fset.Position(end).Offset])
+		current[len(current)-1] = append(current[len(current)-1], text)
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		if isStdlib(importPath) {
+			std = append(std, text)
+		} else {
+			others[len(others)-1] = append(others[len(others)-1], text)
+		}
+	}
+	for _, c := range f.Comments {
+		if c.Pos() > decl.Lparen && c.End() < decl.Rparen {
+			comments--
+		}
+	}
+
+	groups := others
+	if len(groups[len(groups)-1]) == 0 {
+		groups = groups[:len(groups)-1]
+	}
+	if len(std) > 0 {
+		groups = append([][]string{std}, groups...)
+	}
+	// Comments of their own between the imports would be lost
+	if comments != 0 || reflect.DeepEqual(groups, current) {
+		return src, nil
+	}
+
+	var regrouped bytes.Buffer
+	regrouped.Write(src[:fset.Position(decl.Lparen).Offset])
+	regrouped.WriteString("(\n")
+	for i, group := range groups {
+		if i > 0 {
+			regrouped.WriteString("\n")
+		}
+		for _, text := range group {
+			regrouped.WriteString("\t" + text + "\n")
+		}
+	}
+	regrouped.Write(src[fset.Position(decl.Rparen).Offset:])
+	return format.Source(regrouped.Bytes())
+}
+
+// packageRefs returns the identifiers used as the X of an X.Sel expression that the file
+// does not declare; those are package names or package-level names of sibling files
+func packageRefs(f *ast.File) map[string]bool {
+	refs := make(map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+			refs[id.Name] = true
+		}
+		return true
+	})
+	return refs
+}
+
+// importPathToName guesses the package name of an unaliased import the way goimports does:
+// the last path element without a version suffix, a "go-" prefix or a "-go" style tail
+func importPathToName(importPath string) string {
+	base := path.Base(importPath)
+	if isVersion(base) {
+		base = path.Base(path.Dir(importPath))
+	}
+	if i := strings.Index(base, ".v"); i > 0 && isVersion(base[i+1:]) {
+		base = base[:i] // gopkg.in/yaml.v3
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// isVersion reports whether s is a version path element such as v2 or v1.17.0
+func isVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, part := range strings.Split(s[1:], ".") {
+		if _, err := strconv.Atoi(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+func TestFormatGoFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "formats",
+			files: map[string]string{"main.go": "package main\nfunc main(){\n  println( \"hi\" )\n}\n"},
+			want:  "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n",
+		},
+		{
+			name: "removes unused imports",
+			files: map[string]string{"main.go": `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	amqp "github.com/rabbitmq/amqp091-go"
+	_ "github.com/lib/pq"
+)
+
+func main() { fmt.Println(redis.Nil) }
+`},
+			want: `package main
+
+import (
+	"fmt"
+
+	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+func main() { fmt.Println(redis.Nil) }
+`,
+		},
+		{
+			name: "adds missing standard library imports",
+			files: map[string]string{"server.go": `package app
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Run(ctx context.Context, r *gin.Engine, addr string) {
+	log.Printf("Starting on %s", addr)
+	http.ListenAndServe(addr, r)
+}
+`},
+			want: `package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Run(ctx context.Context, r *gin.Engine, addr string) {
+	log.Printf("Starting on %s", addr)
+	http.ListenAndServe(addr, r)
+}
+`,
+		},
+		{
+			name: "groups standard library imports apart",
+			files: map[string]string{"server.go": `package app
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"os"
+
+	"example.com/demo/internal/deps"
+)
+
+func Run(r *gin.Engine, d *deps.Deps) {
+	logrus.Info(fmt.Sprint(os.Args))
+	log.Print("up")
+}
+`},
+			want: `package app
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"example.com/demo/internal/deps"
+)
+
+func Run(r *gin.Engine, d *deps.Deps) {
+	logrus.Info(fmt.Sprint(os.Args))
+	log.Print("up")
+}
+`,
+		},
+		{
+			name:  "adds a standard library group",
+			files: map[string]string{"server.go": "package app\n\nimport \"github.com/gin-gonic/gin\"\n\nfunc Run(r *gin.Engine) { log.Print(r) }\n"},
+			want:  "package app\n\nimport (\n\t\"log\"\n\n\t\"github.com/gin-gonic/gin\"\n)\n\nfunc Run(r *gin.Engine) { log.Print(r) }\n",
+		},
+		{
+			name:  "generated code keeps its imports",
+			files: map[string]string{"api.pb.go": "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n\nimport (\n\tprotoimpl \"google.golang.org/protobuf/runtime/protoimpl\"\n\tsync \"sync\"\n)\n\nvar _ = protoimpl.X\nvar _ sync.Mutex\n"},
+			want:  "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n\nimport (\n\tprotoimpl \"google.golang.org/protobuf/runtime/protoimpl\"\n\tsync \"sync\"\n)\n\nvar _ = protoimpl.X\nvar _ sync.Mutex\n",
+		},
+		{
+			name: "package-level names of sibling files are not packages",
+			files: map[string]string{
+				"app/server.go": "package app\n\nfunc Run() { log.Print(\"up\") }\n",
+				"app/logger.go": "package app\n\ntype logger struct{}\n\nfunc (logger) Print(string) {}\n\nvar log logger\n",
+			},
+			want: "package app\n\nfunc Run() { log.Print(\"up\") }\n",
+		},
+		{
+			name:  "locals shadow packages",
+			files: map[string]string{"main.go": "package main\n\nimport \"time\"\n\nfunc main() {\n\tvar time struct{ Now int }\n\t_ = time.Now\n}\n"},
+			want:  "package main\n\nfunc main() {\n\tvar time struct{ Now int }\n\t_ = time.Now\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := newMemoryOutput()
+			for name, src := range tt.files {
+				if err := out.WriteFile(name, []byte(src)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := formatGoFiles(out); err != nil {
+				t.Fatalf("formatGoFiles() error = %v", err)
+			}
+			first := sortedKeys(tt.files)[len(tt.files)-1]
+			got, _ := out.ReadFile(first)
+			if string(got) != tt.want {
+				t.Errorf("%s =\n%s\nwant\n%s", first, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatGoFiles_ParseError(t *testing.T) {
+	out := newMemoryOutput()
+	out.WriteFile("README.md", []byte("func {"))
+	out.WriteFile("internal/app/server.go", []byte("package app\n\nfunc Run() {\n\treturn nil nil\n}\n"))
+
+	err := formatGoFiles(out)
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != errors.ErrCodeTemplate {
+		t.Fatalf("formatGoFiles() error = %#v, want a template error", err)
+	}
+	if appErr.Context["path"] != "internal/app/server.go" || appErr.Context["line"] != 4 {
+		t.Errorf("error context = %v, want the path and line 4", appErr.Context)
+	}
+	if !strings.Contains(appErr.Message, "internal/app/server.go") || !strings.Contains(appErr.Message, "line 4") {
+		t.Errorf("error message %q does not name the path and line", appErr.Message)
+	}
+}
+
+func TestImportPathToName(t *testing.T) {
+	tests := map[string]string{
+		"net/http":                                 "http",
+		"github.com/redis/go-redis/v9":             "redis",
+		"github.com/segmentio/kafka-go":            "kafka",
+		"github.com/rabbitmq/amqp091-go":           "amqp091",
+		"github.com/go-stomp/stomp":                "stomp",
+		"github.com/robfig/cron/v3":                "cron",
+		"gopkg.in/yaml.v3":                         "yaml",
+		"go.opentelemetry.io/otel/semconv/v1.17.0": "semconv",
+	}
+	for importPath, want := range tests {
+		if got := importPathToName(importPath); got != want {
+			t.Errorf("importPathToName(%q) = %q, want %q", importPath, got, want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of TestGolden")
+
+// goldenDir holds one directory per fixture: request.json (the body of POST /generate),
+// optionally openapi.yaml and schema.sql for the openapi and sql fields, and files/ with
+// every generated file stored as <path>.golden. Paths are relative to internal/service.
+const goldenDir = "testdata/golden"
+
+// TestGolden runs each fixture through GenerateProject and compares the generated files
+// with the golden ones. Run go test ./internal/service -run TestGolden -update to rewrite
+// them after a template change, and review the diff.
+func TestGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join(goldenDir, "*", "request.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("no fixtures in %s", goldenDir)
+	}
+
+	// Read the fixtures before repoService changes into the repository root
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := make(map[string]*GenerateRequest)
+	for _, fixture := range fixtures {
+		dir := filepath.Join(wd, filepath.Dir(fixture))
+		requests[dir] = readGoldenRequest(t, dir)
+	}
+	s := repoService(t)
+
+	for _, dir := range sortedKeys(requests) {
+		req := requests[dir]
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			if err := req.Validate(); err != nil {
+				t.Fatalf("invalid fixture: %v", err)
+			}
+			result, err := s.GenerateProject(context.Background(), req)
+			if err != nil {
+				t.Fatalf("GenerateProject() error = %v", err)
+			}
+			got := make(map[string]string)
+			names, _ := result.files.Files()
+			for _, name := range names {
+				data, _ := result.files.ReadFile(name)
+				got[name] = string(data)
+			}
+
+			goldenFiles := filepath.Join(dir, "files")
+			if *update {
+				writeGoldenFiles(t, goldenFiles, got)
+				return
+			}
+			want := readGoldenFiles(t, goldenFiles)
+			for _, name := range sortedKeys(want) {
+				if _, ok := got[name]; !ok {
+					t.Errorf("%s is no longer generated", name)
+				}
+			}
+			for _, name := range sortedKeys(got) {
+				golden, ok := want[name]
+				if !ok {
+					t.Errorf("%s is generated but has no golden file:\n%s", name, unifiedDiff("/dev/null", name, "", got[name]))
+					continue
+				}
+				if diff := unifiedDiff("golden/"+name, "generated/"+name, golden, got[name]); diff != "" {
+					t.Errorf("%s differs from its golden file:\n%s", name, diff)
+				}
+			}
+		})
+	}
+}
+
+func readGoldenRequest(t *testing.T, dir string) *GenerateRequest {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "request.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &GenerateRequest{}
+	if err := json.Unmarshal(data, req); err != nil {
+		t.Fatalf("%s: %v", dir, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "openapi.yaml")); err == nil {
+		req.OpenAPI = string(data)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "schema.sql")); err == nil {
+		req.SQL = string(data)
+	}
+	return req
+}
+
+func readGoldenFiles(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[strings.TrimSuffix(filepath.ToSlash(rel), ".golden")] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read golden files (run with -update to create them): %v", err)
+	}
+	return files
+}
+
+func writeGoldenFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name)+".golden")
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// unifiedDiff returns the unified diff, with three lines of context, that turns a into b,
+// or "" if they are equal
+func unifiedDiff(nameA, nameB, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		// A hunk runs from context lines before this change to context lines after the last
+		// change that follows within 2*context unchanged lines
+		last := k
+		for e := k; e < len(ops) && e-last <= 2*context; e++ {
+			if ops[e].kind != ' ' {
+				last = e
+			}
+		}
+		start, end := max(0, k-context), min(len(ops), last+context+1)
+
+		countA, countB := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", ops[start].a+1, countA, ops[start].b+1, countB)
+		for _, op := range ops[start:end] {
+			out.WriteString(string(op.kind) + op.line + "\n")
+		}
+		k = end
+	}
+	return out.String()
+}
+
+// diffOp is a line kept (' '), removed ('-') or added ('+'); a and b are the line indexes
+// in the two texts at which it occurs
+type diffOp struct {
+	kind byte
+	line string
+	a, b int
+}
+
+// diffLines computes a shortest edit script from the longest common subsequence of the
+// lines, after setting aside their common prefix and suffix
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the common subsequence of midA[i:] and midB[j:]
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{' ', a[i], i, i})
+	}
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			ops = append(ops, diffOp{' ', midA[i], prefix + i, prefix + j})
+			i++
+			j++
+		case i < len(midA) && (j == len(midB) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', midA[i], prefix + i, prefix + j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', midB[j], prefix + i, prefix + j})
+			j++
+		}
+	}
+	for k := 0; k < suffix; k++ {
+		ops = append(ops, diffOp{' ', a[len(a)-suffix+k], len(a) - suffix + k, len(b) - suffix + k})
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
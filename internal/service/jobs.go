@@ -0,0 +1,206 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// Job statuses
+const (
+	JobQueued    = "queued"    // waiting for a generation worker
+	JobRunning   = "running"   // generating; Stage names the current stage
+	JobSucceeded = "succeeded" // the archive can be downloaded
+	JobFailed    = "failed"    // Error tells why
+)
+
+// Job is an asynchronous generation
+type Job struct {
+	ID          string           `json:"id"`
+	Status      string           `json:"status"`
+	Stage       string           `json:"stage,omitempty"`
+	ProjectName string           `json:"projectName"`
+	Framework   string           `json:"framework"`
+	Error       *JobError        `json:"error,omitempty"`
+	Warnings    []models.Warning `json:"warnings,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	StartedAt   *time.Time       `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time       `json:"finishedAt,omitempty"`
+	// ExpiresAt is when a finished job, and its archive, is removed
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	// Result is the generated project of a succeeded job
+	Result *GenerateResult `json:"-"`
+}
+
+// JobError is the error of a failed job
+type JobError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// JobStore keeps jobs in memory. Finished jobs are removed once their TTL has passed; at
+// most maxJobs are kept at a time.
+type JobStore struct {
+	mu      sync.RWMutex
+	jobs    map[string]*Job
+	ttl     time.Duration
+	maxJobs int
+	now     func() time.Time
+	cleanup *time.Ticker
+}
+
+// NewJobStore creates a job store keeping finished jobs for ttl
+func NewJobStore(ttl time.Duration, maxJobs int) *JobStore {
+	s := &JobStore{
+		jobs:    make(map[string]*Job),
+		ttl:     ttl,
+		maxJobs: maxJobs,
+		now:     time.Now,
+	}
+
+	// Remove expired jobs every minute, or every TTL if that is shorter
+	interval := time.Minute
+	if ttl > 0 && ttl < interval {
+		interval = ttl
+	}
+	s.cleanup = time.NewTicker(interval)
+	go s.cleanupExpired()
+
+	return s
+}
+
+// Create adds a queued job for a request and returns a copy of it. It fails with a busy
+// error when the store is full.
+func (s *JobStore) Create(req *GenerateRequest) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeExpired()
+	if s.maxJobs > 0 && len(s.jobs) >= s.maxJobs {
+		return nil, errors.ErrBusy("Too many jobs, please try again later", nil).
+			WithContext("max_jobs", s.maxJobs)
+	}
+
+	job := &Job{
+		ID:          uuid.New().String(),
+		Status:      JobQueued,
+		ProjectName: req.ProjectName,
+		Framework:   req.Framework,
+		CreatedAt:   s.now().UTC(),
+	}
+	s.jobs[job.ID] = job
+	return job.copy(), nil
+}
+
+// Get returns a copy of a job
+func (s *JobStore) Get(id string) (*Job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok || s.expired(job) {
+		return nil, false
+	}
+	return job.copy(), true
+}
+
+// Start marks a job as running
+func (s *JobStore) Start(id string) {
+	s.update(id, func(job *Job) {
+		now := s.now().UTC()
+		job.Status = JobRunning
+		job.StartedAt = &now
+	})
+}
+
+// SetStage records the stage a running job has reached
+func (s *JobStore) SetStage(id, stage string) {
+	s.update(id, func(job *Job) {
+		job.Stage = stage
+	})
+}
+
+// Succeed finishes a job with its generated project
+func (s *JobStore) Succeed(id string, result *GenerateResult) {
+	s.update(id, func(job *Job) {
+		job.Status = JobSucceeded
+		job.Stage = ""
+		job.Warnings = result.Warnings
+		job.Result = result
+		s.finish(job)
+	})
+}
+
+// Fail finishes a job with an error
+func (s *JobStore) Fail(id string, err error) {
+	s.update(id, func(job *Job) {
+		job.Status = JobFailed
+		job.Error = NewJobError(err)
+		s.finish(job)
+	})
+}
+
+// NewJobError returns the code and user-facing message of an error; other errors than
+// AppErrors are generation errors
+func NewJobError(err error) *JobError {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return &JobError{Code: appErr.Code, Message: appErr.Message}
+	}
+	return &JobError{Code: errors.ErrCodeGeneration, Message: err.Error()}
+}
+
+func (s *JobStore) finish(job *Job) {
+	now := s.now().UTC()
+	expires := now.Add(s.ttl)
+	job.FinishedAt = &now
+	job.ExpiresAt = &expires
+}
+
+func (s *JobStore) update(id string, fn func(job *Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func (s *JobStore) expired(job *Job) bool {
+	return job.ExpiresAt != nil && !s.now().Before(*job.ExpiresAt)
+}
+
+// removeExpired removes the finished jobs whose TTL has passed; s.mu must be held
+func (s *JobStore) removeExpired() {
+	for id, job := range s.jobs {
+		if s.expired(job) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// cleanupExpired periodically removes expired jobs
+func (s *JobStore) cleanupExpired() {
+	for range s.cleanup.C {
+		s.mu.Lock()
+		s.removeExpired()
+		s.mu.Unlock()
+	}
+}
+
+// Stop stops the cleanup goroutine
+func (s *JobStore) Stop() {
+	if s.cleanup != nil {
+		s.cleanup.Stop()
+	}
+}
+
+// copy returns a copy of the job; the result and warnings are shared, as they are not
+// changed once set
+func (j *Job) copy() *Job {
+	c := *j
+	return &c
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+func TestJobStore(t *testing.T) {
+	store := NewJobStore(time.Minute, 2)
+	defer store.Stop()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	req := &GenerateRequest{ProjectName: "demo", Framework: "gin"}
+	succeeded, err := store.Create(req)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if succeeded.Status != JobQueued || succeeded.ProjectName != "demo" {
+		t.Errorf("Create() = %+v, want a queued job for demo", succeeded)
+	}
+
+	store.Start(succeeded.ID)
+	store.SetStage(succeeded.ID, "framework")
+	job, _ := store.Get(succeeded.ID)
+	if job.Status != JobRunning || job.Stage != "framework" || job.StartedAt == nil {
+		t.Errorf("running job = %+v", job)
+	}
+
+	store.Succeed(succeeded.ID, &GenerateResult{})
+	job, _ = store.Get(succeeded.ID)
+	if job.Status != JobSucceeded || job.Stage != "" || job.Result == nil || !job.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("succeeded job = %+v", job)
+	}
+
+	failed, _ := store.Create(req)
+	store.Fail(failed.ID, errors.ErrTimeout("Project generation timed out", nil))
+	job, _ = store.Get(failed.ID)
+	if job.Status != JobFailed || job.Error == nil || job.Error.Code != errors.ErrCodeTimeout {
+		t.Errorf("failed job = %+v", job)
+	}
+
+	// The store is full until the finished jobs expire
+	if _, err := store.Create(req); err == nil {
+		t.Errorf("Create() on a full store succeeded")
+	}
+	now = now.Add(time.Minute)
+	if _, ok := store.Get(succeeded.ID); ok {
+		t.Errorf("Get() returned an expired job")
+	}
+	if _, err := store.Create(req); err != nil {
+		t.Errorf("Create() after expiry error = %v", err)
+	}
+	if len(store.jobs) != 1 {
+		t.Errorf("store keeps %d jobs, want 1", len(store.jobs))
+	}
+}
@@ -1,121 +1,262 @@
 package service
 
 import (
-	"path/filepath"
+	"fmt"
+	"path"
+	"strings"
 
 	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
 )
 
-// renderDomainLayer renders the domain layer templates
-func (s *GeneratorService) renderDomainLayer(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	outPath := filepath.Join(tmp, constants.DirInternalDomain, "entity.go")
-	data := map[string]interface{}{
-		"ModuleName": req.ModuleName,
-		"Includes":   includes,
+// Names of the files rendered into the architecture layers: once per package, or per entity
+// with a suffix after the entity's snake_case name
+const (
+	fileCache           = "cache.go"
+	fileErrors          = "errors.go"
+	fileCacheRepository = "cache_repository.go"
+	fileResponse        = "response.go"
+	fileExampleJob      = "example_job.go"
+
+	suffixModel            = "_model"
+	suffixRepository       = "_repository"
+	suffixUsecase          = "_usecase"
+	suffixHandler          = "_handler"
+	suffixRabbitMQConsumer = "_rabbitmq_consumer"
+	suffixKafkaConsumer    = "_kafka_consumer"
+	suffixActiveMQConsumer = "_activemq_consumer"
+)
+
+// packageFiles lists the files rendered once per package of each layer
+var packageFiles = map[string][]string{
+	constants.LayerDomain:     {fileCache},
+	constants.LayerErrors:     {fileErrors},
+	constants.LayerRepository: {fileCacheRepository},
+	constants.LayerHandler:    {fileResponse},
+	constants.LayerJob:        {fileExampleJob},
+}
+
+// entitySuffixes lists the suffixes of the files rendered per entity in each layer
+var entitySuffixes = map[string][]string{
+	constants.LayerDomain:     {""},
+	constants.LayerModels:     {suffixModel},
+	constants.LayerRepository: {suffixRepository},
+	constants.LayerUsecase:    {suffixUsecase},
+	constants.LayerHandler:    {suffixHandler},
+	constants.LayerConsumer:   {suffixRabbitMQConsumer, suffixKafkaConsumer, suffixActiveMQConsumer},
+}
+
+// validateEntityFiles checks that no entity file of the request's architecture lands on a file
+// of another entity or on a file shared by a layer package, e.g. an entity named Response in
+// a flat project, whose domain file would replace the handlers' response.go
+func (s *GeneratorService) validateEntityFiles(req *GenerateRequest) error {
+	entities := req.ResolvedEntities()
+	owners := make(map[string]string)
+	for _, def := range entities {
+		for _, layer := range architectureLayers {
+			for _, name := range packageFiles[layer] {
+				owners[path.Join(s.layerDir(req, layer, snakeCase(def.Name)), name)] = "the " + layer + " layer"
+			}
+		}
+	}
+
+	for _, def := range entities {
+		snake := snakeCase(def.Name)
+		for _, layer := range architectureLayers {
+			for _, suffix := range entitySuffixes[layer] {
+				file := path.Join(s.layerDir(req, layer, snake), snake+suffix+constants.GoFileExtension)
+				if owner, ok := owners[file]; ok {
+					return errors.ErrValidation(fmt.Sprintf("entity %s cannot be generated with the %s architecture: %s is also written for %s",
+						def.Name, architectureName(req), file, owner), nil).
+						WithContext("entity", def.Name)
+				}
+				owners[file] = "entity " + def.Name
+			}
+		}
+	}
+	return nil
+}
+
+// layerData builds the template data for a file rendered into the given layer of a module.
+// uses lists the other layers the file references, so their imports can be emitted.
+func (s *GeneratorService) layerData(req *GenerateRequest, layer, module string, includes map[string]bool, uses ...string) map[string]interface{} {
+	refs := s.layerRefs(req, layer, module)
+	return map[string]interface{}{
+		"ModuleName":   req.ModuleName,
+		"Includes":     includes,
+		"Package":      refs[layer].Package,
+		"Layers":       refs,
+		"LayerImports": refs.importsFor(uses...),
+	}
+}
+
+// entityData builds the template data for a file of one entity.
+// The first entity is the primary one: it also hosts the consumer and external API examples.
+func (s *GeneratorService) entityData(req *GenerateRequest, entity EntityView, primary bool, layer string, includes map[string]bool, uses ...string) map[string]interface{} {
+	data := s.layerData(req, layer, entity.Module, includes, uses...)
+	data["Entity"] = entity
+	data["Primary"] = primary
+	data["Framework"] = req.Framework
+	return data
+}
+
+// entityFile returns the output path of an entity file in the given layer
+func (s *GeneratorService) entityFile(req *GenerateRequest, entity EntityView, layer, suffix string) string {
+	return path.Join(s.layerDir(req, layer, entity.Module), entity.Snake+suffix+constants.GoFileExtension)
+}
+
+// packageEntities returns one entity per distinct package of the layer,
+// so package-level files (errors, cache, responses) are rendered once per package
+func (s *GeneratorService) packageEntities(req *GenerateRequest, entities []EntityView, layer string) []EntityView {
+	seen := make(map[string]bool)
+	var result []EntityView
+	for _, entity := range entities {
+		dir := s.layerDir(req, layer, entity.Module)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		result = append(result, entity)
 	}
-	return s.renderTemplate(constants.TemplateDomainEntity, outPath, data)
+	return result
+}
+
+// renderDomainLayer renders the domain entities and the cache port
+func (s *GeneratorService) renderDomainLayer(out outputSink, req *GenerateRequest, entities []EntityView, includes map[string]bool) error {
+	for i, entity := range entities {
+		outPath := s.entityFile(req, entity, constants.LayerDomain, "")
+		data := s.entityData(req, entity, i == 0, constants.LayerDomain, includes)
+		if err := s.renderTemplate(out, constants.TemplateDomainEntity, outPath, data); err != nil {
+			return err
+		}
+	}
+
+	for _, entity := range s.packageEntities(req, entities, constants.LayerDomain) {
+		outPath := path.Join(s.layerDir(req, constants.LayerDomain, entity.Module), fileCache)
+		data := s.layerData(req, constants.LayerDomain, entity.Module, includes)
+		if err := s.renderTemplate(out, constants.TemplateDomainCache, outPath, data); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // renderErrorsLayer renders the errors package
-func (s *GeneratorService) renderErrorsLayer(tmp string, req *GenerateRequest) error {
-	outPath := filepath.Join(tmp, constants.DirInternalErrors, "errors.go")
-	data := map[string]interface{}{
-		"ModuleName": req.ModuleName,
+func (s *GeneratorService) renderErrorsLayer(out outputSink, req *GenerateRequest, entities []EntityView) error {
+	for _, entity := range s.packageEntities(req, entities, constants.LayerErrors) {
+		outPath := path.Join(s.layerDir(req, constants.LayerErrors, entity.Module), fileErrors)
+		data := s.layerData(req, constants.LayerErrors, entity.Module, nil)
+		if err := s.renderTemplate(out, constants.TemplateErrors, outPath, data); err != nil {
+			return err
+		}
 	}
-	return s.renderTemplate(constants.TemplateErrors, outPath, data)
+	return nil
 }
 
 // renderModelsLayer renders the database models (infrastructure layer)
-func (s *GeneratorService) renderModelsLayer(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	outPath := filepath.Join(tmp, constants.DirInternalRepoModels, "user_model.go")
-	data := map[string]interface{}{
-		"ModuleName": req.ModuleName,
-		"Includes":   includes,
+func (s *GeneratorService) renderModelsLayer(out outputSink, req *GenerateRequest, entities []EntityView, includes map[string]bool) error {
+	for i, entity := range entities {
+		outPath := s.entityFile(req, entity, constants.LayerModels, suffixModel)
+		data := s.entityData(req, entity, i == 0, constants.LayerModels, includes, constants.LayerDomain)
+		if err := s.renderTemplate(out, constants.TemplateEntityModel, outPath, data); err != nil {
+			return err
+		}
 	}
-	return s.renderTemplate(constants.TemplateUserModel, outPath, data)
+	return nil
 }
 
 // renderRepositoryLayer renders the repository layer templates
-func (s *GeneratorService) renderRepositoryLayer(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	// Render user repository
-	userRepoPath := filepath.Join(tmp, constants.DirInternalRepo, "user_repository.go")
-	userRepoData := map[string]interface{}{
-		"ModuleName": req.ModuleName,
-		"Includes":   includes,
-	}
-	if err := s.renderTemplate(constants.TemplateUserRepo, userRepoPath, userRepoData); err != nil {
-		return err
+func (s *GeneratorService) renderRepositoryLayer(out outputSink, req *GenerateRequest, entities []EntityView, includes map[string]bool) error {
+	for i, entity := range entities {
+		outPath := s.entityFile(req, entity, constants.LayerRepository, suffixRepository)
+		data := s.entityData(req, entity, i == 0, constants.LayerRepository, includes,
+			constants.LayerDomain, constants.LayerErrors, constants.LayerModels)
+		if err := s.renderTemplate(out, constants.TemplateEntityRepo, outPath, data); err != nil {
+			return err
+		}
 	}
 
 	// Render cache repository
-	cacheRepoPath := filepath.Join(tmp, constants.DirInternalRepo, "cache_repository.go")
-	cacheRepoData := map[string]interface{}{
-		"ModuleName": req.ModuleName,
-		"Includes":   includes,
+	for _, entity := range s.packageEntities(req, entities, constants.LayerRepository) {
+		outPath := path.Join(s.layerDir(req, constants.LayerRepository, entity.Module), fileCacheRepository)
+		data := s.layerData(req, constants.LayerRepository, entity.Module, includes,
+			constants.LayerDomain, constants.LayerErrors)
+		if err := s.renderTemplate(out, constants.TemplateCacheRepo, outPath, data); err != nil {
+			return err
+		}
 	}
-	return s.renderTemplate(constants.TemplateCacheRepo, cacheRepoPath, cacheRepoData)
+	return nil
 }
 
 // renderUsecaseLayer renders the usecase layer templates
-func (s *GeneratorService) renderUsecaseLayer(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	outPath := filepath.Join(tmp, constants.DirInternalUsecase, "user_usecase.go")
-	data := map[string]interface{}{
-		"ModuleName": req.ModuleName,
-		"Includes":   includes,
+func (s *GeneratorService) renderUsecaseLayer(out outputSink, req *GenerateRequest, entities []EntityView, includes map[string]bool) error {
+	for i, entity := range entities {
+		outPath := s.entityFile(req, entity, constants.LayerUsecase, suffixUsecase)
+		data := s.entityData(req, entity, i == 0, constants.LayerUsecase, includes,
+			constants.LayerDomain, constants.LayerErrors)
+		if err := s.renderTemplate(out, constants.TemplateEntityUsecase, outPath, data); err != nil {
+			return err
+		}
 	}
-	return s.renderTemplate(constants.TemplateUserUsecase, outPath, data)
+	return nil
 }
 
 // renderHandlerLayer renders the handler layer templates
-func (s *GeneratorService) renderHandlerLayer(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	outPath := filepath.Join(tmp, constants.DirInternalHandler, "user_handler.go")
-	data := map[string]interface{}{
-		"ModuleName": req.ModuleName,
-		"Framework":  req.Framework,
-		"Includes":   includes,
+func (s *GeneratorService) renderHandlerLayer(out outputSink, req *GenerateRequest, entities []EntityView, includes map[string]bool) error {
+	for i, entity := range entities {
+		outPath := s.entityFile(req, entity, constants.LayerHandler, suffixHandler)
+		data := s.entityData(req, entity, i == 0, constants.LayerHandler, includes,
+			constants.LayerDomain, constants.LayerErrors, constants.LayerUsecase)
+		if err := s.renderTemplate(out, constants.TemplateEntityHandler, outPath, data); err != nil {
+			return err
+		}
+	}
+
+	// Render the response helpers shared by the handlers of a package
+	for _, entity := range s.packageEntities(req, entities, constants.LayerHandler) {
+		outPath := path.Join(s.layerDir(req, constants.LayerHandler, entity.Module), fileResponse)
+		data := s.layerData(req, constants.LayerHandler, entity.Module, includes, constants.LayerErrors)
+		data["Framework"] = req.Framework
+		if err := s.renderTemplate(out, constants.TemplateHandlerResponse, outPath, data); err != nil {
+			return err
+		}
 	}
-	return s.renderTemplate(constants.TemplateUserHandler, outPath, data)
+	return nil
 }
 
 // renderJobsLayer renders the scheduled jobs layer templates (Input Adapter: Jobs)
-func (s *GeneratorService) renderJobsLayer(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	data := map[string]interface{}{
-		"ModuleName": req.ModuleName,
-		"Includes":   includes,
-	}
+func (s *GeneratorService) renderJobsLayer(out outputSink, req *GenerateRequest, primary EntityView, includes map[string]bool) error {
+	data := s.layerData(req, constants.LayerJob, primary.Module, includes)
 
 	// Render example job (Adapter: Scheduled Jobs)
-	jobPath := filepath.Join(tmp, constants.DirInternalJob, "example_job.go")
-	return s.renderTemplate(constants.TemplateExampleJob, jobPath, data)
+	jobPath := path.Join(s.layerDir(req, constants.LayerJob, primary.Module), fileExampleJob)
+	return s.renderTemplate(out, constants.TemplateExampleJob, jobPath, data)
 }
 
-// renderConsumersLayer renders the message queue consumers layer templates (Input Adapter: Consumers)
-func (s *GeneratorService) renderConsumersLayer(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	data := map[string]interface{}{
-		"ModuleName": req.ModuleName,
-		"Includes":   includes,
-	}
+// renderConsumersLayer renders the message queue consumers of the primary entity (Input Adapter: Consumers)
+func (s *GeneratorService) renderConsumersLayer(out outputSink, req *GenerateRequest, primary EntityView, includes map[string]bool) error {
+	data := s.entityData(req, primary, true, constants.LayerConsumer, includes, constants.LayerUsecase)
 
 	// Render RabbitMQ consumer if RabbitMQ is included (Adapter: Message Consumer)
 	if includes["rabbitmq"] {
-		rabbitPath := filepath.Join(tmp, constants.DirInternalConsumer, "user_rabbitmq_consumer.go")
-		if err := s.renderTemplate(constants.TemplateRabbitMQConsumer, rabbitPath, data); err != nil {
+		rabbitPath := s.entityFile(req, primary, constants.LayerConsumer, suffixRabbitMQConsumer)
+		if err := s.renderTemplate(out, constants.TemplateRabbitMQConsumer, rabbitPath, data); err != nil {
 			return err
 		}
 	}
 
 	// Render Kafka consumer if Kafka is included (Adapter: Message Consumer)
 	if includes["kafka"] {
-		kafkaPath := filepath.Join(tmp, constants.DirInternalConsumer, "user_kafka_consumer.go")
-		if err := s.renderTemplate(constants.TemplateKafkaConsumer, kafkaPath, data); err != nil {
+		kafkaPath := s.entityFile(req, primary, constants.LayerConsumer, suffixKafkaConsumer)
+		if err := s.renderTemplate(out, constants.TemplateKafkaConsumer, kafkaPath, data); err != nil {
 			return err
 		}
 	}
 
 	// Render ActiveMQ consumer if ActiveMQ is included (Adapter: Message Consumer)
 	if includes["activemq"] {
-		activemqPath := filepath.Join(tmp, constants.DirInternalConsumer, "user_activemq_consumer.go")
-		if err := s.renderTemplate(constants.TemplateActiveMQConsumer, activemqPath, data); err != nil {
+		activemqPath := s.entityFile(req, primary, constants.LayerConsumer, suffixActiveMQConsumer)
+		if err := s.renderTemplate(out, constants.TemplateActiveMQConsumer, activemqPath, data); err != nil {
 			return err
 		}
 	}
@@ -123,51 +264,154 @@ func (s *GeneratorService) renderConsumersLayer(tmp string, req *GenerateRequest
 	return nil
 }
 
+// renderAPILayer renders the DTOs, handlers and operation stubs generated from the OpenAPI document
+func (s *GeneratorService) renderAPILayer(out outputSink, req *GenerateRequest, api *APIView, includes map[string]bool) error {
+	data := map[string]interface{}{
+		"ModuleName": req.ModuleName,
+		"Framework":  req.Framework,
+		"Includes":   includes,
+		"API":        api,
+	}
+	files := []struct{ template, name string }{
+		{constants.TemplateAPITypes, "types.go"},
+		{constants.TemplateAPIHandler, "handler.go"},
+		{constants.TemplateAPIOperations, "operations.go"},
+	}
+	for _, f := range files {
+		if err := s.renderTemplate(out, f.template, path.Join(api.Dir, f.name), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderProtoLayer renders the proto file of the gRPC services, its committed Go stubs
+// and the buf configuration that regenerates them
+func (s *GeneratorService) renderProtoLayer(out outputSink, req *GenerateRequest, proto *ProtoView) error {
+	data := map[string]interface{}{
+		"ModuleName": req.ModuleName,
+		"Proto":      proto,
+	}
+	if err := s.renderTemplate(out, constants.TemplateProto, path.Join(constants.DirProto, proto.File), data); err != nil {
+		return err
+	}
+	if err := s.renderTemplate(out, constants.TemplateBuf, constants.BufFileName, data); err != nil {
+		return err
+	}
+	if err := s.renderTemplate(out, constants.TemplateBufGen, constants.BufGenFileName, data); err != nil {
+		return err
+	}
+
+	// Stubs sit next to each other in gen/, mirroring the proto tree (paths=source_relative)
+	stubs := path.Join(constants.DirGen, strings.TrimSuffix(proto.File, ".proto"))
+	if err := out.WriteFile(stubs+".pb.go", proto.Stubs); err != nil {
+		return errors.ErrFileSystem("Failed to write protobuf stubs", err).
+			WithContext("path", stubs+".pb.go")
+	}
+
+	// The service stubs come from a template; the Go files are formatted once rendering is done
+	return s.renderTemplate(out, constants.TemplateProtoGRPC, stubs+"_grpc.pb.go", data)
+}
+
 // renderAppServer renders the app server templates
-func (s *GeneratorService) renderAppServer(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	outPath := filepath.Join(tmp, constants.DirInternalApp, "server.go")
+func (s *GeneratorService) renderAppServer(out outputSink, req *GenerateRequest, entities []EntityView, api *APIView, proto *ProtoView, includes map[string]bool) error {
+	outPath := path.Join(constants.DirInternalApp, "server.go")
+	hasRoutes := len(entities) > 0 || api != nil
 	data := map[string]interface{}{
-		"ModuleName":     req.ModuleName,
-		"ProjectName":    req.ProjectName,
-		"Framework":      req.Framework,
-		"Includes":       includes,
-		"IncludeExample": req.IncludeExample,
+		"ModuleName":  req.ModuleName,
+		"ProjectName": req.ProjectName,
+		"Framework":   req.Framework,
+		"Includes":    includes,
+		"HasRoutes":   hasRoutes,
+		"Entities":    entities,
+		"API":         api,
+		"Proto":       proto,
+		"Primary":     nil,
+	}
+	if len(entities) > 0 {
+		data["Primary"] = entities[0]
 	}
 
-	// Use example server template if IncludeExample is true, otherwise use simple server
+	// Use the full server template when there are routes to register, otherwise use simple server
 	templatePath := constants.TemplateServerSimple
-	if req.IncludeExample {
+	if hasRoutes {
 		templatePath = constants.TemplateServer
 	}
 
 	// Render server.go
-	if err := s.renderTemplate(templatePath, outPath, data); err != nil {
+	if err := s.renderTemplate(out, templatePath, outPath, data); err != nil {
 		return err
 	}
 
 	// Render the centralized routes file for the app (RegisterRoutes)
 	routePath := constants.TemplateRoutesSample
-	if req.IncludeExample {
+	if hasRoutes {
 		routePath = constants.TemplateRoutes
 	}
-	routesOut := filepath.Join(tmp, constants.DirInternalApp, "routes.go")
+	routesOut := path.Join(constants.DirInternalApp, "routes.go")
 	routesData := map[string]interface{}{
 		"ModuleName": req.ModuleName,
 		"Framework":  req.Framework,
+		"Entities":   entities,
+		"API":        api,
+		"Proto":      proto,
 	}
-	if err := s.renderTemplate(routePath, routesOut, routesData); err != nil {
+	if err := s.renderTemplate(out, routePath, routesOut, routesData); err != nil {
 		return err
 	}
 
+	// Render the REST facade of the gRPC services
+	if proto != nil && includes[constants.LibGRPCGateway] {
+		gatewayOut := path.Join(constants.DirInternalApp, "gateway.go")
+		if err := s.renderTemplate(out, constants.TemplateGateway, gatewayOut, data); err != nil {
+			return err
+		}
+	}
+
 	// Render bootstrap that initializes repositories/usecases/handlers
 	bootstrapPath := constants.TemplateBootstrapSample
-	if req.IncludeExample {
+	if hasRoutes {
 		bootstrapPath = constants.TemplateBootstrap
+		data["LayerImports"] = bootstrapImports(entities, api, includes)
 	}
-	bootstrapOut := filepath.Join(tmp, constants.DirInternalApp, "bootstrap.go")
-	if err := s.renderTemplate(bootstrapPath, bootstrapOut, data); err != nil {
+	bootstrapOut := path.Join(constants.DirInternalApp, "bootstrap.go")
+	if err := s.renderTemplate(out, bootstrapPath, bootstrapOut, data); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// bootstrapImports returns the de-duplicated layer imports of the bootstrap file
+func bootstrapImports(entities []EntityView, api *APIView, includes map[string]bool) []string {
+	seen := make(map[string]bool)
+	var imports []string
+	add := func(refs LayerRefs, layers ...string) {
+		for _, imp := range refs.importsFor(layers...) {
+			if !seen[imp] {
+				seen[imp] = true
+				imports = append(imports, imp)
+			}
+		}
+	}
+
+	for _, entity := range entities {
+		add(entity.Layers, constants.LayerHandler, constants.LayerDomain, constants.LayerRepository, constants.LayerUsecase)
+	}
+
+	// Jobs and consumers live next to the primary entity
+	if len(entities) > 0 {
+		primary := entities[0].Layers
+		if includes["cron"] {
+			add(primary, constants.LayerJob)
+		}
+		if includes["rabbitmq"] || includes["kafka"] || includes["activemq"] {
+			add(primary, constants.LayerConsumer)
+		}
+	}
+
+	if api != nil {
+		imports = append(imports, api.Import)
+	}
+	return imports
+}
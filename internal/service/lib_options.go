@@ -0,0 +1,193 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// validateLibOptions checks the request's libOptions against the options schema of each lib
+func (s *GeneratorService) validateLibOptions(req *GenerateRequest) error {
+	for _, lib := range sortedKeys(req.LibOptions) {
+		schema := s.manifest.Libs[lib].Options
+		for _, name := range sortedKeys(req.LibOptions[lib]) {
+			def, ok := schema[name]
+			if !ok {
+				msg := fmt.Sprintf("libOptions.%s.%s is not an option of %s", lib, name, lib)
+				if len(schema) > 0 {
+					msg += fmt.Sprintf(" (valid options: %s)", strings.Join(sortedKeys(schema), ", "))
+				}
+				return errors.ErrValidation(msg, nil).
+					WithContext("library", lib).
+					WithContext("option", name)
+			}
+			if _, err := libOptionValue(def, req.LibOptions[lib][name]); err != nil {
+				return errors.ErrValidation(fmt.Sprintf("libOptions.%s.%s %v", lib, name, err), nil).
+					WithContext("library", lib).
+					WithContext("option", name)
+			}
+		}
+	}
+	return nil
+}
+
+// libOptions returns the options of a lib for its templates: the supplied values over the
+// defaults of the lib's options schema
+func (s *GeneratorService) libOptions(req *GenerateRequest, lib string) map[string]interface{} {
+	schema := s.manifest.Libs[lib].Options
+	options := make(map[string]interface{}, len(schema))
+	for name, def := range schema {
+		if def.Default == nil {
+			continue
+		}
+		if v, err := libOptionValue(def, def.Default); err == nil {
+			options[name] = v
+		}
+	}
+	for name, v := range req.LibOptions[lib] {
+		if v, err := libOptionValue(schema[name], v); err == nil {
+			options[name] = v
+		}
+	}
+	return options
+}
+
+// libOptionsLayer returns the config values set by the supplied options of a lib.
+// ok is false when none of them sets a config key.
+func (s *GeneratorService) libOptionsLayer(req *GenerateRequest, lib string) (layer configLayer, ok bool) {
+	schema := s.manifest.Libs[lib].Options
+	tree := make(map[string]interface{})
+	for _, name := range sortedKeys(req.LibOptions[lib]) {
+		def := schema[name]
+		if def.Config == "" {
+			continue
+		}
+		v, err := libOptionValue(def, req.LibOptions[lib][name])
+		if err != nil {
+			continue
+		}
+		setConfigKey(tree, def.Config, v)
+	}
+	if len(tree) == 0 {
+		return configLayer{}, false
+	}
+	return configLayer{Source: "libOptions." + lib, Level: configLevelRequest, Tree: tree}, true
+}
+
+// setConfigKey sets a dotted key in a config tree, creating the sections on its path
+func setConfigKey(tree map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := tree[part].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			tree[part] = sub
+		}
+		tree = sub
+	}
+	tree[parts[len(parts)-1]] = value
+}
+
+// libOptionValue checks an option value against its definition and converts it to the
+// form config files and templates use: int64 for ints and []interface{} for string lists
+func libOptionValue(def models.LibOptionDef, v interface{}) (interface{}, error) {
+	switch def.Type {
+	case constants.OptionTypeString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		if len(def.Enum) > 0 && !containsString(def.Enum, s) {
+			return nil, fmt.Errorf("must be one of: %s", strings.Join(def.Enum, ", "))
+		}
+		return s, nil
+
+	case constants.OptionTypeInt:
+		i, ok := optionInt(v)
+		if !ok {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		if def.Min != nil && i < *def.Min {
+			return nil, fmt.Errorf("must be at least %d", *def.Min)
+		}
+		if def.Max != nil && i > *def.Max {
+			return nil, fmt.Errorf("must be at most %d", *def.Max)
+		}
+		return i, nil
+
+	case constants.OptionTypeBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return b, nil
+
+	case constants.OptionTypeStringList:
+		var items []interface{}
+		switch typed := v.(type) {
+		case []interface{}:
+			items = typed
+		case []string:
+			for _, item := range typed {
+				items = append(items, item)
+			}
+		default:
+			return nil, fmt.Errorf("must be a list of strings")
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("must be a list of non-empty strings")
+			}
+			list[i] = s
+		}
+		return list, nil
+
+	case constants.OptionTypeDuration:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a duration string such as 30s or 5m")
+		}
+		if _, err := time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("must be a duration string such as 30s or 5m")
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("has unknown type %q", def.Type)
+}
+
+// optionInt converts a decoded JSON number to an int64, rejecting fractions
+func optionInt(v interface{}) (int64, bool) {
+	switch typed := v.(type) {
+	case int:
+		return int64(typed), true
+	case int64:
+		return typed, true
+	case float64:
+		if typed != math.Trunc(typed) || math.Abs(typed) > math.MaxInt32 {
+			return 0, false
+		}
+		return int64(typed), true
+	case json.Number:
+		i, err := typed.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// resolveRequest adds the libs implied by the selected ones to a request and validates the
+// result. The request is not modified; a copy is returned when libs were added, with a
+// warning for each.
+func (s *GeneratorService) resolveRequest(req *GenerateRequest) (*GenerateRequest, []models.Warning, error) {
+	resolved, warnings := s.resolveLibs(req)
+	if err := s.validateRequest(resolved); err != nil {
+		return nil, nil, err
+	}
+	return resolved, warnings, nil
+}
+
+// resolveLibs adds the libs the selected ones imply, and the libs those imply in turn.
+// Unknown libs are left for validateRequest to report.
+func (s *GeneratorService) resolveLibs(req *GenerateRequest) (*GenerateRequest, []models.Warning) {
+	selected := make(map[string]bool, len(req.Libs))
+	for _, lib := range req.Libs {
+		selected[lib] = true
+	}
+
+	var added []string
+	var warnings []models.Warning
+	queue := append([]string(nil), req.Libs...)
+	for len(queue) > 0 {
+		lib := queue[0]
+		queue = queue[1:]
+		for _, implied := range s.manifest.Libs[lib].Implies {
+			if selected[implied] {
+				continue
+			}
+			selected[implied] = true
+			added = append(added, implied)
+			queue = append(queue, implied)
+			warnings = append(warnings, models.Warning{
+				Code:    constants.WarnImpliedLib,
+				Message: fmt.Sprintf("%s was added because %s implies it", implied, lib),
+			})
+		}
+	}
+	if len(added) == 0 {
+		return req, nil
+	}
+
+	resolved := *req
+	resolved.Libs = append(append([]string(nil), req.Libs...), added...)
+	return &resolved, warnings
+}
+
+// validateLibRules checks the selected libs against the requires, conflicts and group rules
+// of the manifest, reporting every rule that is broken
+func (s *GeneratorService) validateLibRules(req *GenerateRequest) error {
+	selected := make(map[string]bool, len(req.Libs))
+	for _, lib := range req.Libs {
+		selected[lib] = true
+	}
+	libs := sortedKeys(selected)
+
+	var violations []string
+	conflicts := make(map[string]bool)
+	groups := make(map[string][]string)
+	for _, lib := range libs {
+		def := s.manifest.Libs[lib]
+		for _, required := range def.Requires {
+			if _, ok := s.manifest.Frameworks[required]; ok {
+				if req.Framework != required {
+					violations = append(violations, fmt.Sprintf("%s requires the %s framework", lib, required))
+				}
+			} else if !selected[required] {
+				violations = append(violations, fmt.Sprintf("%s requires %s", lib, required))
+			}
+		}
+		for _, other := range def.Conflicts {
+			pair := []string{lib, other}
+			sort.Strings(pair)
+			if selected[other] && !conflicts[pair[0]+" "+pair[1]] {
+				conflicts[pair[0]+" "+pair[1]] = true
+				violations = append(violations, fmt.Sprintf("%s conflicts with %s", pair[0], pair[1]))
+			}
+		}
+		if def.Group != "" {
+			groups[def.Group] = append(groups[def.Group], lib)
+		}
+	}
+	for _, group := range sortedKeys(groups) {
+		if members := groups[group]; len(members) > 1 {
+			violations = append(violations, fmt.Sprintf("only one %s lib can be selected, got %s",
+				group, strings.Join(members, ", ")))
+		}
+	}
+
+	if len(violations) > 0 {
+		return errors.ErrValidation("invalid libs: "+strings.Join(violations, "; "), nil).
+			WithContext("violations", violations)
+	}
+	return nil
+}
+
+// validateLibRefs checks that the rules of a lib name other libs of the manifest and do not
+// contradict each other. requires may also name the one framework the lib is built for.
+func validateLibRefs(name string, l models.LibDef, libs map[string]models.LibDef, frameworks map[string]models.FrameworkDef) error {
+	if _, ok := frameworks[name]; ok {
+		return fmt.Errorf("the name is also a framework's, which requires could not tell apart")
+	}
+
+	var requiredFrameworks []string
+	requiredLibs := make([]string, 0, len(l.Requires))
+	for _, ref := range l.Requires {
+		if _, ok := frameworks[ref]; ok {
+			requiredFrameworks = append(requiredFrameworks, ref)
+		} else {
+			requiredLibs = append(requiredLibs, ref)
+		}
+	}
+	if len(requiredFrameworks) > 1 {
+		return fmt.Errorf("requires more than one framework: %s", strings.Join(requiredFrameworks, ", "))
+	}
+
+	rules := []struct {
+		kind string
+		refs []string
+	}{{"requires", requiredLibs}, {"conflicts", l.Conflicts}, {"implies", l.Implies}}
+	for _, rule := range rules {
+		kind := rule.kind
+		for _, ref := range rule.refs {
+			if ref == name {
+				return fmt.Errorf("%s cannot name the library itself", kind)
+			}
+			if _, ok := libs[ref]; !ok {
+				return fmt.Errorf("%s an unknown library: %s", kind, ref)
+			}
+		}
+	}
+
+	for _, ref := range append(requiredLibs, l.Implies...) {
+		if containsString(l.Conflicts, ref) || containsString(libs[ref].Conflicts, name) {
+			return fmt.Errorf("requires or implies %s, which conflicts with it", ref)
+		}
+		if l.Group != "" && libs[ref].Group == l.Group {
+			return fmt.Errorf("requires or implies %s of its own group %s", ref, l.Group)
+		}
+	}
+	return nil
+}
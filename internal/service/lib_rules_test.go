@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// rulesService returns a service whose manifest only declares libs with selection rules
+func rulesService() *GeneratorService {
+	return &GeneratorService{snapshot: &snapshot{manifest: &models.Manifest{
+		Frameworks: map[string]models.FrameworkDef{"gin": {}, "grpc": {}},
+		Libs: map[string]models.LibDef{
+			"mysql":    {Group: "database"},
+			"postgres": {Group: "database"},
+			"redis":    {},
+			"cron":     {},
+			"tracing":  {Implies: []string{"metrics"}},
+			"metrics":  {Implies: []string{"resty"}},
+			"resty":    {},
+			"worker":   {Requires: []string{"cron", "redis"}, Conflicts: []string{"resty"}},
+			"gateway":  {Requires: []string{"grpc"}},
+		},
+	}}}
+}
+
+func TestResolveLibs(t *testing.T) {
+	s := rulesService()
+
+	req := &GenerateRequest{Framework: "gin", Libs: []string{"redis", "tracing"}}
+	resolved, warnings := s.resolveLibs(req)
+	if want := []string{"redis", "tracing", "metrics", "resty"}; !reflect.DeepEqual(resolved.Libs, want) {
+		t.Errorf("resolveLibs() libs = %v, want %v", resolved.Libs, want)
+	}
+	if len(req.Libs) != 2 {
+		t.Errorf("resolveLibs() changed the request's libs to %v", req.Libs)
+	}
+	if len(warnings) != 2 || warnings[0].Code != constants.WarnImpliedLib || !strings.Contains(warnings[1].Message, "metrics implies") {
+		t.Errorf("resolveLibs() warnings = %v", warnings)
+	}
+
+	if resolved, warnings := s.resolveLibs(&GenerateRequest{Libs: []string{"redis"}}); len(resolved.Libs) != 1 || warnings != nil {
+		t.Errorf("resolveLibs() without implied libs = %v, %v", resolved.Libs, warnings)
+	}
+}
+
+func TestValidateLibRules(t *testing.T) {
+	s := rulesService()
+
+	tests := []struct {
+		name string
+		libs []string
+		want []string
+	}{
+		{"valid", []string{"postgres", "worker", "cron", "redis"}, nil},
+		{"group", []string{"postgres", "mysql"}, []string{"only one database lib can be selected, got mysql, postgres"}},
+		{"every rule", []string{"worker", "resty", "mysql", "postgres"}, []string{
+			"worker requires cron",
+			"worker requires redis",
+			"resty conflicts with worker",
+			"only one database lib can be selected, got mysql, postgres",
+		}},
+		{"implied conflict", []string{"tracing", "worker", "cron", "redis"}, []string{"resty conflicts with worker"}},
+		{"framework", []string{"gateway", "redis"}, []string{"gateway requires the grpc framework"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, _ := s.resolveLibs(&GenerateRequest{Framework: "gin", Libs: tt.libs})
+			err := s.validateLibRules(resolved)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("validateLibRules() error = %v", err)
+				}
+				return
+			}
+			appErr, ok := err.(*errors.AppError)
+			if !ok || appErr.Code != errors.ErrCodeValidation {
+				t.Fatalf("validateLibRules() error = %v, want a validation error", err)
+			}
+			if got := appErr.Context["violations"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLibRefs(t *testing.T) {
+	libs := map[string]models.LibDef{
+		"mysql":    {Group: "database"},
+		"postgres": {Group: "database"},
+		"redis":    {Conflicts: []string{"cron"}},
+		"cron":     {},
+	}
+	frameworks := map[string]models.FrameworkDef{"gin": {}, "grpc": {}}
+	tests := []struct {
+		name string
+		lib  models.LibDef
+		want string
+	}{
+		{"valid", models.LibDef{Requires: []string{"redis"}, Conflicts: []string{"mysql"}}, ""},
+		{"unknown", models.LibDef{Implies: []string{"kafka"}}, "unknown library: kafka"},
+		{"itself", models.LibDef{Conflicts: []string{"worker"}}, "the library itself"},
+		{"requires a conflict", models.LibDef{Requires: []string{"redis"}, Conflicts: []string{"redis"}}, "conflicts with it"},
+		{"implies a lib of its group", models.LibDef{Group: "database", Implies: []string{"postgres"}}, "its own group"},
+		{"requires a framework", models.LibDef{Requires: []string{"grpc", "redis"}}, ""},
+		{"requires two frameworks", models.LibDef{Requires: []string{"grpc", "gin"}}, "more than one framework: grpc, gin"},
+		{"implies a framework", models.LibDef{Implies: []string{"grpc"}}, "unknown library: grpc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLibRefs("worker", tt.lib, libs, frameworks)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("validateLibRefs() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("validateLibRefs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if err := validateLibRefs("gin", models.LibDef{}, libs, frameworks); err == nil {
+		t.Error("validateLibRefs() accepted a lib named like a framework")
+	}
+}
+
+func TestGenerateProject_LibRules(t *testing.T) {
+	s := repoService(t)
+
+	tests := []struct {
+		name      string
+		framework string
+		libs      []string
+		want      string
+	}{
+		{"group", "gin", []string{"mysql", "postgres"}, "only one database lib can be selected, got mysql, postgres"},
+		{"framework", "gin", []string{"grpcgateway"}, "grpcgateway requires the grpc framework"},
+		{"conflict", "gin", []string{"rabbitmq", "activemq"}, "activemq conflicts with rabbitmq"},
+		{"valid", constants.FrameworkGRPC, []string{"grpcgateway", "postgres"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := benchmarkRequest()
+			req.Framework = tt.framework
+			req.Libs = tt.libs
+			_, err := s.GenerateProject(context.Background(), req)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("GenerateProject() error = %v", err)
+				}
+				return
+			}
+			appErr, ok := err.(*errors.AppError)
+			if !ok || appErr.Code != errors.ErrCodeValidation || !strings.Contains(appErr.Message, tt.want) {
+				t.Errorf("GenerateProject() error = %v, want a validation error %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateProject_ImpliedLibs(t *testing.T) {
+	s := repoService(t)
+	req := benchmarkRequest()
+	req.Libs = []string{"cron", "opentelemetry"}
+
+	out := newMemoryOutput()
+	warnings, err := s.generate(context.Background(), req, out)
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	for _, want := range []string{"redis was added because cron implies it", "validator was added because opentelemetry implies it"} {
+		found := false
+		for _, w := range warnings {
+			found = found || w.Code == constants.WarnImpliedLib && w.Message == want
+		}
+		if !found {
+			t.Errorf("warnings = %v, want %q", warnings, want)
+		}
+	}
+	for _, name := range []string{"internal/infrastructure/redis/redis.go", "internal/infrastructure/validator/validator.go"} {
+		if _, err := out.ReadFile(name); err != nil {
+			t.Errorf("implied lib not rendered: %v", err)
+		}
+	}
+	if len(req.Libs) != 2 {
+		t.Errorf("generate() changed the request's libs to %v", req.Libs)
+	}
+}
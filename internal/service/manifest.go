@@ -32,7 +32,7 @@ func loadManifest(path string) (*models.Manifest, error) {
 	return &m, nil
 }
 
-// GetManifest returns the loaded manifest
+// GetManifest returns the current manifest
 func (s *GeneratorService) GetManifest() *models.Manifest {
-	return s.manifest
+	return s.pin().manifest
 }
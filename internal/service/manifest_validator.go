@@ -5,6 +5,9 @@ import (
 	"os"
 	"strings"
 
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+
 	"github.com/xhkzeroone/go-generator/internal/constants"
 	"github.com/xhkzeroone/go-generator/internal/errors"
 	"github.com/xhkzeroone/go-generator/internal/models"
@@ -50,6 +53,20 @@ func validateManifest(m *models.Manifest) error {
 		if err := validateLibrary(name, lib); err != nil {
 			return errors.ErrConfig(fmt.Sprintf("Invalid library '%s': %v", name, err), nil)
 		}
+		if err := validateLibRefs(name, lib, m.Libs, m.Frameworks); err != nil {
+			return errors.ErrConfig(fmt.Sprintf("Invalid library '%s': %v", name, err), nil)
+		}
+	}
+
+	// Validate architectures
+	if _, ok := m.Architectures[constants.DefaultArchitecture]; !ok {
+		return errors.ErrConfig(fmt.Sprintf("Manifest must define the default architecture '%s'", constants.DefaultArchitecture), nil)
+	}
+
+	for name, arch := range m.Architectures {
+		if err := validateArchitectureDef(name, arch); err != nil {
+			return errors.ErrConfig(fmt.Sprintf("Invalid architecture '%s': %v", name, err), nil)
+		}
 	}
 
 	return nil
@@ -95,10 +112,37 @@ func validateFramework(name string, f models.FrameworkDef) error {
 	if len(f.Imports) == 0 {
 		return fmt.Errorf("framework must have at least one import")
 	}
+	if err := validateImports(f.Imports); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// validateImports checks that every import names a valid module at a canonical semantic
+// version (e.g. v1.28.0) and belongs to that module
+func validateImports(imports []models.ImportDef) error {
+	for _, imp := range imports {
+		if err := module.CheckImportPath(imp.Path); err != nil {
+			return fmt.Errorf("invalid import: %v", err)
+		}
+		if !semver.IsValid(imp.Version) {
+			return fmt.Errorf("invalid version %q of import %s: not a semantic version (e.g. v1.2.0)", imp.Version, imp.Path)
+		}
+		mod := imp.ModulePath()
+		if err := module.Check(mod, imp.Version); err != nil {
+			return fmt.Errorf("invalid module of import %s: %v", imp.Path, err)
+		}
+		if semver.Canonical(imp.Version)+semver.Build(imp.Version) != imp.Version {
+			return fmt.Errorf("invalid module of import %s: version %s is not canonical (e.g. v1.2.0)", imp.Path, imp.Version)
+		}
+		if imp.Path != mod && !strings.HasPrefix(imp.Path, mod+"/") {
+			return fmt.Errorf("import %s is not in module %s", imp.Path, mod)
+		}
+	}
+	return nil
+}
+
 // validateLibrary validates a library definition
 func validateLibrary(name string, l models.LibDef) error {
 	if name == "" {
@@ -141,6 +185,9 @@ func validateLibrary(name string, l models.LibDef) error {
 	if len(l.Imports) == 0 {
 		return fmt.Errorf("library must have at least one import")
 	}
+	if err := validateImports(l.Imports); err != nil {
+		return err
+	}
 
 	// Validate category if provided
 	validCategories := map[string]bool{
@@ -155,5 +202,42 @@ func validateLibrary(name string, l models.LibDef) error {
 		return fmt.Errorf("invalid category: %s. Valid categories: database, caching, messaging, utilities, observability, other", l.Category)
 	}
 
+	// Validate options schema
+	for _, optName := range sortedKeys(l.Options) {
+		if err := validateLibOptionDef(name, l.Options[optName]); err != nil {
+			return fmt.Errorf("invalid option %s: %v", optName, err)
+		}
+	}
+
+	return nil
+}
+
+// validateLibOptionDef validates the definition of a lib option
+func validateLibOptionDef(lib string, o models.LibOptionDef) error {
+	switch o.Type {
+	case constants.OptionTypeString, constants.OptionTypeInt, constants.OptionTypeBool,
+		constants.OptionTypeStringList, constants.OptionTypeDuration:
+	default:
+		return fmt.Errorf("type must be one of: string, int, bool, string_list, duration")
+	}
+
+	if len(o.Enum) > 0 && o.Type != constants.OptionTypeString {
+		return fmt.Errorf("enum is only supported for string options")
+	}
+	if (o.Min != nil || o.Max != nil) && o.Type != constants.OptionTypeInt {
+		return fmt.Errorf("min and max are only supported for int options")
+	}
+
+	if o.Default != nil {
+		if _, err := libOptionValue(o, o.Default); err != nil {
+			return fmt.Errorf("default %v", err)
+		}
+	}
+
+	// Options set keys of their own lib's config section
+	if o.Config != "" && !strings.HasPrefix(o.Config, lib+".") {
+		return fmt.Errorf("config key must be under the %s section: %s", lib, o.Config)
+	}
+
 	return nil
 }
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"go/importer"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+// Combination is one framework, architecture, example and lib selection of the manifest
+type Combination struct {
+	Framework      string   `json:"framework"`
+	Architecture   string   `json:"architecture"`
+	IncludeExample bool     `json:"includeExample"`
+	Libs           []string `json:"libs"`
+}
+
+func (c Combination) String() string {
+	s := c.Framework + "/" + c.Architecture
+	if c.IncludeExample {
+		s += " +example"
+	}
+	return s + " [" + strings.Join(c.Libs, " ") + "]"
+}
+
+// CombinationResult is the outcome of generating and type-checking one combination
+type CombinationResult struct {
+	Combination
+	Errors []SourceError `json:"errors,omitempty"`
+}
+
+// Failed reports whether the combination did not generate or type-check
+func (r CombinationResult) Failed() bool {
+	return len(r.Errors) > 0
+}
+
+// matrixFactor is one dimension of the combination matrix: the framework, the architecture,
+// the example code, a group of exclusive libs or a single lib. The level "" leaves
+// the example or lib out.
+type matrixFactor struct {
+	levels []string
+}
+
+// matrixPair is a level of one factor together with a level of a later one
+type matrixPair struct {
+	i, a, j, b int
+}
+
+// combinationMatrix samples the combinations of the manifest
+type combinationMatrix struct {
+	s       *GeneratorService
+	factors []matrixFactor
+}
+
+func (s *GeneratorService) combinationMatrix() *combinationMatrix {
+	m := &combinationMatrix{s: s}
+	m.factors = append(m.factors,
+		matrixFactor{levels: sortedKeys(s.manifest.Frameworks)},
+		matrixFactor{levels: sortedKeys(s.manifest.Architectures)},
+		matrixFactor{levels: []string{"", "example"}},
+	)
+
+	groups := make(map[string][]string)
+	for _, name := range sortedKeys(s.manifest.Libs) {
+		lib := s.manifest.Libs[name]
+		if lib.Group != "" {
+			groups[lib.Group] = append(groups[lib.Group], name)
+			continue
+		}
+		m.factors = append(m.factors, matrixFactor{levels: []string{"", name}})
+	}
+	for _, group := range sortedKeys(groups) {
+		m.factors = append(m.factors, matrixFactor{levels: append([]string{""}, groups[group]...)})
+	}
+	return m
+}
+
+// combination turns a row of factor levels into a combination
+func (m *combinationMatrix) combination(row []int) Combination {
+	var c Combination
+	for i, f := range m.factors {
+		level := f.levels[row[i]]
+		switch i {
+		case 0:
+			c.Framework = level
+		case 1:
+			c.Architecture = level
+		case 2:
+			c.IncludeExample = level != ""
+		default:
+			if level != "" {
+				c.Libs = append(c.Libs, level)
+			}
+		}
+	}
+	sort.Strings(c.Libs)
+	return c
+}
+
+// complete fills the unset factors (-1) of a row, returning nil when no valid combination
+// has the levels already set. Libs and the example are left out; the framework and the
+// architecture take the first level that makes the combination valid.
+func (m *combinationMatrix) complete(row []int) []int {
+	full := append([]int(nil), row...)
+	var try func(i int) bool
+	try = func(i int) bool {
+		if i == len(full) {
+			_, _, err := m.s.resolveRequest(m.combination(full).request())
+			return err == nil
+		}
+		if row[i] >= 0 {
+			return try(i + 1)
+		}
+		if i > 1 {
+			full[i] = 0
+			return try(i + 1)
+		}
+		for level := range m.factors[i].levels {
+			full[i] = level
+			if try(i + 1) {
+				return true
+			}
+		}
+		return false
+	}
+	if try(0) {
+		return full
+	}
+	return nil
+}
+
+// PairwiseCombinations samples the valid combinations of the manifest so that every pair of
+// levels of two factors (a framework with a lib, two libs, a lib with the example code, ...)
+// occurs in at least one of them. It is a greedy covering; the result is deterministic.
+func (s *GeneratorService) PairwiseCombinations() []Combination {
+	m := s.pin().combinationMatrix()
+
+	uncovered := make(map[matrixPair]bool)
+	var pairs []matrixPair
+	for i := range m.factors {
+		for j := i + 1; j < len(m.factors); j++ {
+			for a := range m.factors[i].levels {
+				for b := range m.factors[j].levels {
+					p := matrixPair{i, a, j, b}
+					uncovered[p] = true
+					pairs = append(pairs, p)
+				}
+			}
+		}
+	}
+	pairOf := func(i, a, j, b int) matrixPair {
+		if i > j {
+			return matrixPair{j, b, i, a}
+		}
+		return matrixPair{i, a, j, b}
+	}
+
+	var combinations []Combination
+	for _, p := range pairs {
+		if !uncovered[p] {
+			continue
+		}
+		row := make([]int, len(m.factors))
+		for i := range row {
+			row[i] = -1
+		}
+		row[p.i], row[p.j] = p.a, p.b
+		if m.complete(row) == nil {
+			// No valid combination has this pair, e.g. grpcgateway without grpc
+			delete(uncovered, p)
+			continue
+		}
+
+		// Give every other factor the level that covers the most new pairs
+		for k := range row {
+			if row[k] >= 0 {
+				continue
+			}
+			best, bestGain := -1, -1
+			for level := range m.factors[k].levels {
+				row[k] = level
+				if m.complete(row) == nil {
+					continue
+				}
+				gain := 0
+				for other, otherLevel := range row {
+					if other != k && otherLevel >= 0 && uncovered[pairOf(k, level, other, otherLevel)] {
+						gain++
+					}
+				}
+				if gain > bestGain {
+					best, bestGain = level, gain
+				}
+			}
+			row[k] = best
+		}
+
+		for i := range row {
+			for j := i + 1; j < len(row); j++ {
+				delete(uncovered, matrixPair{i, row[i], j, row[j]})
+			}
+		}
+		combinations = append(combinations, m.combination(row))
+	}
+	return combinations
+}
+
+// VerifyCombinations generates each combination and type-checks the result. Standard
+// library packages are loaded through the go command's export data; third-party imports
+// resolve to stubs, so nothing is downloaded.
+func (s *GeneratorService) VerifyCombinations(combinations []Combination) []CombinationResult {
+	std := importer.Default()
+	results := make([]CombinationResult, len(combinations))
+	for i, c := range combinations {
+		results[i] = CombinationResult{Combination: c, Errors: s.verifyCombination(c, std)}
+	}
+	return results
+}
+
+func (s *GeneratorService) verifyCombination(c Combination, std types.Importer) []SourceError {
+	req := c.request()
+	out := newMemoryOutput()
+	if _, err := s.generate(context.Background(), req, out); err != nil {
+		return generationErrors(err)
+	}
+	errs, err := typeCheckProject(out, req.ModuleName, std)
+	if err != nil {
+		return []SourceError{{Message: err.Error()}}
+	}
+	return errs
+}
+
+// request builds the generate request of a combination
+func (c Combination) request() *GenerateRequest {
+	return &GenerateRequest{
+		ProjectName:    "verify",
+		ModuleName:     "example.com/verify",
+		Framework:      c.Framework,
+		Architecture:   c.Architecture,
+		Libs:           c.Libs,
+		IncludeExample: c.IncludeExample,
+	}
+}
+
+// generationErrors reports a failed generation; a Go file that does not parse names its
+// path and line
+func generationErrors(err error) []SourceError {
+	e := SourceError{Message: err.Error()}
+	if appErr, ok := err.(*errors.AppError); ok {
+		if file, ok := appErr.Context["path"].(string); ok {
+			e.File = file
+		}
+		if line, ok := appErr.Context["line"].(int); ok {
+			e.Line = line
+		}
+	}
+	return []SourceError{e}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"go/importer"
+	"testing"
+)
+
+func TestPairwiseCombinations(t *testing.T) {
+	s := repoService(t)
+	m := s.combinationMatrix()
+	combinations := s.PairwiseCombinations()
+
+	exhaustive := 1
+	for _, f := range m.factors {
+		exhaustive *= len(f.levels)
+	}
+	if len(combinations) == 0 || len(combinations) > exhaustive/100 {
+		t.Fatalf("PairwiseCombinations() = %d combinations out of %d", len(combinations), exhaustive)
+	}
+
+	var rows [][]int
+	for _, c := range combinations {
+		if _, _, err := s.resolveRequest(c.request()); err != nil {
+			t.Errorf("%s is not valid: %v", c, err)
+		}
+		rows = append(rows, matrixRow(m, c))
+	}
+
+	// Every pair of levels is in some combination, unless no valid combination has it
+	for i := range m.factors {
+		for j := i + 1; j < len(m.factors); j++ {
+			for a := range m.factors[i].levels {
+				for b := range m.factors[j].levels {
+					covered := false
+					for _, row := range rows {
+						if row[i] == a && row[j] == b {
+							covered = true
+							break
+						}
+					}
+					if covered {
+						continue
+					}
+					row := make([]int, len(m.factors))
+					for k := range row {
+						row[k] = -1
+					}
+					row[i], row[j] = a, b
+					if m.complete(row) != nil {
+						t.Errorf("no combination has %q with %q", m.factors[i].levels[a], m.factors[j].levels[b])
+					}
+				}
+			}
+		}
+	}
+}
+
+// matrixRow returns the factor levels of a combination
+func matrixRow(m *combinationMatrix, c Combination) []int {
+	row := make([]int, len(m.factors))
+	for i, f := range m.factors {
+		for level, name := range f.levels {
+			switch {
+			case i == 0 && name == c.Framework,
+				i == 1 && name == c.Architecture,
+				i == 2 && (name != "") == c.IncludeExample,
+				i > 2 && name != "" && containsString(c.Libs, name):
+				row[i] = level
+			}
+		}
+	}
+	return row
+}
+
+// TestCombinationMatrix generates and type-checks a pairwise sample of the framework,
+// architecture, lib and example combinations
+func TestCombinationMatrix(t *testing.T) {
+	if testing.Short() {
+		t.Skip("type-checks every sampled combination")
+	}
+	s := repoService(t)
+
+	for _, result := range s.VerifyCombinations(s.PairwiseCombinations()) {
+		for _, e := range result.Errors {
+			t.Errorf("%s: %s", result.Combination, e)
+		}
+	}
+}
+
+func TestTypeCheckProject(t *testing.T) {
+	files := map[string]string{
+		"go.mod": "module example.com/demo\n\ngo 1.21\n",
+		"cmd/main.go": `package main
+
+import (
+	"log"
+
+	"example.com/demo/internal/app"
+)
+
+func main() {
+	if err := app.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+`,
+		"internal/app/app.go": `package app
+
+import (
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+)
+
+func Run() error {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+	reader := kafka.NewReader(kafka.ReaderConfig{Topic: "orders"})
+	var partitions int = reader.Config().Partition
+	var retries int = "3"
+	return start(client, partitions, retries)
+}
+`,
+	}
+	out := newMemoryOutput()
+	for name, content := range files {
+		if err := out.WriteFile(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errs, err := typeCheckProject(out, "example.com/demo", importer.Default())
+	if err != nil {
+		t.Fatalf("typeCheckProject() error = %v", err)
+	}
+	want := []string{
+		`internal/app/app.go:13:20: cannot use "3" (untyped string constant) as int value in variable declaration`,
+		"internal/app/app.go:14:9: undefined: start",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("typeCheckProject() = %v, want %v", errs, want)
+	}
+	for i, e := range errs {
+		if e.String() != want[i] {
+			t.Errorf("error %d = %s, want %s", i, e, want[i])
+		}
+	}
+}
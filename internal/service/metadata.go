@@ -25,7 +25,7 @@ type DepMeta struct {
 }
 
 // loadDepsMetadata loads the deps metadata from JSON file
-func (s *GeneratorService) loadDepsMetadata() (map[string]DepMetadata, error) {
+func loadDepsMetadata() (map[string]DepMetadata, error) {
 	data, err := os.ReadFile(constants.TemplateDepsMeta)
 	if err != nil {
 		return nil, errors.ErrFileSystem("Failed to read deps metadata file", err).
@@ -42,7 +42,7 @@ func (s *GeneratorService) loadDepsMetadata() (map[string]DepMetadata, error) {
 }
 
 // loadConfigMetadata loads the config metadata from JSON file
-func (s *GeneratorService) loadConfigMetadata() (map[string]DepMeta, error) {
+func loadConfigMetadata() (map[string]DepMeta, error) {
 	b, err := os.ReadFile(constants.TemplateConfigMeta)
 	if err != nil {
 		return nil, errors.ErrFileSystem("Failed to read config metadata file", err).
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// commonInitialisms are kept upper-case in Go identifiers (golint style)
+var commonInitialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SQL": true, "URL": true, "URI": true, "UUID": true, "XML": true,
+}
+
+// splitWords splits a name on underscores, hyphens, spaces and case changes.
+// "orderItem", "order_item" and "OrderItem" all yield ["order", "item"];
+// "userID" yields ["user", "id"] and "HTTPServer" yields ["http", "server"].
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(current) > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// pascalCase converts a name to an exported Go identifier, e.g. "user_id" -> "UserID"
+func pascalCase(s string) string {
+	var b strings.Builder
+	for _, w := range splitWords(s) {
+		if upper := strings.ToUpper(w); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+// camelCase converts a name to lower camel case, e.g. "user_id" -> "userId"
+func camelCase(s string) string {
+	words := splitWords(s)
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+// snakeCase converts a name to snake case, e.g. "OrderItem" -> "order_item"
+func snakeCase(s string) string {
+	return strings.Join(splitWords(s), "_")
+}
+
+// kebabCase converts a name to kebab case, e.g. "OrderItem" -> "order-item"
+func kebabCase(s string) string {
+	return strings.Join(splitWords(s), "-")
+}
+
+// plural returns the English plural of a lower-case word or of the last word of a snake/kebab name
+func plural(s string) string {
+	switch {
+	case s == "":
+		return s
+	case strings.HasSuffix(s, "s") || strings.HasSuffix(s, "x") || strings.HasSuffix(s, "z") ||
+		strings.HasSuffix(s, "ch") || strings.HasSuffix(s, "sh"):
+		return s + "es"
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	default:
+		return s + "s"
+	}
+}
+
+// singular returns the English singular of a lower-case plural word, the inverse of plural
+// for regular nouns, e.g. "categories" -> "category" and "addresses" -> "address"
+func singular(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies") && len(s) > 3:
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "sses") || strings.HasSuffix(s, "xes") || strings.HasSuffix(s, "zes") ||
+		strings.HasSuffix(s, "ches") || strings.HasSuffix(s, "shes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "ss") || strings.HasSuffix(s, "us") || strings.HasSuffix(s, "is"):
+		return s
+	case strings.HasSuffix(s, "s") && len(s) > 1:
+		return s[:len(s)-1]
+	default:
+		return s
+	}
+}
+
+// identifier converts free text such as an operationId or a schema name into an exported
+// Go identifier, e.g. "pets.list-all" -> "PetsListAll". It returns "" when the result
+// would not start with a letter.
+func identifier(s string) string {
+	name := pascalCase(asciiWords(s))
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		return ""
+	}
+	return name
+}
+
+// asciiWords replaces every rune that cannot appear in an ASCII identifier with an underscore
+func asciiWords(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, s)
+}
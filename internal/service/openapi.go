@@ -0,0 +1,1050 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// APIView is the template-facing description of the API generated from an OpenAPI document
+type APIView struct {
+	Title       string
+	Version     string
+	Description string
+	BasePath    string         // path prefix taken from the first server URL, e.g. "/v1"
+	Dir         string         // directory of the generated package
+	Package     string         // Go package name
+	Import      string         // import spec as seen from internal/app
+	Qual        string         // identifier qualifier as seen from internal/app, e.g. "api."
+	Types       []*APIType     // declared types, sorted by name
+	Operations  []APIOperation // operations in path and method order
+	Imports     []string       // imports required by the declared types
+	Spec        string         // the document as JSON, served by the docs package
+}
+
+// APIType is a Go type declared for a schema, an inline object or the inputs of an operation
+type APIType struct {
+	Name        string
+	Doc         string      // first line of the doc comment
+	Description string      // schema description
+	Kind        string      // "struct", "enum" or "named"
+	Fields      []APIField  // struct fields
+	GoType      string      // underlying type of enum and named types
+	Values      []EnumValue // enum constants, empty when the values are not valid identifiers
+}
+
+// APIField is a field of a declared struct
+type APIField struct {
+	Name   string
+	GoType string
+	Tag    string // struct tag without the enclosing backquotes
+	Doc    string
+}
+
+// APIOperation is one operation of the document
+type APIOperation struct {
+	Name     string     // exported handler method, e.g. "ShowPetByID"
+	Func     string     // unexported method holding the implementation, e.g. "showPetById"
+	ID       string     // operationId, or the default name when the document has none
+	Method   string     // HTTP method, e.g. "GET"
+	Verb     string     // router method of the framework, e.g. "GET" or "Get"
+	Path     string     // path as written in the document, e.g. "/pets/{petId}"
+	Route    string     // router path, e.g. "/v1/pets/:petId"
+	Summary  string     // one-line description
+	Params   string     // name of the inputs struct, empty when the operation takes no inputs
+	Inputs   []APIParam // path, query and header parameters
+	Body     *APIBody   // JSON request body
+	Status   int        // success status code
+	Response string     // Go type of the success body, empty when none
+}
+
+// APIParam is a path, query or header parameter of an operation
+type APIParam struct {
+	Name     string // Go field name in the inputs struct
+	Key      string // parameter name in the document
+	RouteKey string // parameter name in the router path
+	In       string // path | query | header
+	GoType   string // field type
+	Elem     string // element type of optional scalars, which are stored as pointers
+	Required bool
+}
+
+// APIBody is the JSON request body of an operation
+type APIBody struct {
+	GoType   string
+	Required bool
+}
+
+// httpMethods lists the operation methods in the order routes are registered
+var httpMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// reservedAPINames are declared by the generated handler file itself
+var reservedAPINames = []string{"APIHandler", "NewAPIHandler"}
+
+// validatorFormats maps OpenAPI string formats to validator rules
+var validatorFormats = map[string]string{
+	"email":    "email",
+	"uri":      "url",
+	"url":      "url",
+	"uuid":     "uuid",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"hostname": "hostname",
+}
+
+// builtinRoutes are the routes every generated HTTP server registers itself. A true value
+// marks a prefix route, which also serves every path below it.
+var builtinRoutes = map[string]bool{
+	"/health":  false,
+	"/swagger": true,
+}
+
+// pathParamPattern matches the {name} templates of an OpenAPI path
+var pathParamPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// apiView parses the request's OpenAPI document; it returns nil when the request has none.
+// Parts of the document that are accepted but not generated are reported as warnings.
+func (s *GeneratorService) apiView(req *GenerateRequest) (*APIView, []models.Warning, error) {
+	if strings.TrimSpace(req.OpenAPI) == "" {
+		return nil, nil, nil
+	}
+
+	doc, spec, err := parseOpenAPI(req.OpenAPI)
+	if err != nil {
+		return nil, nil, errors.ErrValidation(fmt.Sprintf("invalid openapi document: %v", err), nil)
+	}
+
+	b := newAPIBuilder(doc, req.Framework)
+	view, err := b.build()
+	if err != nil {
+		return nil, nil, errors.ErrValidation(fmt.Sprintf("invalid openapi document: %v", err), nil)
+	}
+
+	view.Spec = spec
+	view.Dir = s.apiDir(req)
+	view.Package = path.Base(view.Dir)
+
+	// Per-module packages are aliased in internal/app the way layerRefs aliases entity modules
+	name := view.Package
+	view.Import = fmt.Sprintf("%q", req.ModuleName+"/"+view.Dir)
+	if s.isModuleLayer(req, constants.LayerHandler) {
+		name = constants.APIModuleName + view.Package
+		view.Import = name + " " + view.Import
+	}
+	view.Qual = name + "."
+	return view, b.warnings, nil
+}
+
+// apiDir returns the directory of the generated API package. The API gets a package of
+// its own so that schema names cannot collide with the DTOs of the entity handlers.
+func (s *GeneratorService) apiDir(req *GenerateRequest) string {
+	dir := s.layerDir(req, constants.LayerHandler, constants.APIModuleName)
+	if s.isModuleLayer(req, constants.LayerHandler) {
+		return dir
+	}
+	return path.Join(dir, constants.APIModuleName)
+}
+
+// parseOpenAPI decodes a YAML or JSON OpenAPI 3.0/3.1 document. It also returns the
+// document re-encoded as indented JSON for the docs package.
+func parseOpenAPI(text string) (*models.OpenAPIDoc, string, error) {
+	data := []byte(strings.TrimSpace(text))
+	if !bytes.HasPrefix(data, []byte("{")) {
+		converted, err := YAMLToJSON(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		data = converted
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	var doc models.OpenAPIDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, "", err
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.0") && !strings.HasPrefix(doc.OpenAPI, "3.1") {
+		if _, ok := raw["swagger"]; ok {
+			return nil, "", fmt.Errorf("swagger 2.0 documents are not supported, convert the document to OpenAPI 3")
+		}
+		return nil, "", fmt.Errorf("unsupported openapi version %q, expected 3.0.x or 3.1.x", doc.OpenAPI)
+	}
+
+	spec, err := json.MarshalIndent(raw, "", "    ")
+	if err != nil {
+		return nil, "", err
+	}
+	return &doc, string(spec), nil
+}
+
+// apiBuilder converts an OpenAPI document into an APIView, declaring Go types as it goes
+type apiBuilder struct {
+	doc       *models.OpenAPIDoc
+	framework string
+	types     map[string]*APIType // declared types by Go name
+	names     map[string]bool     // package-level identifiers already in use
+	schemas   map[string]string   // component schema name -> Go type name
+	imports   map[string]bool
+	warnings  []models.Warning
+}
+
+func newAPIBuilder(doc *models.OpenAPIDoc, framework string) *apiBuilder {
+	b := &apiBuilder{
+		doc:       doc,
+		framework: framework,
+		types:     make(map[string]*APIType),
+		names:     make(map[string]bool),
+		schemas:   make(map[string]string),
+		imports:   make(map[string]bool),
+	}
+	for _, name := range reservedAPINames {
+		b.names[name] = true
+	}
+	return b
+}
+
+// build declares the component schemas and collects every operation of the document
+func (b *apiBuilder) build() (*APIView, error) {
+	view := &APIView{
+		Title:       oneLine(b.doc.Info.Title),
+		Version:     oneLine(b.doc.Info.Version),
+		Description: oneLine(b.doc.Info.Description),
+		BasePath:    basePath(b.doc.Servers),
+	}
+	if view.Title == "" {
+		view.Title = "API"
+	}
+
+	// Components first, so that their names take precedence over synthesized ones
+	for _, name := range sortedKeys(b.doc.Components.Schemas) {
+		if _, err := b.componentType("#/components/schemas/" + name); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := make(map[string]string)
+	for _, p := range sortedKeys(b.doc.Paths) {
+		item := b.doc.Paths[p]
+		ops := item.Operations()
+		for _, method := range httpMethods {
+			op, ok := ops[method]
+			if !ok {
+				continue
+			}
+			operation, err := b.operation(p, method, item.Parameters, op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, p, err)
+			}
+			if other, dup := seen[operation.Name]; dup {
+				return nil, fmt.Errorf("%s %s: operation name %s is already used by %s", method, p, operation.Name, other)
+			}
+			seen[operation.Name] = method + " " + p
+			view.Operations = append(view.Operations, operation)
+		}
+	}
+
+	if len(view.Operations) == 0 {
+		return nil, fmt.Errorf("the document defines no operations")
+	}
+	if len(view.Operations) > constants.MaxOpenAPIOperations {
+		return nil, fmt.Errorf("the document defines more than %d operations", constants.MaxOpenAPIOperations)
+	}
+
+	for _, name := range sortedKeys(b.types) {
+		view.Types = append(view.Types, b.types[name])
+	}
+	for imp := range b.imports {
+		view.Imports = append(view.Imports, fmt.Sprintf("%q", imp))
+	}
+	sort.Strings(view.Imports)
+	return view, nil
+}
+
+// operation converts a single operation of a path
+func (b *apiBuilder) operation(p, method string, shared []*models.OpenAPIParameter, op *models.OpenAPIOperation) (APIOperation, error) {
+	if !strings.HasPrefix(p, "/") || strings.ContainsAny(pathParamPattern.ReplaceAllString(p, ""), ":*{} \"`\\") {
+		return APIOperation{}, fmt.Errorf("unsupported path")
+	}
+
+	if route, ok := builtinRoute(basePath(b.doc.Servers) + p); ok {
+		return APIOperation{}, fmt.Errorf("the path collides with the %s route of the generated server", route)
+	}
+
+	id := op.OperationID
+	if id == "" {
+		id = operationName(method, p)
+	}
+	name := identifier(id)
+	if name == "" {
+		return APIOperation{}, fmt.Errorf("operationId %q does not yield a Go identifier", id)
+	}
+
+	operation := APIOperation{
+		Name:    name,
+		Func:    camelCase(name),
+		ID:      id,
+		Method:  method,
+		Verb:    routerVerb(b.framework, method),
+		Path:    p,
+		Summary: oneLine(op.Summary),
+		Status:  200,
+	}
+	if token.IsKeyword(operation.Func) {
+		operation.Func += "Op"
+	}
+	if operation.Summary == "" {
+		operation.Summary = oneLine(op.Description)
+	}
+
+	inputs := &APIType{Kind: "struct"}
+	if err := b.parameters(&operation, inputs, shared, op.Parameters); err != nil {
+		return APIOperation{}, err
+	}
+	if err := b.requestBody(&operation, inputs, op.RequestBody); err != nil {
+		return APIOperation{}, err
+	}
+	if len(inputs.Fields) > 0 {
+		inputs.Name = b.unique(name + "Params")
+		inputs.Doc = fmt.Sprintf("%s holds the inputs of %s", inputs.Name, name)
+		operation.Params = inputs.Name
+		b.types[inputs.Name] = inputs
+	}
+
+	// Router paths use :name or {name} placeholders; names are reduced to identifier characters
+	routeKeys := make(map[string]string)
+	for _, in := range operation.Inputs {
+		if in.In == "path" {
+			routeKeys[in.Key] = in.RouteKey
+		}
+	}
+	var missing error
+	route := pathParamPattern.ReplaceAllStringFunc(p, func(m string) string {
+		key := m[1 : len(m)-1]
+		if rk, ok := routeKeys[key]; ok {
+			return routeParam(b.framework, rk)
+		}
+		missing = fmt.Errorf("path parameter %s is not declared", key)
+		return ""
+	})
+	if missing != nil {
+		return APIOperation{}, missing
+	}
+	operation.Route = basePath(b.doc.Servers) + route
+	if b.framework == constants.FrameworkNetHTTP && strings.HasSuffix(operation.Route, "/") {
+		// A trailing slash is a prefix match in ServeMux patterns unless anchored
+		operation.Route += "{$}"
+	}
+
+	if err := b.response(&operation, op.Responses); err != nil {
+		return APIOperation{}, err
+	}
+	return operation, nil
+}
+
+// parameters adds the path, query and header parameters of an operation to its inputs.
+// Operation parameters override path-level parameters with the same name and location.
+func (b *apiBuilder) parameters(op *APIOperation, inputs *APIType, shared, own []*models.OpenAPIParameter) error {
+	var params []*models.OpenAPIParameter
+	index := make(map[string]int)
+	for _, list := range [][]*models.OpenAPIParameter{shared, own} {
+		for _, param := range list {
+			resolved, err := b.resolveParameter(param)
+			if err != nil {
+				return err
+			}
+			key := resolved.In + ":" + resolved.Name
+			if i, ok := index[key]; ok {
+				params[i] = resolved
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, resolved)
+		}
+	}
+
+	fields := make(map[string]bool)
+	for _, param := range params {
+		if param.In != "path" && param.In != "query" && param.In != "header" {
+			continue // cookie parameters are left to the implementation
+		}
+		if param.Name == "" || strings.ContainsAny(param.Name, "\"`\\") {
+			return fmt.Errorf("invalid %s parameter name %q", param.In, param.Name)
+		}
+
+		name := identifier(param.Name)
+		if name == "" {
+			name = "Param"
+		}
+		if fields[name] || name == "Body" {
+			name += pascalCase(param.In)
+		}
+		if fields[name] {
+			return fmt.Errorf("parameters map to the same field %s", name)
+		}
+		fields[name] = true
+
+		schema := b.resolveSchema(param.Schema)
+		required := param.Required || param.In == "path"
+		in := APIParam{
+			Name:     name,
+			Key:      param.Name,
+			RouteKey: asciiWords(param.Name),
+			In:       param.In,
+			GoType:   paramType(schema),
+			Required: required,
+		}
+		if !required && in.GoType != "string" && in.GoType != "[]string" {
+			in.Elem = in.GoType
+			in.GoType = "*" + in.GoType
+		}
+		op.Inputs = append(op.Inputs, in)
+
+		// Binding already rejects a missing required parameter; a required rule would also
+		// reject a valid zero value, such as the 0 of /items/0
+		rules := validateRules(schema, false, in.GoType)
+		if required {
+			rules = strings.Join(schemaRules(schema), ",")
+		}
+		tag := ""
+		if rules != "" {
+			tag = `validate:"` + rules + `"`
+		}
+		inputs.Fields = append(inputs.Fields, APIField{
+			Name:   name,
+			GoType: in.GoType,
+			Tag:    tag,
+			Doc:    fmt.Sprintf("%s %s parameter", param.Name, param.In),
+		})
+	}
+	return nil
+}
+
+// requestBody adds the JSON request body of an operation to its inputs
+func (b *apiBuilder) requestBody(op *APIOperation, inputs *APIType, body *models.OpenAPIRequestBody) error {
+	if body == nil {
+		return nil
+	}
+	if body.Ref != "" {
+		name := strings.TrimPrefix(body.Ref, "#/components/requestBodies/")
+		resolved, ok := b.doc.Components.RequestBodies[name]
+		if !ok || resolved == nil {
+			return fmt.Errorf("unresolved reference %s", body.Ref)
+		}
+		body = resolved
+	}
+
+	media := jsonMedia(body.Content)
+	if media == nil {
+		// Form and binary bodies are not bound; the operation receives no body
+		b.warnings = append(b.warnings, models.Warning{
+			Code: constants.WarnUnsupportedMediaType,
+			Message: fmt.Sprintf("%s %s: %s request body is not generated, only JSON bodies are bound",
+				op.Method, op.Path, strings.Join(sortedKeys(body.Content), ", ")),
+		})
+		return nil
+	}
+	goType, err := b.goType(media.Schema, op.Name+"Request")
+	if err != nil {
+		return err
+	}
+	op.Body = &APIBody{GoType: goType, Required: body.Required}
+
+	tag := ""
+	if body.Required && canBeNil(goType) {
+		tag = `validate:"required"`
+	}
+	inputs.Fields = append(inputs.Fields, APIField{Name: "Body", GoType: goType, Tag: tag, Doc: "JSON request body"})
+	return nil
+}
+
+// response picks the success response of an operation: the lowest 2xx status, or the default response
+func (b *apiBuilder) response(op *APIOperation, responses map[string]*models.OpenAPIResponse) error {
+	key := ""
+	for _, status := range sortedKeys(responses) {
+		if strings.HasPrefix(status, "2") {
+			key = status
+			break
+		}
+	}
+	if key == "" {
+		if _, ok := responses["default"]; !ok {
+			return nil
+		}
+		key = "default"
+	}
+	if status, err := strconv.Atoi(key); err == nil {
+		op.Status = status
+	}
+
+	resp := responses[key]
+	if resp != nil && resp.Ref != "" {
+		name := strings.TrimPrefix(resp.Ref, "#/components/responses/")
+		resolved, ok := b.doc.Components.Responses[name]
+		if !ok || resolved == nil {
+			return fmt.Errorf("unresolved reference %s", resp.Ref)
+		}
+		resp = resolved
+	}
+	if resp == nil {
+		return nil
+	}
+
+	media := jsonMedia(resp.Content)
+	if media == nil {
+		return nil
+	}
+	goType, err := b.goType(media.Schema, op.Name+"Response")
+	if err != nil {
+		return err
+	}
+	op.Response = goType
+	return nil
+}
+
+// componentType returns the Go type of a component schema, declaring it on first use
+func (b *apiBuilder) componentType(ref string) (string, error) {
+	name := strings.TrimPrefix(ref, "#/components/schemas/")
+	if name == ref {
+		return "", fmt.Errorf("unsupported reference %s, only local component schemas are supported", ref)
+	}
+	if goName, ok := b.schemas[name]; ok {
+		return goName, nil
+	}
+	schema, ok := b.doc.Components.Schemas[name]
+	if !ok || schema == nil {
+		return "", fmt.Errorf("unresolved reference %s", ref)
+	}
+
+	goName := identifier(name)
+	if goName == "" {
+		return "", fmt.Errorf("schema name %q does not yield a Go identifier", name)
+	}
+	if b.names[goName] {
+		return "", fmt.Errorf("schema %s: type name %s is already used", name, goName)
+	}
+	b.names[goName] = true
+	b.schemas[name] = goName
+
+	if err := b.declare(goName, schema); err != nil {
+		return "", fmt.Errorf("schema %s: %w", name, err)
+	}
+	return goName, nil
+}
+
+// declare declares a named Go type for a schema; the name must already be reserved
+func (b *apiBuilder) declare(name string, schema *models.OpenAPISchema) error {
+	t := &APIType{
+		Name:        name,
+		Doc:         name + " is defined by the OpenAPI document",
+		Description: oneLine(schema.Description),
+	}
+	b.types[name] = t
+
+	switch {
+	case isObjectSchema(schema):
+		t.Kind = "struct"
+		return b.structFields(t, schema)
+	case schemaType(schema) == "string" && len(schema.Enum) > 0:
+		t.Kind = "enum"
+		t.GoType = "string"
+		t.Values = b.enumValues(name, schema.Enum)
+		return nil
+	default:
+		t.Kind = "named"
+		goType, err := b.goType(schema, name+"Item")
+		if err != nil {
+			return err
+		}
+		t.GoType = goType
+		return nil
+	}
+}
+
+// structFields adds the properties of an object schema, including those of its allOf parts
+func (b *apiBuilder) structFields(t *APIType, schema *models.OpenAPISchema) error {
+	props := make(map[string]*models.OpenAPISchema)
+	required := make(map[string]bool)
+	if err := b.collectProperties(schema, props, required, make(map[string]bool)); err != nil {
+		return err
+	}
+
+	fields := make(map[string]string)
+	for _, prop := range sortedKeys(props) {
+		if strings.ContainsAny(prop, "\"`\\ ,") {
+			return fmt.Errorf("property name %q cannot be used in a struct tag", prop)
+		}
+		fieldName := identifier(prop)
+		if fieldName == "" {
+			fieldName = "Field" + pascalCase(asciiWords(prop))
+		}
+		if other, dup := fields[fieldName]; dup {
+			return fmt.Errorf("properties %s and %s map to the same field %s", other, prop, fieldName)
+		}
+		fields[fieldName] = prop
+
+		ps := props[prop]
+		goType, err := b.goType(ps, t.Name+fieldName)
+		if err != nil {
+			return fmt.Errorf("property %s: %w", prop, err)
+		}
+
+		resolved := b.resolveSchema(ps)
+		nullable := isNullable(ps) || isNullable(resolved)
+		isRequired := required[prop]
+		if (nullable || (!isRequired && b.isStruct(goType))) && !canBeNil(goType) {
+			goType = "*" + goType
+		}
+
+		tag := `json:"` + prop
+		if !isRequired {
+			tag += ",omitempty"
+		}
+		tag += `"`
+		// Struct values are validated field by field; required only applies to other types
+		structValue := b.isStruct(goType) || goType == "time.Time"
+		if rules := validateRules(resolved, isRequired && !nullable && !structValue, goType); rules != "" {
+			tag += ` validate:"` + rules + `"`
+		}
+
+		t.Fields = append(t.Fields, APIField{
+			Name:   fieldName,
+			GoType: goType,
+			Tag:    tag,
+			Doc:    oneLine(ps.Description),
+		})
+	}
+	return nil
+}
+
+// collectProperties merges the properties and required lists of a schema and its allOf parts
+func (b *apiBuilder) collectProperties(schema *models.OpenAPISchema, props map[string]*models.OpenAPISchema, required, visiting map[string]bool) error {
+	if schema.Ref != "" {
+		if visiting[schema.Ref] {
+			return fmt.Errorf("circular allOf reference %s", schema.Ref)
+		}
+		visiting[schema.Ref] = true
+		defer delete(visiting, schema.Ref)
+
+		resolved := b.resolveSchema(schema)
+		if resolved == schema {
+			return fmt.Errorf("unresolved reference %s", schema.Ref)
+		}
+		schema = resolved
+	}
+
+	for _, part := range schema.AllOf {
+		if part == nil {
+			continue
+		}
+		if err := b.collectProperties(part, props, required, visiting); err != nil {
+			return err
+		}
+	}
+	for name, prop := range schema.Properties {
+		props[name] = prop
+	}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	return nil
+}
+
+// goType returns the Go type of a schema, declaring named types for inline objects and
+// enums under the name hint
+func (b *apiBuilder) goType(schema *models.OpenAPISchema, hint string) (string, error) {
+	if schema == nil {
+		return "interface{}", nil
+	}
+	if schema.Ref != "" {
+		return b.componentType(schema.Ref)
+	}
+	if len(schema.AllOf) == 1 && len(schema.Properties) == 0 {
+		return b.goType(schema.AllOf[0], hint)
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		keyword := "oneOf"
+		if len(schema.OneOf) == 0 {
+			keyword = "anyOf"
+		}
+		b.warnings = append(b.warnings, models.Warning{
+			Code:    constants.WarnUnsupportedSchema,
+			Message: fmt.Sprintf("%s: %s schemas are not mapped, the value is generated as interface{}", hint, keyword),
+		})
+		return "interface{}", nil
+	}
+	if isObjectSchema(schema) {
+		name := b.unique(hint)
+		return name, b.declare(name, schema)
+	}
+
+	switch schemaType(schema) {
+	case "string":
+		if len(schema.Enum) > 0 {
+			name := b.unique(hint)
+			return name, b.declare(name, schema)
+		}
+		if schema.Format == "date-time" {
+			b.imports["time"] = true
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32", nil
+		}
+		return "int64", nil
+	case "number":
+		if schema.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		elem, err := b.goType(schema.Items, hint+"Item")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case "object":
+		if ap := schema.AdditionalProperties; ap != nil && ap.Schema != nil {
+			elem, err := b.goType(ap.Schema, hint+"Value")
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + elem, nil
+		}
+		return "map[string]interface{}", nil
+	}
+	return "interface{}", nil
+}
+
+// enumValues builds the constants of an enum type. Constants are omitted when a value does
+// not produce a usable identifier or collides with another declaration.
+func (b *apiBuilder) enumValues(typeName string, values []interface{}) []EnumValue {
+	var consts []EnumValue
+	used := make(map[string]bool)
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		value := fmt.Sprint(v)
+		suffix := pascalCase(asciiWords(value))
+		name := typeName + suffix
+		if suffix == "" || used[name] || b.names[name] {
+			return nil
+		}
+		used[name] = true
+		consts = append(consts, EnumValue{Const: name, Value: value})
+	}
+	for name := range used {
+		b.names[name] = true
+	}
+	return consts
+}
+
+// resolveSchema follows a local component reference; other schemas are returned as-is
+func (b *apiBuilder) resolveSchema(schema *models.OpenAPISchema) *models.OpenAPISchema {
+	for depth := 0; schema != nil && schema.Ref != "" && depth < 32; depth++ {
+		resolved, ok := b.doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		if !ok || resolved == nil {
+			return schema
+		}
+		schema = resolved
+	}
+	return schema
+}
+
+// resolveParameter follows a reference to a component parameter
+func (b *apiBuilder) resolveParameter(param *models.OpenAPIParameter) (*models.OpenAPIParameter, error) {
+	if param == nil {
+		return nil, fmt.Errorf("empty parameter")
+	}
+	if param.Ref == "" {
+		return param, nil
+	}
+	resolved, ok := b.doc.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+	if !ok || resolved == nil {
+		return nil, fmt.Errorf("unresolved reference %s", param.Ref)
+	}
+	return resolved, nil
+}
+
+// isStruct reports whether goType is a struct declared by the builder
+func (b *apiBuilder) isStruct(goType string) bool {
+	t, ok := b.types[goType]
+	return ok && t.Kind == "struct"
+}
+
+// unique reserves and returns name, or name followed by the first free number
+func (b *apiBuilder) unique(name string) string {
+	candidate := name
+	for i := 2; b.names[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	b.names[candidate] = true
+	return candidate
+}
+
+// validateRules derives validator rules from the constraints of a schema
+func validateRules(schema *models.OpenAPISchema, required bool, goType string) string {
+	rules := schemaRules(schema)
+	switch {
+	case required && strings.TrimPrefix(goType, "*") != "bool":
+		rules = append([]string{"required"}, rules...)
+	case len(rules) > 0:
+		rules = append([]string{"omitempty"}, rules...)
+	}
+	return strings.Join(rules, ",")
+}
+
+// schemaRules returns the validator rules of the constraints of a schema
+func schemaRules(schema *models.OpenAPISchema) []string {
+	var rules []string
+	if schema != nil {
+		switch schemaType(schema) {
+		case "string":
+			if schema.MinLength != nil {
+				rules = append(rules, fmt.Sprintf("min=%d", *schema.MinLength))
+			}
+			if schema.MaxLength != nil {
+				rules = append(rules, fmt.Sprintf("max=%d", *schema.MaxLength))
+			}
+			if rule, ok := validatorFormats[schema.Format]; ok {
+				rules = append(rules, rule)
+			}
+		case "integer", "number":
+			rules = append(rules, boundRule("gte", "gt", schema.Minimum, schema.ExclusiveMinimum)...)
+			rules = append(rules, boundRule("lte", "lt", schema.Maximum, schema.ExclusiveMaximum)...)
+		case "array":
+			if schema.MinItems != nil {
+				rules = append(rules, fmt.Sprintf("min=%d", *schema.MinItems))
+			}
+			if schema.MaxItems != nil {
+				rules = append(rules, fmt.Sprintf("max=%d", *schema.MaxItems))
+			}
+		}
+		if rule := oneOfRule(schema.Enum); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// boundRule converts an inclusive or exclusive bound into a validator rule
+func boundRule(inclusive, exclusive string, bound *float64, excl models.ExclusiveBound) []string {
+	var rules []string
+	switch {
+	case excl.Value != nil:
+		rules = append(rules, exclusive+"="+strconv.FormatFloat(*excl.Value, 'f', -1, 64))
+	case bound != nil && excl.Set:
+		rules = append(rules, exclusive+"="+strconv.FormatFloat(*bound, 'f', -1, 64))
+	case bound != nil:
+		rules = append(rules, inclusive+"="+strconv.FormatFloat(*bound, 'f', -1, 64))
+	}
+	return rules
+}
+
+// oneOfRule builds a oneof rule, or nothing when a value cannot be expressed in one
+func oneOfRule(values []interface{}) string {
+	var parts []string
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		s := fmt.Sprint(v)
+		if s == "" || strings.ContainsAny(s, " ,|\"`'\\") {
+			return ""
+		}
+		parts = append(parts, s)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "oneof=" + strings.Join(parts, " ")
+}
+
+// paramType returns the Go type of a parameter; complex schemas are read as raw strings
+func paramType(schema *models.OpenAPISchema) string {
+	if schema == nil {
+		return "string"
+	}
+	switch schemaType(schema) {
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]string"
+	}
+	return "string"
+}
+
+// schemaType returns the non-null type of a schema, inferring objects from their properties
+func schemaType(schema *models.OpenAPISchema) string {
+	for _, t := range schema.Type {
+		if t != "null" {
+			return t
+		}
+	}
+	if len(schema.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// isObjectSchema reports whether a schema becomes a struct
+func isObjectSchema(schema *models.OpenAPISchema) bool {
+	if len(schema.Properties) > 0 || len(schema.AllOf) > 1 {
+		return true
+	}
+	if len(schema.AllOf) == 1 && schemaType(schema) == "object" {
+		return true
+	}
+	return schemaType(schema) == "object" && schema.AdditionalProperties == nil && len(schema.OneOf) == 0 && len(schema.AnyOf) == 0
+}
+
+// isNullable reports whether a schema allows null (3.0 nullable or a 3.1 "null" type)
+func isNullable(schema *models.OpenAPISchema) bool {
+	if schema == nil {
+		return false
+	}
+	if schema.Nullable {
+		return true
+	}
+	for _, t := range schema.Type {
+		if t == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// canBeNil reports whether the zero value of goType is nil
+func canBeNil(goType string) bool {
+	return strings.HasPrefix(goType, "*") || strings.HasPrefix(goType, "[]") ||
+		strings.HasPrefix(goType, "map[") || goType == "interface{}"
+}
+
+// jsonMedia returns the JSON media type of a content map
+func jsonMedia(content map[string]*models.OpenAPIMediaType) *models.OpenAPIMediaType {
+	if media, ok := content[constants.ContentTypeJSON]; ok && media != nil {
+		return media
+	}
+	for _, contentType := range sortedKeys(content) {
+		if strings.HasSuffix(contentType, "+json") || strings.HasSuffix(contentType, "/json") {
+			return content[contentType]
+		}
+	}
+	return nil
+}
+
+// basePath returns the path of the first server URL without a trailing slash
+func basePath(servers []models.OpenAPIServer) string {
+	if len(servers) == 0 || strings.Contains(servers[0].URL, "{") {
+		return ""
+	}
+	u, err := url.Parse(servers[0].URL)
+	if err != nil || !strings.HasPrefix(u.Path, "/") || strings.ContainsAny(u.Path, ":*") {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// builtinRoute returns the built-in route a path collides with, if any
+func builtinRoute(p string) (string, bool) {
+	for route, prefix := range builtinRoutes {
+		if p == route || (prefix && strings.HasPrefix(p, route+"/")) {
+			return route, true
+		}
+	}
+	return "", false
+}
+
+// operationName builds the default name of an operation without an operationId from its
+// method and path. A segment followed by a path parameter names a single item, so it is made
+// singular: GET /orders/{order-id}/items yields "GetOrderItemsByOrderID".
+func operationName(method, p string) string {
+	var name, params strings.Builder
+	name.WriteString(pascalCase(strings.ToLower(method)))
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	static := 0
+	for i, segment := range segments {
+		if m := pathParamPattern.FindStringSubmatch(segment); m != nil {
+			if params.Len() > 0 {
+				params.WriteString("And")
+			}
+			params.WriteString(pascalCase(asciiWords(m[1])))
+			continue
+		}
+		words := splitWords(asciiWords(segment))
+		if len(words) == 0 {
+			continue
+		}
+		if i+1 < len(segments) && pathParamPattern.MatchString(segments[i+1]) {
+			words[len(words)-1] = singular(words[len(words)-1])
+		}
+		name.WriteString(pascalCase(strings.Join(words, "_")))
+		static++
+	}
+	if static == 0 {
+		name.WriteString("Root")
+	}
+	if params.Len() > 0 {
+		name.WriteString("By" + params.String())
+	}
+	return name.String()
+}
+
+// routerVerb returns the route registration method of the framework for an HTTP method
+func routerVerb(framework, method string) string {
+	if framework == "fiber" || framework == constants.FrameworkChi {
+		return method[:1] + strings.ToLower(method[1:])
+	}
+	return method
+}
+
+// routeParam returns the router path placeholder of the framework for a path parameter
+func routeParam(framework, key string) string {
+	if framework == constants.FrameworkNetHTTP || framework == constants.FrameworkChi {
+		return "{" + key + "}"
+	}
+	return ":" + key
+}
+
+// oneLine collapses text into a single line suitable for a Go comment
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// sortedKeys returns the keys of a string-keyed map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
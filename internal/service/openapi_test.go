@@ -0,0 +1,422 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+)
+
+// buildAPI parses an OpenAPI document with the given top-level sections after the openapi
+// and info header, and builds the view of a framework
+func buildAPI(t *testing.T, framework, sections string) (*APIView, *apiBuilder, error) {
+	t.Helper()
+	doc, _, err := parseOpenAPI("openapi: 3.0.3\ninfo:\n  title: Test\n  version: \"1\"\n" + sections)
+	if err != nil {
+		t.Fatalf("parseOpenAPI() error = %v", err)
+	}
+	b := newAPIBuilder(doc, framework)
+	view, err := b.build()
+	return view, b, err
+}
+
+func TestOperationName(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"GET", "/pets", "GetPets"},
+		{"GET", "/pets/{petId}", "GetPetByPetID"},
+		{"GET", "/orders/{order-id}/items", "GetOrderItemsByOrderID"},
+		{"DELETE", "/orders/{orderId}/items/{itemId}", "DeleteOrderItemByOrderIDAndItemID"},
+		{"PUT", "/categories/{id}", "PutCategoryByID"},
+		{"POST", "/v1/user-profiles/", "PostV1UserProfiles"},
+		{"GET", "/", "GetRoot"},
+		{"GET", "/{id}", "GetRootByID"},
+	}
+	for _, tt := range tests {
+		if got := operationName(tt.method, tt.path); got != tt.want {
+			t.Errorf("operationName(%s, %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAPIBuilder(t *testing.T) {
+	tests := []struct {
+		name      string
+		framework string
+		sections  string
+		wantErr   string
+		check     func(t *testing.T, view *APIView, b *apiBuilder)
+	}{
+		{
+			name:      "references",
+			framework: "gin",
+			sections: `
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      parameters:
+        - $ref: "#/components/parameters/Trace"
+      requestBody:
+        $ref: "#/components/requestBodies/NewPet"
+      responses:
+        "201":
+          $ref: "#/components/responses/Pet"
+components:
+  parameters:
+    Trace:
+      name: X-Trace
+      in: header
+      schema: {type: string}
+  requestBodies:
+    NewPet:
+      required: true
+      content:
+        application/json:
+          schema: {$ref: "#/components/schemas/NewPet"}
+  responses:
+    Pet:
+      description: A pet
+      content:
+        application/json:
+          schema: {$ref: "#/components/schemas/Pet"}
+  schemas:
+    NewPet:
+      type: object
+      properties:
+        name: {type: string}
+    Pet:
+      allOf:
+        - $ref: "#/components/schemas/NewPet"
+        - type: object
+          properties:
+            id: {type: integer}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				op := view.Operations[0]
+				if op.Body == nil || op.Body.GoType != "NewPet" || !op.Body.Required {
+					t.Errorf("body = %+v, want a required NewPet", op.Body)
+				}
+				if op.Response != "Pet" || op.Status != 201 {
+					t.Errorf("response = %d %s, want 201 Pet", op.Status, op.Response)
+				}
+				if len(op.Inputs) != 1 || op.Inputs[0].Key != "X-Trace" || op.Inputs[0].In != "header" {
+					t.Errorf("inputs = %+v, want the X-Trace header", op.Inputs)
+				}
+				if fields := b.types["Pet"].Fields; len(fields) != 2 || fields[0].Name != "ID" || fields[1].Name != "Name" {
+					t.Errorf("Pet fields = %+v, want the fields of both allOf parts", fields)
+				}
+			},
+		},
+		{
+			name:      "unresolved reference",
+			framework: "gin",
+			sections: `
+paths:
+  /pets:
+    get:
+      responses:
+        "200": {$ref: "#/components/responses/Missing"}
+`,
+			wantErr: "GET /pets: unresolved reference #/components/responses/Missing",
+		},
+		{
+			name:      "path-level parameters",
+			framework: "echo",
+			sections: `
+paths:
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer}}
+      - {name: limit, in: query, schema: {type: integer}}
+    get:
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: string}}
+        - {name: sort, in: query, schema: {type: string}}
+      responses:
+        "200": {description: OK}
+    delete:
+      responses:
+        "204": {description: Deleted}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				get, del := view.Operations[0], view.Operations[1]
+				if len(get.Inputs) != 3 || get.Inputs[1].Key != "limit" || get.Inputs[1].GoType != "string" || !get.Inputs[1].Required {
+					t.Errorf("GET inputs = %+v, want limit overridden by the operation", get.Inputs)
+				}
+				if len(del.Inputs) != 2 || del.Inputs[1].GoType != "*int64" {
+					t.Errorf("DELETE inputs = %+v, want the path-level parameters", del.Inputs)
+				}
+				if get.Route != "/pets/:id" {
+					t.Errorf("route = %s, want /pets/:id", get.Route)
+				}
+			},
+		},
+		{
+			name:      "undeclared path parameter",
+			framework: "gin",
+			sections: `
+paths:
+  /pets/{id}:
+    get:
+      responses:
+        "200": {description: OK}
+`,
+			wantErr: "GET /pets/{id}: path parameter id is not declared",
+		},
+		{
+			name:      "base path",
+			framework: "chi",
+			sections: `
+servers:
+  - url: https://api.example.com/v1/
+  - url: /v2
+paths:
+  /pets:
+    get:
+      responses:
+        "200": {description: OK}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				if view.BasePath != "/v1" || view.Operations[0].Route != "/v1/pets" {
+					t.Errorf("base path = %q, route = %q, want /v1", view.BasePath, view.Operations[0].Route)
+				}
+			},
+		},
+		{
+			name:      "server variables",
+			framework: "chi",
+			sections: `
+servers:
+  - url: https://{region}.example.com/v1
+paths:
+  /pets:
+    get:
+      responses:
+        "200": {description: OK}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				if view.BasePath != "" {
+					t.Errorf("base path = %q, want none for a templated server URL", view.BasePath)
+				}
+			},
+		},
+		{
+			name:      "nethttp trailing slash",
+			framework: constants.FrameworkNetHTTP,
+			sections: `
+paths:
+  /pets/:
+    get:
+      responses:
+        "200": {description: OK}
+  /pets/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "200": {description: OK}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				if got := view.Operations[0].Route; got != "/pets/{$}" {
+					t.Errorf("route = %s, want /pets/{$}", got)
+				}
+				if got := view.Operations[1].Route; got != "/pets/{id}" {
+					t.Errorf("route = %s, want /pets/{id}", got)
+				}
+			},
+		},
+		{
+			name:      "default operation names",
+			framework: "gin",
+			sections: `
+paths:
+  /orders/{order-id}/items:
+    get:
+      parameters:
+        - {name: order-id, in: path, required: true, schema: {type: string}}
+      responses:
+        "200": {description: OK}
+    post:
+      operationId: addItem
+      parameters:
+        - {name: order-id, in: path, required: true, schema: {type: string}}
+      responses:
+        "201": {description: Created}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				get, post := view.Operations[0], view.Operations[1]
+				if get.Name != "GetOrderItemsByOrderID" || get.ID != "GetOrderItemsByOrderID" || get.Func != "getOrderItemsByOrderId" {
+					t.Errorf("GET names = %s, %s, %s", get.Name, get.ID, get.Func)
+				}
+				if post.Name != "AddItem" || post.ID != "addItem" {
+					t.Errorf("POST names = %s, %s, want the operationId", post.Name, post.ID)
+				}
+				if get.Route != "/orders/:order_id/items" {
+					t.Errorf("route = %s", get.Route)
+				}
+			},
+		},
+		{
+			name:      "health route",
+			framework: "gin",
+			sections: `
+paths:
+  /health:
+    get:
+      responses:
+        "200": {description: OK}
+`,
+			wantErr: "GET /health: the path collides with the /health route",
+		},
+		{
+			name:      "swagger route",
+			framework: constants.FrameworkNetHTTP,
+			sections: `
+servers:
+  - url: /swagger
+paths:
+  /spec:
+    get:
+      responses:
+        "200": {description: OK}
+`,
+			wantErr: "GET /spec: the path collides with the /swagger route",
+		},
+		{
+			name:      "health below the base path",
+			framework: "gin",
+			sections: `
+servers:
+  - url: /v1
+paths:
+  /health:
+    get:
+      responses:
+        "200": {description: OK}
+  /healthz:
+    get:
+      responses:
+        "200": {description: OK}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				if len(view.Operations) != 2 || view.Operations[0].Route != "/v1/health" {
+					t.Errorf("operations = %+v", view.Operations)
+				}
+			},
+		},
+		{
+			name:      "form body",
+			framework: "gin",
+			sections: `
+paths:
+  /uploads:
+    post:
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              properties:
+                file: {type: string, format: binary}
+      responses:
+        "201": {description: Created}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				if op := view.Operations[0]; op.Body != nil || op.Params != "" {
+					t.Errorf("operation = %+v, want no body", op)
+				}
+				if len(b.warnings) != 1 || b.warnings[0].Code != constants.WarnUnsupportedMediaType ||
+					!strings.Contains(b.warnings[0].Message, "POST /uploads: multipart/form-data request body") {
+					t.Errorf("warnings = %v", b.warnings)
+				}
+			},
+		},
+		{
+			name:      "parameter rules",
+			framework: "gin",
+			sections: `
+paths:
+  /pets/{id}/toys/{slot}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+        - {name: slot, in: path, required: true, schema: {type: integer, minimum: 1}}
+        - {name: page, in: query, required: true, schema: {type: integer, minimum: 0}}
+        - {name: limit, in: query, schema: {type: integer, maximum: 100}}
+      responses:
+        "200": {description: OK}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				// Binding rejects missing required parameters, so their zero values stay valid
+				want := []string{"", `validate:"gte=1"`, `validate:"gte=0"`, `validate:"omitempty,lte=100"`}
+				fields := b.types[view.Operations[0].Params].Fields
+				if len(fields) != len(want) {
+					t.Fatalf("fields = %+v", fields)
+				}
+				for i, field := range fields {
+					if field.Tag != want[i] {
+						t.Errorf("%s tag = %q, want %q", field.Name, field.Tag, want[i])
+					}
+				}
+			},
+		},
+		{
+			name:      "oneOf and anyOf",
+			framework: "gin",
+			sections: `
+paths:
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                pet:
+                  oneOf: [{type: string}, {type: integer}]
+                tag:
+                  anyOf: [{type: string}, {type: boolean}]
+      responses:
+        "201": {description: Created}
+`,
+			check: func(t *testing.T, view *APIView, b *apiBuilder) {
+				body := view.Operations[0].Body.GoType
+				for _, field := range b.types[body].Fields {
+					if field.GoType != "interface{}" {
+						t.Errorf("%s type = %s, want interface{}", field.Name, field.GoType)
+					}
+				}
+				want := []string{
+					body + "Pet: oneOf schemas are not mapped",
+					body + "Tag: anyOf schemas are not mapped",
+				}
+				if len(b.warnings) != len(want) {
+					t.Fatalf("warnings = %v", b.warnings)
+				}
+				for i, w := range b.warnings {
+					if w.Code != constants.WarnUnsupportedSchema || !strings.HasPrefix(w.Message, want[i]) {
+						t.Errorf("warning %d = %v, want %q", i, w, want[i])
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view, b, err := buildAPI(t, tt.framework, tt.sections)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("build() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("build() error = %v", err)
+			}
+			tt.check(t, view, b)
+		})
+	}
+}
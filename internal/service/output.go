@@ -0,0 +1,120 @@
+package service
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+)
+
+// outputSink receives the files of a generated project. Names are slash-separated paths
+// relative to the project root; directories are implied by the files written into them.
+type outputSink interface {
+	// WriteFile adds a file. Writing a name twice fails with fs.ErrExist: two parts of the
+	// project would otherwise land on the same path and one would silently replace the other.
+	WriteFile(name string, data []byte) error
+	// ReplaceFile replaces the content of a file already written, e.g. once it is formatted
+	ReplaceFile(name string, data []byte) error
+	ReadFile(name string) ([]byte, error)
+	// Files lists the names of every written file in path order
+	Files() ([]string, error)
+}
+
+// memoryOutput keeps the generated files in memory. It is the sink of every request.
+type memoryOutput struct {
+	files map[string][]byte
+}
+
+func newMemoryOutput() *memoryOutput {
+	return &memoryOutput{files: make(map[string][]byte)}
+}
+
+func (m *memoryOutput) WriteFile(name string, data []byte) error {
+	if _, ok := m.files[path.Clean(name)]; ok {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+	m.files[path.Clean(name)] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memoryOutput) ReplaceFile(name string, data []byte) error {
+	if _, ok := m.files[path.Clean(name)]; !ok {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrNotExist}
+	}
+	m.files[path.Clean(name)] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memoryOutput) ReadFile(name string) ([]byte, error) {
+	data, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return data, nil
+}
+
+func (m *memoryOutput) Files() ([]string, error) {
+	return sortedKeys(m.files), nil
+}
+
+// diskOutput writes the generated files under a root directory. Files already present in the
+// directory do not count as written; only the files of this generation are checked for
+// duplicates.
+type diskOutput struct {
+	root    string
+	written map[string]bool
+}
+
+func newDiskOutput(root string) *diskOutput {
+	return &diskOutput{root: root, written: make(map[string]bool)}
+}
+
+func (d *diskOutput) WriteFile(name string, data []byte) error {
+	if d.written[path.Clean(name)] {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+	p := d.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), constants.DirPerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(p, data, fileMode(name)); err != nil {
+		return err
+	}
+	d.written[path.Clean(name)] = true
+	return nil
+}
+
+func (d *diskOutput) ReplaceFile(name string, data []byte) error {
+	if !d.written[path.Clean(name)] {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrNotExist}
+	}
+	return os.WriteFile(d.path(name), data, fileMode(name))
+}
+
+func (d *diskOutput) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(d.path(name))
+}
+
+func (d *diskOutput) Files() ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(d.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(d.root, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+func (d *diskOutput) path(name string) string {
+	return filepath.Join(d.root, filepath.FromSlash(name))
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+// repoService returns a service on the repository's manifest. Template paths are relative
+// to the repository root, so the test runs from there.
+func repoService(tb testing.TB) *GeneratorService {
+	tb.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.Chdir("../.."); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { os.Chdir(wd) })
+
+	s, err := NewGeneratorService(constants.DefaultManifestPath)
+	if err != nil {
+		tb.Fatalf("NewGeneratorService() error = %v", err)
+	}
+	return s
+}
+
+func benchmarkRequest() *GenerateRequest {
+	return &GenerateRequest{
+		ProjectName:    "demo",
+		ModuleName:     "example.com/demo",
+		Framework:      "gin",
+		Libs:           []string{"postgres", "redis", "kafka", "cron"},
+		IncludeExample: true,
+	}
+}
+
+func TestOutputSinks(t *testing.T) {
+	s := repoService(t)
+	req := benchmarkRequest()
+
+	memory := newMemoryOutput()
+	if _, err := s.generate(context.Background(), req, memory); err != nil {
+		t.Fatalf("generate() to memory error = %v", err)
+	}
+	disk := newDiskOutput(t.TempDir())
+	if _, err := s.generate(context.Background(), req, disk); err != nil {
+		t.Fatalf("generate() to disk error = %v", err)
+	}
+
+	memoryFiles, _ := memory.Files()
+	diskFiles, err := disk.Files()
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	if len(memoryFiles) == 0 || len(memoryFiles) != len(diskFiles) {
+		t.Fatalf("memory has %d files, disk has %d", len(memoryFiles), len(diskFiles))
+	}
+	for i, name := range memoryFiles {
+		if diskFiles[i] != name {
+			t.Fatalf("file %d: memory %s, disk %s", i, name, diskFiles[i])
+		}
+		want, _ := memory.ReadFile(name)
+		got, err := disk.ReadFile(name)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s differs on disk (error %v)", name, err)
+		}
+	}
+
+	if _, err := memory.ReadFile("missing.go"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile() of a missing file error = %v, want not exist", err)
+	}
+}
+
+func TestOutputDuplicates(t *testing.T) {
+	sinks := map[string]outputSink{
+		"memory": newMemoryOutput(),
+		"disk":   newDiskOutput(t.TempDir()),
+	}
+	for name, out := range sinks {
+		if err := out.WriteFile("internal/app/server.go", []byte("a")); err != nil {
+			t.Fatalf("%s: WriteFile() error = %v", name, err)
+		}
+		if err := out.WriteFile("internal/app/./server.go", []byte("b")); !stderrors.Is(err, fs.ErrExist) {
+			t.Errorf("%s: second WriteFile() error = %v, want fs.ErrExist", name, err)
+		}
+		if err := out.ReplaceFile("internal/app/server.go", []byte("c")); err != nil {
+			t.Errorf("%s: ReplaceFile() error = %v", name, err)
+		}
+		if data, _ := out.ReadFile("internal/app/server.go"); string(data) != "c" {
+			t.Errorf("%s: ReadFile() = %q, want the replaced content", name, data)
+		}
+		if err := out.ReplaceFile("missing.go", nil); !stderrors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: ReplaceFile() of a missing file error = %v, want fs.ErrNotExist", name, err)
+		}
+	}
+
+	s := repoService(t)
+	out := newMemoryOutput()
+	if err := s.renderTemplate(out, constants.TemplateGoMod, "go.mod", map[string]interface{}{}); err != nil {
+		t.Fatalf("renderTemplate() error = %v", err)
+	}
+	err := s.renderTemplate(out, constants.TemplateMain, "go.mod", map[string]interface{}{})
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Context["template_path"] != constants.TemplateMain || appErr.Context["output_path"] != "go.mod" ||
+		!strings.Contains(appErr.Message, constants.TemplateMain+" writes go.mod") {
+		t.Errorf("renderTemplate() of a duplicate error = %v, want both paths", err)
+	}
+}
+
+func BenchmarkGenerateProject_Memory(b *testing.B) {
+	s := repoService(b)
+	req := benchmarkRequest()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		out := newMemoryOutput()
+		if _, err := s.generate(context.Background(), req, out); err != nil {
+			b.Fatal(err)
+		}
+		if err := writeArchive(io.Discard, out, constants.ArchiveFormatZip, constants.MinArchiveTime); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGenerateProject_Disk renders through a temporary directory, as every request did
+// before the output went to memory
+func BenchmarkGenerateProject_Disk(b *testing.B) {
+	s := repoService(b)
+	req := benchmarkRequest()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tmp, err := os.MkdirTemp("", constants.TempDirPrefix+req.ProjectName+"-*")
+		if err != nil {
+			b.Fatal(err)
+		}
+		out := newDiskOutput(tmp)
+		if _, err := s.generate(context.Background(), req, out); err != nil {
+			b.Fatal(err)
+		}
+		if err := writeArchive(io.Discard, out, constants.ArchiveFormatZip, constants.MinArchiveTime); err != nil {
+			b.Fatal(err)
+		}
+		os.RemoveAll(tmp)
+	}
+}
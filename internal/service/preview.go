@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// PreviewFile is a generated file as listed by the preview endpoints
+type PreviewFile struct {
+	Path    string `json:"path"`              // slash-separated path relative to the project root
+	Size    int    `json:"size"`              // size in bytes
+	SHA256  string `json:"sha256"`            // hex-encoded SHA-256 of the contents
+	Content string `json:"content,omitempty"` // file contents, only for requested files
+}
+
+// PreviewResult lists the files of a generated project and the warnings raised while generating it
+type PreviewResult struct {
+	Files    []PreviewFile    `json:"files"`
+	Warnings []models.Warning `json:"warnings,omitempty"`
+}
+
+// PreviewProject runs the generation pipeline and lists the generated files in path order.
+// Every file carries its contents when paths is empty; otherwise only the files in paths do,
+// and a path that was not generated is a not-found error.
+func (s *GeneratorService) PreviewProject(ctx context.Context, req *GenerateRequest, paths []string) (*PreviewResult, error) {
+	wanted := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		wanted[cleanPreviewPath(p)] = true
+	}
+
+	out := newMemoryOutput()
+	warnings, err := s.generate(ctx, req, out)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := previewFiles(out, wanted)
+	if err != nil {
+		return nil, errors.ErrFileSystem("Failed to read generated files", err)
+	}
+
+	for _, file := range files {
+		delete(wanted, file.Path)
+	}
+	if missing := sortedKeys(wanted); len(missing) > 0 {
+		return nil, errors.ErrNotFound("file "+missing[0]).WithContext("path", missing[0])
+	}
+
+	return &PreviewResult{Files: files, Warnings: warnings}, nil
+}
+
+// previewFiles lists the generated files. Contents are included for the paths in wanted,
+// or for every file when wanted is empty.
+func previewFiles(out outputSink, wanted map[string]bool) ([]PreviewFile, error) {
+	names, err := out.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]PreviewFile, 0, len(names))
+	for _, name := range names {
+		data, err := out.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(data)
+		file := PreviewFile{
+			Path:   name,
+			Size:   len(data),
+			SHA256: hex.EncodeToString(sum[:]),
+		}
+		if len(wanted) == 0 || wanted[file.Path] {
+			file.Content = string(data)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// cleanPreviewPath normalizes a requested path, e.g. "./cmd//main.go" to "cmd/main.go"
+func cleanPreviewPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
+
+// PreviewProjectFile runs the generation pipeline and returns the generated file at path
+func (s *GeneratorService) PreviewProjectFile(ctx context.Context, req *GenerateRequest, p string) (*PreviewFile, []models.Warning, error) {
+	result, err := s.PreviewProject(ctx, req, []string{p})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	want := cleanPreviewPath(p)
+	for i := range result.Files {
+		if result.Files[i].Path == want {
+			return &result.Files[i], result.Warnings, nil
+		}
+	}
+	return nil, nil, errors.ErrNotFound("file "+want).WithContext("path", want)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestPreviewFiles(t *testing.T) {
+	out := newMemoryOutput()
+	files := map[string]string{
+		"go.mod":                  "module example.com/demo\n",
+		"cmd/main.go":             "package main\n",
+		"internal/deps/config.go": "package deps\n",
+		"config/config.json":      "{}\n",
+	}
+	for name, content := range files {
+		if err := out.WriteFile(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := previewFiles(out, map[string]bool{cleanPreviewPath("./cmd//main.go"): true})
+	if err != nil {
+		t.Fatalf("previewFiles() error = %v", err)
+	}
+
+	wantOrder := []string{"cmd/main.go", "config/config.json", "go.mod", "internal/deps/config.go"}
+	if len(got) != len(wantOrder) {
+		t.Fatalf("got %d files, want %d", len(got), len(wantOrder))
+	}
+	for i, file := range got {
+		if file.Path != wantOrder[i] {
+			t.Errorf("file %d = %s, want %s", i, file.Path, wantOrder[i])
+		}
+		if file.Size != len(files[file.Path]) || len(file.SHA256) != 64 {
+			t.Errorf("%s: size %d, sha256 %q", file.Path, file.Size, file.SHA256)
+		}
+		wantContent := ""
+		if file.Path == "cmd/main.go" {
+			wantContent = files[file.Path]
+		}
+		if file.Content != wantContent {
+			t.Errorf("%s: content = %q, want %q", file.Path, file.Content, wantContent)
+		}
+	}
+
+	all, err := previewFiles(out, nil)
+	if err != nil {
+		t.Fatalf("previewFiles() error = %v", err)
+	}
+	for _, file := range all {
+		if file.Content != files[file.Path] {
+			t.Errorf("%s: content = %q, want every file's contents", file.Path, file.Content)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+// Progress event types
+const (
+	ProgressStart  = "start"
+	ProgressFinish = "finish"
+)
+
+// ProgressEvent reports the start or the finish of a generation stage, e.g. "framework",
+// "library redis" or "format"
+type ProgressEvent struct {
+	Type  string `json:"type"`
+	Stage string `json:"stage"`
+	// DurationMs is the time the stage took (finish only)
+	DurationMs float64 `json:"durationMs,omitempty"`
+	// Files is the number of files the stage wrote (finish only)
+	Files int `json:"files"`
+	// TotalFiles is the number of files generated so far
+	TotalFiles int `json:"totalFiles"`
+}
+
+// ProgressFunc receives the progress events of a generation
+type ProgressFunc func(event ProgressEvent)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose generations report their progress to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// stageTracker splits a generation into stages: starting one finishes the previous one.
+// Stages are reported to the progress function of the context, if any, and a done context
+// stops the generation at the next stage.
+type stageTracker struct {
+	ctx   context.Context
+	out   outputSink
+	fn    ProgressFunc
+	stage string
+	start time.Time
+	files int
+}
+
+func newStageTracker(ctx context.Context, out outputSink) *stageTracker {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return &stageTracker{ctx: ctx, out: out, fn: fn}
+}
+
+// Start finishes the current stage and starts the next one. Once the context is done it
+// returns a CANCELLED or TIMEOUT error naming the stage instead.
+func (t *stageTracker) Start(stage string) error {
+	t.Finish()
+	if err := t.ctx.Err(); err != nil {
+		return contextError(err, stage)
+	}
+
+	t.stage, t.start = stage, time.Now()
+	if t.fn != nil {
+		t.files = t.countFiles()
+		t.fn(ProgressEvent{Type: ProgressStart, Stage: stage, TotalFiles: t.files})
+	}
+	return nil
+}
+
+// Finish finishes the current stage, if any
+func (t *stageTracker) Finish() {
+	if t.stage == "" {
+		return
+	}
+	if t.fn != nil {
+		total := t.countFiles()
+		t.fn(ProgressEvent{
+			Type:       ProgressFinish,
+			Stage:      t.stage,
+			DurationMs: float64(time.Since(t.start).Microseconds()) / 1000,
+			Files:      total - t.files,
+			TotalFiles: total,
+		})
+	}
+	t.stage = ""
+}
+
+func (t *stageTracker) countFiles() int {
+	names, _ := t.out.Files()
+	return len(names)
+}
+
+// contextError turns the error of a done context into a CANCELLED or TIMEOUT error naming
+// the stage that was about to start
+func contextError(err error, stage string) *errors.AppError {
+	var appErr *errors.AppError
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		appErr = errors.ErrTimeout(constants.ErrGenerationTimeout, err)
+	} else {
+		appErr = errors.ErrCancelled(constants.ErrGenerationCancelled, err)
+	}
+	return appErr.WithContext("stage", stage)
+}
@@ -1,22 +1,39 @@
 package service
 
 import (
-	"path/filepath"
-
 	"github.com/xhkzeroone/go-generator/internal/constants"
 )
 
 // renderProjectFiles renders additional project files (Dockerfile, .gitignore, etc.)
-func (s *GeneratorService) renderProjectFiles(tmp string, req *GenerateRequest, includes map[string]bool) error {
+func (s *GeneratorService) renderProjectFiles(out outputSink, req *GenerateRequest, entities []EntityView, api *APIView, proto *ProtoView, includes map[string]bool, config map[string]interface{}) error {
 	// Render Dockerfile
+	ports := []int{constants.DefaultPortNum}
+	if req.Framework == constants.FrameworkGRPC {
+		ports = []int{constants.DefaultGRPCPort}
+		if includes[constants.LibGRPCGateway] {
+			ports = append(ports, constants.DefaultPortNum)
+		}
+	}
 	dockerData := map[string]interface{}{
 		"ModuleName":  req.ModuleName,
 		"ProjectName": req.ProjectName,
 		"BinaryName":  req.ProjectName,
-		"Port":        constants.DefaultPortNum,
+		"Ports":       ports,
 		"GoVersion":   constants.DefaultGoVersion,
+		"ConfigPath":  configFilePath(req),
+	}
+	if err := s.renderTemplate(out, constants.TemplateDockerfile, constants.DockerfileName, dockerData); err != nil {
+		return err
+	}
+
+	// Render Makefile
+	makefileData := map[string]interface{}{
+		"ProjectName": req.ProjectName,
+		"Framework":   req.Framework,
+		"API":         api,
+		"Proto":       proto,
 	}
-	if err := s.renderTemplate(constants.TemplateDockerfile, filepath.Join(tmp, constants.DockerfileName), dockerData); err != nil {
+	if err := s.renderTemplate(out, constants.TemplateMakefile, constants.MakefileName, makefileData); err != nil {
 		return err
 	}
 
@@ -25,7 +42,7 @@ func (s *GeneratorService) renderProjectFiles(tmp string, req *GenerateRequest,
 		"ModuleName":  req.ModuleName,
 		"ProjectName": req.ProjectName,
 	}
-	if err := s.renderTemplate(constants.TemplateGitignore, filepath.Join(tmp, constants.GitignoreFileName), gitignoreData); err != nil {
+	if err := s.renderTemplate(out, constants.TemplateGitignore, constants.GitignoreFileName, gitignoreData); err != nil {
 		return err
 	}
 
@@ -33,8 +50,10 @@ func (s *GeneratorService) renderProjectFiles(tmp string, req *GenerateRequest,
 	envExampleData := map[string]interface{}{
 		"ModuleName": req.ModuleName,
 		"Includes":   includes,
+		"ConfigPath": configFilePath(req),
+		"Sections":   envSections(req, config, s.configTitles(req)),
 	}
-	if err := s.renderTemplate(constants.TemplateEnvExample, filepath.Join(tmp, constants.EnvExampleFileName), envExampleData); err != nil {
+	if err := s.renderTemplate(out, constants.TemplateEnvExample, constants.EnvExampleFileName, envExampleData); err != nil {
 		return err
 	}
 
@@ -43,10 +62,16 @@ func (s *GeneratorService) renderProjectFiles(tmp string, req *GenerateRequest,
 		"ProjectName":    req.ProjectName,
 		"ModuleName":     req.ModuleName,
 		"Framework":      req.Framework,
-		"IncludeExample": req.IncludeExample,
+		"IncludeExample": len(entities) > 0,
+		"Entities":       entities,
+		"API":            api,
+		"Proto":          proto,
 		"Includes":       includes,
+		"ConfigPath":     configFilePath(req),
+		"ConfigFile":     configFileName(req),
+		"EnvPrefix":      envPrefix(req.ProjectName),
 	}
-	if err := s.renderTemplate(constants.TemplateReadme, filepath.Join(tmp, constants.ReadmeFileName), readmeData); err != nil {
+	if err := s.renderTemplate(out, constants.TemplateReadme, constants.ReadmeFileName, readmeData); err != nil {
 		return err
 	}
 
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+// ProtoView is the template-facing description of the gRPC API generated for the entities
+type ProtoView struct {
+	Package   string     // proto package, e.g. "my_shop.v1"
+	File      string     // proto file relative to the proto directory, e.g. "my_shop/v1/my_shop.proto"
+	GoPackage string     // Go package name of the generated stubs, e.g. "myshopv1"
+	GoImport  string     // Go import path of the generated stubs
+	Imports   []string   // proto files imported by the API
+	Services  []*RPCView // one service per entity
+	Stubs     []byte     // protoc-gen-go output for the proto file
+}
+
+// RPCView is the gRPC service of one entity and the messages it exchanges
+type RPCView struct {
+	Service  string       // service name, e.g. "OrderItemService"
+	Client   string       // unexported client type of the generated stubs, e.g. "orderItemServiceClient"
+	Comment  string       // leading comment of the service
+	Qual     string       // package qualifier of the generated stubs, e.g. "myshopv1."
+	Import   string       // import spec of the generated stubs
+	ID       RPCField     // id field of the entity and of the requests that select one
+	Fields   []RPCField   // fields of the entity message, numbered after the id
+	Methods  []RPCMethod  // unary methods in declaration order
+	Messages []RPCMessage // messages in declaration order
+}
+
+// RPCMethod is a unary method of a service
+type RPCMethod struct {
+	Name    string // method name, e.g. "GetOrderItem"
+	Input   string // request message
+	Output  string // response message
+	Comment string // leading comment
+}
+
+// RPCMessage is a message of the proto file
+type RPCMessage struct {
+	Name    string
+	Comment string
+	Fields  []RPCField
+}
+
+// RPCField is a field of a proto message
+type RPCField struct {
+	Field    FieldView // entity field carried by the proto field, zero for the id
+	Name     string    // proto field name, e.g. "created_at"
+	GoName   string    // Go field name in the generated message, e.g. "CreatedAt"
+	Kind     string    // entity field type, e.g. "time"
+	Type     string    // proto type, e.g. "google.protobuf.Timestamp"
+	Number   int32     // field number
+	Optional bool      // proto3 optional: presence is tracked and the Go field is a pointer
+}
+
+// protoTimestamp is the well-known type time fields are mapped to
+const protoTimestamp = "google.protobuf.Timestamp"
+
+// protoScalars maps entity field types to proto types. Types without a lossless proto
+// equivalent (uuid, decimal, enum) travel as strings, like in the JSON API.
+var protoScalars = map[string]descriptorpb.FieldDescriptorProto_Type{
+	constants.FieldTypeString:  descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	constants.FieldTypeInt:     descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	constants.FieldTypeBool:    descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	constants.FieldTypeFloat:   descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	constants.FieldTypeUUID:    descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	constants.FieldTypeDecimal: descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	constants.FieldTypeEnum:    descriptorpb.FieldDescriptorProto_TYPE_STRING,
+}
+
+// validateGRPC rejects combinations the gRPC framework cannot serve: OpenAPI operations
+// need an HTTP router
+func validateGRPC(req *GenerateRequest) error {
+	if req.Framework == constants.FrameworkGRPC && strings.TrimSpace(req.OpenAPI) != "" {
+		return errors.ErrValidation("openapi is not supported with the grpc framework", nil).
+			WithContext("framework", req.Framework)
+	}
+	return nil
+}
+
+// protoView builds the proto file of the entities' gRPC services, compiles its Go stubs,
+// and attaches each service to its entity view
+func (s *GeneratorService) protoView(ctx context.Context, req *GenerateRequest, entities []EntityView) (*ProtoView, error) {
+	base := strings.ReplaceAll(req.ProjectName, "-", "_")
+	if base[0] >= '0' && base[0] <= '9' {
+		base = "p" + base
+	}
+	view := &ProtoView{
+		Package:   base + ".v1",
+		File:      path.Join(base, "v1", base+".proto"),
+		GoPackage: strings.ReplaceAll(base, "_", "") + "v1",
+		GoImport:  path.Join(req.ModuleName, constants.DirGen, base, "v1"),
+	}
+	for i := range entities {
+		rpc := rpcView(entities[i])
+		for _, f := range rpc.Fields {
+			if f.Type == protoTimestamp && len(view.Imports) == 0 {
+				view.Imports = append(view.Imports, "google/protobuf/timestamp.proto")
+			}
+		}
+		rpc.Qual = view.GoPackage + "."
+		rpc.Import = fmt.Sprintf("%s %q", view.GoPackage, view.GoImport)
+		view.Services = append(view.Services, rpc)
+		entities[i].RPC = rpc
+	}
+
+	if err := compileProto(ctx, s.protocGenGo, view); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// rpcView describes the CRUD service of an entity: the entity message, one request
+// message per method and an empty delete response
+func rpcView(entity EntityView) *RPCView {
+	rpc := &RPCView{
+		Service: entity.Name + "Service",
+		Client:  strings.ToLower(entity.Name[:1]) + entity.Name[1:] + "ServiceClient",
+		Comment: fmt.Sprintf("%sService manages %s records.", entity.Name, entity.Label),
+		ID:      RPCField{Name: "id", Kind: entity.ID.Kind, Type: protoScalarName(entity.ID.Kind), Number: 1},
+	}
+	for i, f := range entity.Fields {
+		field := RPCField{Field: f, Name: snakeCase(f.Name), Kind: f.Kind, Type: protoScalarName(f.Kind), Number: int32(i + 2), Optional: f.Nullable}
+		if f.Kind == constants.FieldTypeTime {
+			field.Type = protoTimestamp
+			field.Optional = false
+		}
+		rpc.Fields = append(rpc.Fields, field)
+	}
+
+	// Create requests carry the id only when the database does not assign it
+	create := rpc.Fields
+	if !entity.ID.Generated {
+		create = append([]RPCField{rpc.ID}, create...)
+	}
+
+	// Every field of an update request is optional: unset fields are left unchanged
+	update := []RPCField{rpc.ID}
+	for _, f := range rpc.Fields {
+		f.Optional = f.Type != protoTimestamp
+		update = append(update, f)
+	}
+
+	name := entity.Name
+	rpc.Messages = []RPCMessage{
+		{Name: name, Comment: fmt.Sprintf("%s is the %s resource.", name, entity.Label), Fields: append([]RPCField{rpc.ID}, rpc.Fields...)},
+		{Name: "Get" + name + "Request", Comment: fmt.Sprintf("Get%sRequest selects the %s to return.", name, entity.Label), Fields: []RPCField{rpc.ID}},
+		{Name: "Create" + name + "Request", Comment: fmt.Sprintf("Create%sRequest holds the fields of the %s to create.", name, entity.Label), Fields: create},
+		{Name: "Update" + name + "Request", Comment: fmt.Sprintf("Update%sRequest holds the fields to change; unset fields are left unchanged.", name), Fields: update},
+		{Name: "Delete" + name + "Request", Comment: fmt.Sprintf("Delete%sRequest selects the %s to delete.", name, entity.Label), Fields: []RPCField{rpc.ID}},
+		{Name: "Delete" + name + "Response", Comment: fmt.Sprintf("Delete%sResponse is returned once the %s is deleted.", name, entity.Label)},
+	}
+	rpc.Methods = []RPCMethod{
+		{Name: "Get" + name, Input: "Get" + name + "Request", Output: name, Comment: fmt.Sprintf("Get%s returns the %s with the given id.", name, entity.Label)},
+		{Name: "Create" + name, Input: "Create" + name + "Request", Output: name, Comment: fmt.Sprintf("Create%s creates the %s described by the request.", name, entity.Label)},
+		{Name: "Update" + name, Input: "Update" + name + "Request", Output: name, Comment: fmt.Sprintf("Update%s changes the set fields of the %s with the given id.", name, entity.Label)},
+		{Name: "Delete" + name, Input: "Delete" + name + "Request", Output: "Delete" + name + "Response", Comment: fmt.Sprintf("Delete%s deletes the %s with the given id.", name, entity.Label)},
+	}
+	return rpc
+}
+
+// protoScalarName returns the proto type name of an entity field type
+func protoScalarName(kind string) string {
+	t := protoScalars[kind]
+	return strings.ToLower(strings.TrimPrefix(t.String(), "TYPE_"))
+}
+
+// compileProto runs the protoc-gen-go plugin on the descriptor of the view, storing the
+// generated file and the Go names it gives to message fields. The plugin runs as protoc would
+// run it, reading a CodeGeneratorRequest on stdin and writing the response to stdout.
+func compileProto(ctx context.Context, plugin string, view *ProtoView) error {
+	file := fileDescriptor(view)
+	protoFiles := []*descriptorpb.FileDescriptorProto{file}
+	if len(view.Imports) > 0 {
+		protoFiles = append([]*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto)}, protoFiles...)
+	}
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{view.File},
+		Parameter:      proto.String("paths=source_relative"),
+		ProtoFile:      protoFiles,
+	}
+
+	// protogen derives the Go field names the same way the plugin does
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		return fmt.Errorf("invalid proto descriptor: %w", err)
+	}
+	goNames := make(map[string]string)
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
+		}
+		for _, m := range f.Messages {
+			for _, field := range m.Fields {
+				goNames[string(field.Desc.FullName())] = field.GoName
+			}
+		}
+	}
+
+	resp, err := runProtocPlugin(ctx, plugin, req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("protoc-gen-go: %s", resp.GetError())
+	}
+	if len(resp.File) != 1 {
+		return fmt.Errorf("protoc-gen-go: expected one generated file, got %d", len(resp.File))
+	}
+	view.Stubs = []byte(resp.File[0].GetContent())
+
+	for _, rpc := range view.Services {
+		entity := strings.TrimSuffix(rpc.Service, "Service")
+		rpc.ID.GoName = goNames[view.Package+"."+entity+"."+rpc.ID.Name]
+		for i := range rpc.Fields {
+			rpc.Fields[i].GoName = goNames[view.Package+"."+entity+"."+rpc.Fields[i].Name]
+		}
+	}
+	return nil
+}
+
+// runProtocPlugin runs a protoc plugin on a code generation request
+func runProtocPlugin(ctx context.Context, plugin string, req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	input, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, plugin)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", plugin, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", plugin, err)
+	}
+
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return nil, fmt.Errorf("%s: invalid response: %w", plugin, err)
+	}
+	return resp, nil
+}
+
+// fileDescriptor builds the descriptor protoc would produce for the proto file of the view,
+// including the leading comments of messages, services and methods
+func fileDescriptor(view *ProtoView) *descriptorpb.FileDescriptorProto {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(view.File),
+		Package:    proto.String(view.Package),
+		Dependency: view.Imports,
+		Syntax:     proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{
+			GoPackage: proto.String(view.GoImport + ";" + view.GoPackage),
+		},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+	}
+	comment := func(text string, path ...int32) {
+		file.SourceCodeInfo.Location = append(file.SourceCodeInfo.Location, &descriptorpb.SourceCodeInfo_Location{
+			Path:            path,
+			Span:            []int32{0, 0, 0},
+			LeadingComments: proto.String(" " + text + "\n"),
+		})
+	}
+
+	for _, rpc := range view.Services {
+		for _, m := range rpc.Messages {
+			comment(m.Comment, 4, int32(len(file.MessageType)))
+			file.MessageType = append(file.MessageType, messageDescriptor(view, m))
+		}
+
+		service := &descriptorpb.ServiceDescriptorProto{Name: proto.String(rpc.Service)}
+		comment(rpc.Comment, 6, int32(len(file.Service)))
+		for i, m := range rpc.Methods {
+			comment(m.Comment, 6, int32(len(file.Service)), 2, int32(i))
+			service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+				Name:       proto.String(m.Name),
+				InputType:  proto.String("." + view.Package + "." + m.Input),
+				OutputType: proto.String("." + view.Package + "." + m.Output),
+			})
+		}
+		file.Service = append(file.Service, service)
+	}
+	return file
+}
+
+// messageDescriptor builds the descriptor of a message; each optional field gets the
+// synthetic oneof protoc declares for it
+func messageDescriptor(view *ProtoView, m RPCMessage) *descriptorpb.DescriptorProto {
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(m.Name)}
+	for _, f := range m.Fields {
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(f.Name),
+			Number:   proto.Int32(f.Number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			JsonName: proto.String(protoJSONName(f.Name)),
+		}
+		if f.Type == protoTimestamp {
+			field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			field.TypeName = proto.String("." + protoTimestamp)
+		} else {
+			field.Type = protoScalars[f.Kind].Enum()
+		}
+		if f.Optional {
+			field.Proto3Optional = proto.Bool(true)
+			field.OneofIndex = proto.Int32(int32(len(msg.OneofDecl)))
+			msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + f.Name)})
+		}
+		msg.Field = append(msg.Field, field)
+	}
+	return msg
+}
+
+// protoJSONName returns the JSON name protoc derives from a field name
+func protoJSONName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, c := range name {
+		switch {
+		case c == '_':
+			upper = true
+		case upper && c >= 'a' && c <= 'z':
+			b.WriteRune(c - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteRune(c)
+			upper = false
+		}
+	}
+	return b.String()
+}
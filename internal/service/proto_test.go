@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// TestMain builds protoc-gen-go at the version of go.mod and puts it first in PATH, so the
+// gRPC projects of the tests compile the stubs the golden files hold
+func TestMain(m *testing.M) {
+	os.Exit(runWithProtocGenGo(m))
+}
+
+func runWithProtocGenGo(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "protoc-gen-go")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.Command("go", "build", "-o", filepath.Join(dir, constants.ProtocGenGo), "google.golang.org/protobuf/cmd/protoc-gen-go")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "building protoc-gen-go:", err)
+		return 1
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return m.Run()
+}
+
+func TestRunProtocPlugin(t *testing.T) {
+	ctx := context.Background()
+	if _, err := runProtocPlugin(ctx, "protoc-gen-missing", &pluginpb.CodeGeneratorRequest{}); err == nil ||
+		!strings.Contains(err.Error(), "protoc-gen-missing") {
+		t.Errorf("runProtocPlugin(missing) error = %v, want the plugin named", err)
+	}
+
+	resp, err := runProtocPlugin(ctx, constants.ProtocGenGo, &pluginpb.CodeGeneratorRequest{})
+	if err != nil {
+		t.Fatalf("runProtocPlugin() error = %v", err)
+	}
+	if len(resp.File) != 0 || resp.Error != nil {
+		t.Errorf("response = %v, want no files for an empty request", resp)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := runProtocPlugin(cancelled, constants.ProtocGenGo, &pluginpb.CodeGeneratorRequest{}); err != context.Canceled {
+		t.Errorf("runProtocPlugin(cancelled) error = %v, want context.Canceled", err)
+	}
+}
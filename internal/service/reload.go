@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// snapshot is a consistent set of the manifest, the deps and config metadata and the
+// parsed templates. It is never changed once loaded: a reload replaces it.
+type snapshot struct {
+	manifest   *models.Manifest
+	depsMeta   map[string]DepMetadata
+	configMeta map[string]DepMeta
+	// configSections holds the parsed config sections of the frameworks and libs by path
+	configSections map[string]map[string]interface{}
+	templates      *templateCache
+	stamp          string // see sourceStamp
+}
+
+// snapshotSource loads snapshots and holds the current one, shared by every copy of the
+// service
+type snapshotSource struct {
+	manifestPath string
+	current      atomic.Pointer[snapshot]
+
+	// mu serializes reloads
+	mu sync.Mutex
+	// failedStamp is the stamp of the files the last reload failed on, so an unchanged
+	// broken file is not reloaded, and reported, on every check
+	failedStamp string
+}
+
+// ReloadResult describes the snapshot a reload loaded
+type ReloadResult struct {
+	// Reloaded is false when nothing changed since the current snapshot was loaded
+	Reloaded   bool    `json:"reloaded"`
+	Version    string  `json:"version"`
+	Frameworks int     `json:"frameworks"`
+	Libs       int     `json:"libs"`
+	Templates  int     `json:"templates"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// load reads, parses and validates a new snapshot
+func (src *snapshotSource) load() (*snapshot, error) {
+	// Stamp first: a file changed while loading changes the stamp again
+	stamp, err := src.stamp()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := loadManifest(src.manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	depsMeta, err := loadDepsMetadata()
+	if err != nil {
+		return nil, err
+	}
+	configMeta, err := loadConfigMetadata()
+	if err != nil {
+		return nil, err
+	}
+	configSections, err := loadConfigSections(manifest)
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{manifest: manifest, depsMeta: depsMeta, configMeta: configMeta, configSections: configSections, stamp: stamp}
+
+	templates, err := loadTemplateCache(constants.TemplateDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := snap.validateTemplates(templates); err != nil {
+		return nil, err
+	}
+	snap.templates = templates
+	return snap, nil
+}
+
+// stamp describes the manifest, the metadata files, the config sections and the templates
+// by path, size and modification time, so a change to any of them changes the stamp
+func (src *snapshotSource) stamp() (string, error) {
+	stamp, err := templateStamp(constants.TemplateDir)
+	if err != nil {
+		return "", errors.ErrFileSystem("Failed to list templates", err).
+			WithContext("path", constants.TemplateDir)
+	}
+	sections, err := dirStamp(constants.TemplateDir, constants.ConfigSectionExt)
+	if err != nil {
+		return "", errors.ErrFileSystem("Failed to list config sections", err).
+			WithContext("path", constants.TemplateDir)
+	}
+	stamp += sections
+	for _, p := range []string{src.manifestPath, constants.TemplateDepsMeta, constants.TemplateConfigMeta} {
+		info, err := os.Stat(p)
+		if err != nil {
+			return "", errors.ErrFileSystem("Failed to read file", err).
+				WithContext("path", p)
+		}
+		stamp += fmt.Sprintf("%s %d %d\n", filepath.ToSlash(p), info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp, nil
+}
+
+// pin returns a copy of the service bound to the current snapshot
+func (s *GeneratorService) pin() *GeneratorService {
+	c := *s
+	c.snapshot = s.sources.current.Load()
+	return &c
+}
+
+// Reload loads the manifest, the metadata and the templates again and swaps them in once
+// they parse and validate. Generations that already started keep the previous set; on
+// error the current set stays in use.
+func (s *GeneratorService) Reload() (*ReloadResult, error) {
+	s.sources.mu.Lock()
+	defer s.sources.mu.Unlock()
+	return s.reload()
+}
+
+// ReloadIfChanged reloads when a file changed since the current set was loaded. A set that
+// failed to load is not tried again until it changes.
+func (s *GeneratorService) ReloadIfChanged() (*ReloadResult, error) {
+	s.sources.mu.Lock()
+	defer s.sources.mu.Unlock()
+
+	stamp, err := s.sources.stamp()
+	if err != nil {
+		return nil, err
+	}
+	if stamp == s.sources.current.Load().stamp || stamp == s.sources.failedStamp {
+		return &ReloadResult{Reloaded: false}, nil
+	}
+	return s.reload()
+}
+
+// reload loads and swaps in a new snapshot; s.sources.mu must be held
+func (s *GeneratorService) reload() (*ReloadResult, error) {
+	start := time.Now()
+	snap, err := s.sources.load()
+	if err != nil {
+		s.sources.failedStamp, _ = s.sources.stamp()
+		return nil, err
+	}
+	s.sources.failedStamp = ""
+	s.sources.current.Store(snap)
+
+	return &ReloadResult{
+		Reloaded:   true,
+		Version:    snap.manifest.Version,
+		Frameworks: len(snap.manifest.Frameworks),
+		Libs:       len(snap.manifest.Libs),
+		Templates:  len(snap.templates.templates),
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}, nil
+}
+
+// ReloadFunc receives the outcome of an automatic reload
+type ReloadFunc func(result *ReloadResult, err error)
+
+// WatchFiles reloads, after a short quiet period, when the manifest, the metadata or a
+// template changes, until ctx is done. It fails when the files cannot be watched; PollFiles
+// is the fallback.
+func (s *GeneratorService) WatchFiles(ctx context.Context, report ReloadFunc) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// Editors often replace a file rather than write it, so directories are watched
+	dirs := []string{filepath.Dir(s.sources.manifestPath)}
+	err = filepath.WalkDir(constants.TemplateDir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, p)
+		}
+		return err
+	})
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		debounce := time.NewTimer(time.Hour)
+		debounce.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				debounce.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Watch the template directories created later too
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						_ = watcher.Add(event.Name)
+					}
+				}
+				debounce.Reset(constants.ReloadDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				report(nil, errors.ErrFileSystem("Failed to watch templates", err))
+			case <-debounce.C:
+				s.reportChange(report)
+			}
+		}
+	}()
+	return nil
+}
+
+// PollFiles checks the manifest, the metadata and the templates for changes every interval
+// and reloads when they changed, until ctx is done
+func (s *GeneratorService) PollFiles(ctx context.Context, interval time.Duration, report ReloadFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reportChange(report)
+		}
+	}
+}
+
+// reportChange reloads if the files changed and reports a reload or its failure
+func (s *GeneratorService) reportChange(report ReloadFunc) {
+	result, err := s.ReloadIfChanged()
+	if err != nil || result.Reloaded {
+		report(result, err)
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+// copyService returns a service on a copy of the repository's manifest and templates, which
+// the test may change. The test runs from the copy.
+func copyService(t *testing.T) *GeneratorService {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, dir := filepath.Join(wd, "..", ".."), t.TempDir()
+	err = filepath.WalkDir(filepath.Join(root, constants.TemplateDir), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		return copyFile(p, filepath.Join(dir, rel))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFile(filepath.Join(root, constants.DefaultManifestPath), filepath.Join(dir, constants.DefaultManifestPath)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	s, err := NewGeneratorService(constants.DefaultManifestPath)
+	if err != nil {
+		t.Fatalf("NewGeneratorService() error = %v", err)
+	}
+	return s
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), constants.DirPerm); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, constants.FilePerm)
+}
+
+// editManifest rewrites the manifest through fn and moves its modification time on, so
+// the change shows in the stamp however coarse the file system's clock is
+func editManifest(t *testing.T, fn func(m map[string]interface{})) {
+	t.Helper()
+	data, err := os.ReadFile(constants.DefaultManifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	fn(m)
+	if data, err = json.Marshal(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(constants.DefaultManifestPath, data, constants.FilePerm); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(constants.DefaultManifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(constants.DefaultManifestPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setDisplayName(name string) func(m map[string]interface{}) {
+	return func(m map[string]interface{}) {
+		m["frameworks"].(map[string]interface{})["gin"].(map[string]interface{})["display_name"] = name
+	}
+}
+
+func TestReload(t *testing.T) {
+	s := copyService(t)
+	started := s.pin() // a generation that started before the reload
+	original := started.manifest.Frameworks["gin"].DisplayName
+
+	if result, err := s.ReloadIfChanged(); err != nil || result.Reloaded {
+		t.Fatalf("ReloadIfChanged() = %+v, %v; want no reload", result, err)
+	}
+
+	editManifest(t, setDisplayName("Gin (reloaded)"))
+	result, err := s.ReloadIfChanged()
+	if err != nil {
+		t.Fatalf("ReloadIfChanged() error = %v", err)
+	}
+	if !result.Reloaded || result.Frameworks == 0 || result.Templates == 0 {
+		t.Errorf("ReloadIfChanged() = %+v, want a reload", result)
+	}
+	if got := s.GetManifest().Frameworks["gin"].DisplayName; got != "Gin (reloaded)" {
+		t.Errorf("display name after reload = %q", got)
+	}
+	if got := started.manifest.Frameworks["gin"].DisplayName; got != original {
+		t.Errorf("display name of the started generation = %q, want %q", got, original)
+	}
+
+	// A manifest that fails validation is not swapped in, nor tried again until it changes
+	editManifest(t, func(m map[string]interface{}) { m["version"] = "" })
+	_, err = s.ReloadIfChanged()
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrCodeConfig {
+		t.Fatalf("ReloadIfChanged() error = %v, want a config error", err)
+	}
+	if result, err := s.ReloadIfChanged(); err != nil || result.Reloaded {
+		t.Errorf("ReloadIfChanged() on the same files = %+v, %v; want no reload", result, err)
+	}
+	if got := s.GetManifest().Frameworks["gin"].DisplayName; got != "Gin (reloaded)" {
+		t.Errorf("display name after a failed reload = %q", got)
+	}
+
+	// So is a template set missing a template the manifest needs
+	editManifest(t, setDisplayName("Gin"))
+	if err := os.Remove(constants.TemplateMain); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Reload(); err == nil {
+		t.Errorf("Reload() without %s succeeded", constants.TemplateMain)
+	}
+	if _, err := s.GenerateProject(context.Background(), benchmarkRequest()); err != nil {
+		t.Errorf("GenerateProject() after a failed reload error = %v", err)
+	}
+}
+
+func TestReload_ImportVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		section string
+		def     string
+	}{
+		{"framework", "frameworks", "gin"},
+		{"library", "libs", "redis"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := copyService(t)
+			editManifest(t, func(m map[string]interface{}) {
+				def := m[tt.section].(map[string]interface{})[tt.def].(map[string]interface{})
+				def["imports"].([]interface{})[0].(map[string]interface{})["version"] = "latest"
+			})
+			_, err := s.Reload()
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrCodeConfig ||
+				!strings.Contains(err.Error(), "not a semantic version") {
+				t.Errorf("Reload() error = %v, want a config error on the version", err)
+			}
+		})
+	}
+}
+
+// writeConfigSection rewrites a config section file and moves its modification time on
+func writeConfigSection(t *testing.T, path, content string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), constants.FilePerm); err != nil {
+		t.Fatal(err)
+	}
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload_ConfigSections(t *testing.T) {
+	s := copyService(t)
+	path := s.manifest.Libs["redis"].ConfigSection
+	redisAddr := func(s *GeneratorService) interface{} {
+		t.Helper()
+		layers, err := s.configLayers(&GenerateRequest{Framework: "gin", Libs: []string{"redis"}})
+		if err != nil {
+			t.Fatalf("configLayers() error = %v", err)
+		}
+		merged, err := mergeConfigLayers(layers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return merged["redis"].(map[string]interface{})["addr"]
+	}
+
+	// Sections are read when the snapshot loads, not by every generation
+	started := s.pin()
+	writeConfigSection(t, path, `{"redis": {"addr": "cache:6379"}}`)
+	if got := redisAddr(s.pin()); got != "localhost:6379" {
+		t.Errorf("redis.addr before the reload = %v, want the loaded localhost:6379", got)
+	}
+	if result, err := s.ReloadIfChanged(); err != nil || !result.Reloaded {
+		t.Fatalf("ReloadIfChanged() after a section change = %+v, %v; want a reload", result, err)
+	}
+	if got := redisAddr(s.pin()); got != "cache:6379" {
+		t.Errorf("redis.addr after the reload = %v, want cache:6379", got)
+	}
+	if got := redisAddr(started); got != "localhost:6379" {
+		t.Errorf("redis.addr of the started generation = %v, want localhost:6379", got)
+	}
+
+	// A section that does not parse fails the reload, naming its lib
+	writeConfigSection(t, path, `{"redis": `)
+	_, err := s.ReloadIfChanged()
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrCodeConfig || appErr.Context["library"] != "redis" {
+		t.Fatalf("ReloadIfChanged() error = %v, want a config error on redis", err)
+	}
+	if got := redisAddr(s.pin()); got != "cache:6379" {
+		t.Errorf("redis.addr after a failed reload = %v, want cache:6379", got)
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	s := copyService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloads := make(chan *ReloadResult, 1)
+	err := s.WatchFiles(ctx, func(result *ReloadResult, err error) {
+		if err != nil {
+			t.Errorf("reload error = %v", err)
+			return
+		}
+		reloads <- result
+	})
+	if err != nil {
+		t.Skipf("WatchFiles() error = %v", err)
+	}
+
+	editManifest(t, setDisplayName("Gin (watched)"))
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after the manifest changed")
+	}
+	if got := s.GetManifest().Frameworks["gin"].DisplayName; got != "Gin (watched)" {
+		t.Errorf("display name after reload = %q", got)
+	}
+}
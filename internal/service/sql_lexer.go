@@ -0,0 +1,297 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sqlTokenKind classifies a SQL token
+type sqlTokenKind int
+
+const (
+	sqlWord   sqlTokenKind = iota // keyword or bare identifier
+	sqlIdent                      // quoted identifier, "name" or `name`
+	sqlString                     // string literal, 'text' or $tag$text$tag$
+	sqlNumber                     // numeric literal
+	sqlSymbol                     // punctuation and operators
+)
+
+// sqlToken is one token of a SQL script
+type sqlToken struct {
+	kind  sqlTokenKind
+	text  string // identifier or literal value without quotes, or the symbol itself
+	start int    // byte offset of the token in the script
+	end   int    // byte offset just past the token
+	line  int    // 1-based line of the token
+}
+
+// is reports whether the token is the given keyword (case-insensitive) or symbol
+func (t sqlToken) is(s string) bool {
+	switch t.kind {
+	case sqlWord:
+		return strings.EqualFold(t.text, s)
+	case sqlSymbol:
+		return t.text == s
+	}
+	return false
+}
+
+// lexSQL splits a SQL script into tokens, dropping whitespace and comments.
+// Backslash escapes in string literals are only honoured for MySQL.
+func lexSQL(src, dialect string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	line := 1
+	i := 0
+
+	// advance moves to offset end, counting the lines crossed
+	advance := func(end int) {
+		line += strings.Count(src[i:end], "\n")
+		i = end
+	}
+
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n' || c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			advance(i + 1)
+
+		case strings.HasPrefix(src[i:], "--") || (c == '#' && dialect == "mysql"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			advance(i + end)
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			advance(i + 2 + end + 2)
+
+		case c == '\'':
+			text, end, ok := scanQuoted(src, i, '\'', dialect == "mysql")
+			if !ok {
+				return nil, fmt.Errorf("line %d: unterminated string literal", line)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlString, text: text, start: i, end: end, line: line})
+			advance(end)
+
+		case c == '"' || c == '`':
+			text, end, ok := scanQuoted(src, i, c, false)
+			if !ok {
+				return nil, fmt.Errorf("line %d: unterminated quoted identifier", line)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlIdent, text: text, start: i, end: end, line: line})
+			advance(end)
+
+		case c == '$' && dialect != "mysql" && dollarTag(src[i:]) != "":
+			tag := dollarTag(src[i:])
+			end := strings.Index(src[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated dollar-quoted string", line)
+			}
+			bodyEnd := i + len(tag) + end
+			tokens = append(tokens, sqlToken{kind: sqlString, text: src[i+len(tag) : bodyEnd], start: i, end: bodyEnd + len(tag), line: line})
+			advance(bodyEnd + len(tag))
+
+		case isSQLWordStart(c):
+			end := i + 1
+			for end < len(src) && (isSQLWordStart(src[end]) || isDigit(src[end]) || src[end] == '$') {
+				end++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlWord, text: src[i:end], start: i, end: end, line: line})
+			advance(end)
+
+		case isDigit(c):
+			end := i + 1
+			for end < len(src) && (isDigit(src[end]) || src[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlNumber, text: src[i:end], start: i, end: end, line: line})
+			advance(end)
+
+		default:
+			end := i + 1
+			if strings.HasPrefix(src[i:], "::") {
+				end++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlSymbol, text: src[i:end], start: i, end: end, line: line})
+			advance(end)
+		}
+	}
+	return tokens, nil
+}
+
+// scanQuoted reads a literal delimited by quote starting at src[start]; a doubled quote is an
+// escaped quote. It returns the unquoted text and the offset just past the closing quote.
+func scanQuoted(src string, start int, quote byte, backslash bool) (string, int, bool) {
+	var b strings.Builder
+	for i := start + 1; i < len(src); i++ {
+		switch {
+		case backslash && src[i] == '\\' && i+1 < len(src):
+			i++
+			b.WriteByte(src[i])
+		case src[i] == quote && i+1 < len(src) && src[i+1] == quote:
+			i++
+			b.WriteByte(quote)
+		case src[i] == quote:
+			return b.String(), i + 1, true
+		default:
+			b.WriteByte(src[i])
+		}
+	}
+	return "", 0, false
+}
+
+// dollarTag returns the opening tag of a PostgreSQL dollar-quoted string ($$ or $name$), or ""
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1]
+		case isSQLWordStart(s[i]) || (i > 1 && isDigit(s[i])):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func isSQLWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// splitSQLStatements groups tokens into statements separated by semicolons
+func splitSQLStatements(tokens []sqlToken) [][]sqlToken {
+	var statements [][]sqlToken
+	begin := 0
+	for i, t := range tokens {
+		if t.is(";") {
+			if i > begin {
+				statements = append(statements, tokens[begin:i])
+			}
+			begin = i + 1
+		}
+	}
+	if begin < len(tokens) {
+		statements = append(statements, tokens[begin:])
+	}
+	return statements
+}
+
+// sqlCursor walks the tokens of one statement
+type sqlCursor struct {
+	tokens []sqlToken
+	pos    int
+}
+
+// done reports whether every token has been consumed
+func (c *sqlCursor) done() bool {
+	return c.pos >= len(c.tokens)
+}
+
+// peek returns the current token, or a zero token at the end of the statement
+func (c *sqlCursor) peek() sqlToken {
+	if c.done() {
+		return sqlToken{kind: sqlSymbol}
+	}
+	return c.tokens[c.pos]
+}
+
+// next consumes and returns the current token
+func (c *sqlCursor) next() sqlToken {
+	t := c.peek()
+	if !c.done() {
+		c.pos++
+	}
+	return t
+}
+
+// line returns the line of the current token, or of the last one at the end of the statement
+func (c *sqlCursor) line() int {
+	if c.done() {
+		if len(c.tokens) == 0 {
+			return 0
+		}
+		return c.tokens[len(c.tokens)-1].line
+	}
+	return c.tokens[c.pos].line
+}
+
+// at reports whether the next tokens are the given keywords or symbols
+func (c *sqlCursor) at(words ...string) bool {
+	for i, w := range words {
+		if c.pos+i >= len(c.tokens) || !c.tokens[c.pos+i].is(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// accept consumes the given keywords or symbols if they are next
+func (c *sqlCursor) accept(words ...string) bool {
+	if !c.at(words...) {
+		return false
+	}
+	c.pos += len(words)
+	return true
+}
+
+// name consumes a possibly schema-qualified name and returns its last part
+func (c *sqlCursor) name() (string, bool) {
+	t := c.peek()
+	if t.kind != sqlWord && t.kind != sqlIdent {
+		return "", false
+	}
+	c.next()
+	for c.at(".") && c.pos+1 < len(c.tokens) && (c.tokens[c.pos+1].kind == sqlWord || c.tokens[c.pos+1].kind == sqlIdent) {
+		c.next()
+		t = c.next()
+	}
+	return t.text, true
+}
+
+// group consumes a parenthesized group and returns its top-level comma-separated items
+func (c *sqlCursor) group() ([][]sqlToken, bool) {
+	if !c.at("(") {
+		return nil, false
+	}
+	c.next()
+	var items [][]sqlToken
+	begin := c.pos
+	depth := 0
+	for !c.done() {
+		t := c.next()
+		switch {
+		case t.is("("):
+			depth++
+		case t.is(")") && depth > 0:
+			depth--
+		case t.is(")"):
+			items = append(items, c.tokens[begin:c.pos-1])
+			return items, true
+		case t.is(",") && depth == 0:
+			items = append(items, c.tokens[begin:c.pos-1])
+			begin = c.pos
+		}
+	}
+	return nil, false
+}
+
+// skipGroup consumes a parenthesized group if one is next
+func (c *sqlCursor) skipGroup() {
+	c.group()
+}
+
+// text returns the source text of tokens[from:to] of the statement
+func (c *sqlCursor) text(src string, from, to int) string {
+	if from >= to {
+		return ""
+	}
+	return src[c.tokens[from].start:c.tokens[to-1].end]
+}
@@ -0,0 +1,949 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+// sqlTable is a table collected from CREATE TABLE, CREATE INDEX and ALTER TABLE statements
+type sqlTable struct {
+	name        string
+	line        int
+	columns     []*sqlColumn
+	primaryKey  []string
+	indexes     []sqlIndex
+	foreignKeys []sqlForeignKey
+}
+
+// sqlColumn is a column definition mapped to an entity field type
+type sqlColumn struct {
+	name          string
+	line          int
+	fieldType     string   // entity field type, "" when the column is skipped
+	values        []string // enum values
+	sqlType       string   // column type as written, e.g. "varchar(100)"
+	length        int      // declared length of character types
+	notNull       bool
+	defaultExpr   string
+	autoIncrement bool
+}
+
+// sqlIndex is a unique or regular index over plain columns
+type sqlIndex struct {
+	name    string
+	columns []string
+	unique  bool
+	line    int
+}
+
+// sqlForeignKey is a foreign key constraint
+type sqlForeignKey struct {
+	columns    []string
+	refTable   string
+	refColumns []string
+	onDelete   string
+	onUpdate   string
+	line       int
+}
+
+// sqlSchema collects the tables of a DDL script and the warnings raised while reading it
+type sqlSchema struct {
+	src      string
+	dialect  string
+	tables   []*sqlTable
+	enums    map[string][]string // PostgreSQL CREATE TYPE ... AS ENUM
+	warnings []models.Warning
+}
+
+// ignoredSQLStatements are skipped without a warning: they set up sessions, move data or manage
+// permissions and sequences, none of which changes the generated code
+var ignoredSQLStatements = map[string]bool{
+	"SET": true, "BEGIN": true, "COMMIT": true, "ROLLBACK": true, "START": true, "END": true,
+	"USE": true, "SELECT": true, "INSERT": true, "LOCK": true, "UNLOCK": true, "DROP": true,
+	"GRANT": true, "REVOKE": true, "COMMENT": true,
+}
+
+// sqlTypeWords continue a multi-word column type, e.g. "double precision" or "timestamp with time zone"
+var sqlTypeWords = map[string]bool{
+	"varying": true, "precision": true, "with": true, "without": true, "time": true, "zone": true,
+	"unsigned": true, "signed": true, "zerofill": true,
+}
+
+// sqlFieldTypes maps column types to entity field types
+var sqlFieldTypes = map[string]string{
+	"smallint": constants.FieldTypeInt, "int2": constants.FieldTypeInt, "integer": constants.FieldTypeInt,
+	"int": constants.FieldTypeInt, "int4": constants.FieldTypeInt, "bigint": constants.FieldTypeInt,
+	"int8": constants.FieldTypeInt, "mediumint": constants.FieldTypeInt, "tinyint": constants.FieldTypeInt,
+	"serial": constants.FieldTypeInt, "serial4": constants.FieldTypeInt, "bigserial": constants.FieldTypeInt,
+	"serial8": constants.FieldTypeInt, "smallserial": constants.FieldTypeInt, "serial2": constants.FieldTypeInt,
+
+	"boolean": constants.FieldTypeBool, "bool": constants.FieldTypeBool,
+
+	"varchar": constants.FieldTypeString, "character varying": constants.FieldTypeString,
+	"char": constants.FieldTypeString, "character": constants.FieldTypeString, "bpchar": constants.FieldTypeString,
+	"nchar": constants.FieldTypeString, "nvarchar": constants.FieldTypeString, "text": constants.FieldTypeString,
+	"tinytext": constants.FieldTypeString, "mediumtext": constants.FieldTypeString,
+	"longtext": constants.FieldTypeString, "citext": constants.FieldTypeString,
+	"json": constants.FieldTypeString, "jsonb": constants.FieldTypeString,
+	"time": constants.FieldTypeString, "time without time zone": constants.FieldTypeString,
+	"time with time zone": constants.FieldTypeString, "timetz": constants.FieldTypeString,
+
+	"uuid": constants.FieldTypeUUID,
+
+	"numeric": constants.FieldTypeDecimal, "decimal": constants.FieldTypeDecimal, "dec": constants.FieldTypeDecimal,
+
+	"real": constants.FieldTypeFloat, "float4": constants.FieldTypeFloat, "float8": constants.FieldTypeFloat,
+	"double precision": constants.FieldTypeFloat, "double": constants.FieldTypeFloat, "float": constants.FieldTypeFloat,
+
+	"timestamp": constants.FieldTypeTime, "timestamptz": constants.FieldTypeTime,
+	"timestamp without time zone": constants.FieldTypeTime, "timestamp with time zone": constants.FieldTypeTime,
+	"datetime": constants.FieldTypeTime, "date": constants.FieldTypeTime,
+}
+
+// serialTypes are PostgreSQL integer types backed by a sequence
+var serialTypes = map[string]bool{
+	"serial": true, "serial4": true, "bigserial": true, "serial8": true, "smallserial": true, "serial2": true,
+}
+
+// sqlTypeCast matches a trailing PostgreSQL cast such as ::character varying
+var sqlTypeCast = regexp.MustCompile(`::[A-Za-z][A-Za-z0-9_ ]*(\[\])?$`)
+
+// parseSQLSchema reads CREATE TABLE, CREATE INDEX, CREATE TYPE ... AS ENUM and ALTER TABLE
+// statements and converts the tables into entities. Everything it cannot represent is
+// reported as a warning; only malformed input is an error.
+func parseSQLSchema(src, dialect string) ([]models.EntityDef, []models.Warning, error) {
+	tokens, err := lexSQL(src, dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schema := &sqlSchema{src: src, dialect: dialect, enums: make(map[string][]string)}
+	for _, statement := range splitSQLStatements(tokens) {
+		if err := schema.statement(&sqlCursor{tokens: statement}); err != nil {
+			return nil, nil, err
+		}
+	}
+	return schema.entities(), schema.warnings, nil
+}
+
+// importSQL returns a copy of the request whose entities are read from its SQL schema,
+// together with the warnings raised by the conversion. Requests without SQL are returned as is.
+func (s *GeneratorService) importSQL(req *GenerateRequest) (*GenerateRequest, []models.Warning, error) {
+	if strings.TrimSpace(req.SQL) == "" {
+		return req, nil, nil
+	}
+
+	entities, warnings, err := parseSQLSchema(req.SQL, req.SQLDialect())
+	if err != nil {
+		return nil, nil, errors.ErrValidation(fmt.Sprintf("invalid sql: %v", err), nil)
+	}
+	if len(entities) == 0 {
+		var reasons []string
+		for _, w := range warnings {
+			if w.Code == constants.WarnSkippedTable {
+				reasons = append(reasons, w.String())
+			}
+		}
+		message := "invalid sql: no table could be converted into an entity"
+		if len(reasons) > 0 {
+			message += " (" + strings.Join(reasons, "; ") + ")"
+		}
+		return nil, nil, errors.ErrValidation(message, nil)
+	}
+
+	imported := *req
+	imported.SQL = ""
+	imported.Entities = entities
+	if err := imported.Validate(); err != nil {
+		return nil, nil, errors.ErrValidation(fmt.Sprintf("invalid sql schema: %v", err), nil)
+	}
+	return &imported, warnings, nil
+}
+
+// warn records a warning
+func (s *sqlSchema) warn(code string, line int, table, column, format string, args ...interface{}) {
+	s.warnings = append(s.warnings, models.Warning{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Line:    line,
+		Table:   table,
+		Column:  column,
+	})
+}
+
+// table returns the collected table with the given name
+func (s *sqlSchema) table(name string) *sqlTable {
+	for _, t := range s.tables {
+		if strings.EqualFold(t.name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// statement dispatches one statement
+func (s *sqlSchema) statement(c *sqlCursor) error {
+	line := c.line()
+	first := strings.ToUpper(c.peek().text)
+
+	switch {
+	case c.accept("CREATE"):
+		c.accept("OR", "REPLACE")
+		switch {
+		case c.at("TABLE"), c.at("TEMPORARY"), c.at("TEMP"), c.at("UNLOGGED"), c.at("GLOBAL"), c.at("LOCAL"):
+			return s.createTable(c, line)
+		case c.at("UNIQUE"), c.at("INDEX"):
+			return s.createIndex(c, line)
+		case c.at("TYPE"):
+			return s.createType(c, line)
+		case c.at("SCHEMA"), c.at("SEQUENCE"):
+			return nil
+		}
+		s.warn(constants.WarnUnsupportedStatement, line, "", "", "CREATE %s statement ignored", strings.ToUpper(c.peek().text))
+	case c.accept("ALTER", "TABLE"):
+		return s.alterTable(c, line)
+	case c.at("ALTER", "SEQUENCE"), ignoredSQLStatements[first]:
+	default:
+		s.warn(constants.WarnUnsupportedStatement, line, "", "", "%s statement ignored", first)
+	}
+	return nil
+}
+
+// createTable reads CREATE TABLE name (columns and constraints) [options]
+func (s *sqlSchema) createTable(c *sqlCursor, line int) error {
+	temporary := false
+	for _, word := range []string{"GLOBAL", "LOCAL", "TEMPORARY", "TEMP", "UNLOGGED"} {
+		if c.accept(word) && word != "UNLOGGED" {
+			temporary = true
+		}
+	}
+	if !c.accept("TABLE") {
+		return fmt.Errorf("line %d: expected TABLE", line)
+	}
+	c.accept("IF", "NOT", "EXISTS")
+	name, ok := c.name()
+	if !ok {
+		return fmt.Errorf("line %d: expected a table name", line)
+	}
+	if temporary {
+		s.warn(constants.WarnSkippedTable, line, name, "", "temporary table skipped")
+		return nil
+	}
+	if s.table(name) != nil {
+		s.warn(constants.WarnSkippedTable, line, name, "", "table is defined twice, the second definition is skipped")
+		return nil
+	}
+
+	items, ok := c.group()
+	if !ok {
+		s.warn(constants.WarnSkippedTable, line, name, "", "only CREATE TABLE with a column list is supported (not AS, LIKE or PARTITION OF), table skipped")
+		return nil
+	}
+
+	table := &sqlTable{name: name, line: line}
+	for _, item := range items {
+		if len(item) == 0 {
+			return fmt.Errorf("line %d: empty element in table %s", line, name)
+		}
+		ic := &sqlCursor{tokens: item}
+		if isTableConstraint(ic) {
+			s.tableConstraint(table, ic)
+			continue
+		}
+		if err := s.column(table, ic); err != nil {
+			return err
+		}
+	}
+
+	// Table options: storage and charset options don't change the code, partitioning and inheritance do
+	for !c.done() {
+		t := c.next()
+		if t.is("PARTITION") || t.is("INHERITS") {
+			s.warn(constants.WarnUnsupportedConstraint, t.line, name, "", "%s clause ignored", strings.ToUpper(t.text))
+		}
+	}
+
+	s.tables = append(s.tables, table)
+	return nil
+}
+
+// isTableConstraint reports whether a table element is a constraint or index rather than a column
+func isTableConstraint(c *sqlCursor) bool {
+	t := c.peek()
+	if t.kind != sqlWord {
+		return false
+	}
+	switch strings.ToUpper(t.text) {
+	case "CONSTRAINT", "PRIMARY", "FOREIGN", "CHECK", "EXCLUDE", "FULLTEXT", "SPATIAL":
+		return true
+	case "UNIQUE", "KEY", "INDEX":
+		// A column named like a keyword is followed by its type, a constraint by a name or a column list
+		return len(c.tokens) < 2 || c.tokens[1].is("(") || c.tokens[1].is("KEY") || c.tokens[1].is("INDEX") ||
+			len(c.tokens) > 2 && c.tokens[2].is("(")
+	}
+	return false
+}
+
+// tableConstraint reads a table-level constraint or MySQL inline index
+func (s *sqlSchema) tableConstraint(table *sqlTable, c *sqlCursor) {
+	line := c.line()
+	name := ""
+	if c.accept("CONSTRAINT") {
+		name, _ = c.name()
+	}
+
+	switch {
+	case c.accept("PRIMARY", "KEY"):
+		columns, ok := s.columnList(c, table.name, line)
+		if ok {
+			table.primaryKey = columns
+		}
+	case c.at("UNIQUE"), c.at("KEY"), c.at("INDEX"):
+		unique := c.accept("UNIQUE")
+		if !c.accept("KEY") {
+			c.accept("INDEX")
+		}
+		if !c.at("(") {
+			name, _ = c.name()
+		}
+		if columns, ok := s.columnList(c, table.name, line); ok {
+			table.indexes = append(table.indexes, sqlIndex{name: name, columns: columns, unique: unique, line: line})
+		}
+	case c.accept("FOREIGN", "KEY"):
+		columns, ok := s.columnList(c, table.name, line)
+		if !ok {
+			return
+		}
+		if !c.accept("REFERENCES") {
+			s.warn(constants.WarnUnsupportedConstraint, line, table.name, "", "malformed FOREIGN KEY constraint ignored")
+			return
+		}
+		fk := s.references(c, table.name, line)
+		fk.columns = columns
+		table.foreignKeys = append(table.foreignKeys, fk)
+	default:
+		s.warn(constants.WarnUnsupportedConstraint, line, table.name, "", "%s constraint ignored", strings.ToUpper(c.peek().text))
+	}
+}
+
+// columnList reads (a, b, ...) of plain column names; expressions and prefix lengths are rejected
+func (s *sqlSchema) columnList(c *sqlCursor, table string, line int) ([]string, bool) {
+	items, ok := c.group()
+	if !ok {
+		s.warn(constants.WarnUnsupportedConstraint, line, table, "", "constraint without a column list ignored")
+		return nil, false
+	}
+	var columns []string
+	for _, item := range items {
+		ic := &sqlCursor{tokens: item}
+		name, ok := ic.name()
+		// Sort order and NULLS FIRST/LAST don't change which columns are covered
+		for ok && !ic.done() && (ic.accept("ASC") || ic.accept("DESC") || ic.accept("NULLS", "FIRST") || ic.accept("NULLS", "LAST")) {
+		}
+		if !ok || !ic.done() {
+			s.warn(constants.WarnUnsupportedConstraint, line, table, "", "index or constraint on an expression or column prefix ignored")
+			return nil, false
+		}
+		columns = append(columns, name)
+	}
+	return columns, true
+}
+
+// references reads REFERENCES table [(columns)] [ON DELETE action] [ON UPDATE action] and deferrability
+func (s *sqlSchema) references(c *sqlCursor, table string, line int) sqlForeignKey {
+	fk := sqlForeignKey{line: line}
+	fk.refTable, _ = c.name()
+	if c.at("(") {
+		fk.refColumns, _ = s.columnList(c, table, line)
+	}
+	for !c.done() {
+		switch {
+		case c.accept("ON", "DELETE"):
+			fk.onDelete = referentialAction(c)
+		case c.accept("ON", "UPDATE"):
+			fk.onUpdate = referentialAction(c)
+		case c.accept("MATCH"), c.accept("NOT", "DEFERRABLE"), c.accept("DEFERRABLE"), c.accept("INITIALLY"):
+			if c.peek().kind == sqlWord && !c.at("ON") && !c.at("NOT") && !c.at("DEFERRABLE") && !c.at("INITIALLY") {
+				c.next()
+			}
+		default:
+			return fk
+		}
+	}
+	return fk
+}
+
+// referentialAction reads CASCADE, SET NULL, SET DEFAULT, RESTRICT or NO ACTION
+func referentialAction(c *sqlCursor) string {
+	switch {
+	case c.accept("SET", "NULL"):
+		return "SET NULL"
+	case c.accept("SET", "DEFAULT"):
+		return "SET DEFAULT"
+	case c.accept("NO", "ACTION"):
+		return "NO ACTION"
+	case c.accept("CASCADE"):
+		return "CASCADE"
+	case c.accept("RESTRICT"):
+		return "RESTRICT"
+	}
+	c.next()
+	return ""
+}
+
+// column reads a column definition with its inline constraints
+func (s *sqlSchema) column(table *sqlTable, c *sqlCursor) error {
+	line := c.line()
+	nameTok := c.next()
+	if nameTok.kind != sqlWord && nameTok.kind != sqlIdent {
+		return fmt.Errorf("line %d: expected a column name in table %s", line, table.name)
+	}
+	col := &sqlColumn{name: nameTok.text, line: line}
+	table.columns = append(table.columns, col)
+
+	if err := s.columnType(table, col, c); err != nil {
+		return err
+	}
+
+	unknown := false
+	for !c.done() {
+		switch {
+		case c.accept("CONSTRAINT"):
+			c.name()
+		case c.accept("NOT", "NULL"):
+			col.notNull = true
+		case c.accept("NULL"):
+		case c.accept("PRIMARY", "KEY"):
+			table.primaryKey = []string{col.name}
+			c.accept("ASC")
+			c.accept("DESC")
+		case c.accept("UNIQUE"):
+			c.accept("KEY")
+			table.indexes = append(table.indexes, sqlIndex{columns: []string{col.name}, unique: true, line: line})
+		case c.accept("DEFAULT"):
+			s.columnDefault(col, c)
+		case c.accept("REFERENCES"):
+			fk := s.references(c, table.name, line)
+			fk.columns = []string{col.name}
+			table.foreignKeys = append(table.foreignKeys, fk)
+		case c.accept("AUTO_INCREMENT"), c.accept("AUTOINCREMENT"):
+			col.autoIncrement = true
+		case c.accept("GENERATED"):
+			c.accept("ALWAYS")
+			c.accept("BY", "DEFAULT")
+			if c.accept("AS", "IDENTITY") {
+				col.autoIncrement = true
+				c.skipGroup()
+				continue
+			}
+			s.warn(constants.WarnSkippedColumn, line, table.name, col.name, "generated columns are not supported, column skipped")
+			col.fieldType = ""
+			return nil
+		case c.at("AS", "("):
+			s.warn(constants.WarnSkippedColumn, line, table.name, col.name, "generated columns are not supported, column skipped")
+			col.fieldType = ""
+			return nil
+		case c.accept("CHECK"):
+			c.skipGroup()
+			s.warn(constants.WarnUnsupportedConstraint, line, table.name, col.name, "CHECK constraint ignored")
+		case c.accept("COLLATE"), c.accept("CHARACTER", "SET"), c.accept("CHARSET"), c.accept("COMMENT"):
+			c.next()
+		case c.accept("ON", "UPDATE"):
+			t := c.next()
+			c.skipGroup()
+			s.warn(constants.WarnUnsupportedConstraint, line, table.name, col.name, "ON UPDATE %s is not generated", strings.ToUpper(t.text))
+		default:
+			// Report an unknown clause once, then skip its tokens until a known clause follows
+			t := c.next()
+			if !unknown {
+				s.warn(constants.WarnUnsupportedConstraint, t.line, table.name, col.name, "column clause %s ignored", strings.ToUpper(t.text))
+			}
+			unknown = true
+			continue
+		}
+		unknown = false
+	}
+	return nil
+}
+
+// columnType reads the column type and maps it to an entity field type.
+// Unsupported types leave fieldType empty and record a warning.
+func (s *sqlSchema) columnType(table *sqlTable, col *sqlColumn, c *sqlCursor) error {
+	begin := c.pos
+	if _, ok := c.name(); !ok {
+		return fmt.Errorf("line %d: expected a type for column %s.%s", col.line, table.name, col.name)
+	}
+	typeName := strings.ToLower(c.tokens[c.pos-1].text)
+
+	var args []sqlToken
+	array := false
+	for !c.done() {
+		t := c.peek()
+		switch {
+		case t.is("("):
+			items, ok := c.group()
+			if !ok {
+				return fmt.Errorf("line %d: unterminated type arguments for column %s.%s", col.line, table.name, col.name)
+			}
+			for _, item := range items {
+				if len(item) == 1 {
+					args = append(args, item[0])
+				}
+			}
+		case t.is("["):
+			array = true
+			for !c.done() && !c.next().is("]") {
+			}
+		case t.kind == sqlWord && sqlTypeWords[strings.ToLower(t.text)]:
+			// "time" only continues "with time zone"; a bare TIME after a type would be a new clause
+			if strings.EqualFold(t.text, "time") && !c.tokens[c.pos-1].is("with") && !c.tokens[c.pos-1].is("without") {
+				return s.mapColumnType(table, col, typeName, c.text(s.src, begin, c.pos), args, array)
+			}
+			c.next()
+			if !strings.EqualFold(t.text, "unsigned") && !strings.EqualFold(t.text, "signed") && !strings.EqualFold(t.text, "zerofill") {
+				typeName += " " + strings.ToLower(t.text)
+			}
+		default:
+			return s.mapColumnType(table, col, typeName, c.text(s.src, begin, c.pos), args, array)
+		}
+	}
+	return s.mapColumnType(table, col, typeName, c.text(s.src, begin, c.pos), args, array)
+}
+
+// mapColumnType fills the field type, enum values and length of a column
+func (s *sqlSchema) mapColumnType(table *sqlTable, col *sqlColumn, typeName, typeText string, args []sqlToken, array bool) error {
+	col.sqlType = strings.ToLower(strings.Join(strings.Fields(typeText), " "))
+
+	if array {
+		s.warn(constants.WarnUnsupportedType, col.line, table.name, col.name, "array type %s is not supported, column skipped", col.sqlType)
+		return nil
+	}
+
+	var values []string
+	for _, a := range args {
+		if a.kind == sqlString {
+			values = append(values, a.text)
+		}
+	}
+
+	switch {
+	case typeName == "enum" && s.dialect == "mysql":
+		col.fieldType = constants.FieldTypeEnum
+		col.values = values
+	case s.enums[typeName] != nil:
+		col.fieldType = constants.FieldTypeEnum
+		col.values = s.enums[typeName]
+	case typeName == "tinyint" && len(args) == 1 && args[0].text == "1":
+		col.fieldType = constants.FieldTypeBool
+	case sqlFieldTypes[typeName] != "":
+		col.fieldType = sqlFieldTypes[typeName]
+		col.autoIncrement = serialTypes[typeName]
+		if col.fieldType == constants.FieldTypeString && len(args) == 1 && args[0].kind == sqlNumber {
+			col.length, _ = strconv.Atoi(args[0].text)
+		}
+	default:
+		s.warn(constants.WarnUnsupportedType, col.line, table.name, col.name, "column type %s is not supported, column skipped", col.sqlType)
+		return nil
+	}
+
+	// Enum values become Go constants; values that can't be fall back to a plain string
+	if col.fieldType == constants.FieldTypeEnum && !validEnumValues(col.values) {
+		s.warn(constants.WarnUnsupportedType, col.line, table.name, col.name, "enum values cannot be used as Go constants, mapped to string")
+		col.fieldType = constants.FieldTypeString
+		col.values = nil
+	}
+	return nil
+}
+
+// validEnumValues reports whether enum values are usable as field values
+func validEnumValues(values []string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	pattern := regexp.MustCompile(constants.EnumValuePattern)
+	seen := make(map[string]bool)
+	for _, v := range values {
+		key := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(v))
+		if !pattern.MatchString(v) || seen[key] {
+			return false
+		}
+		seen[key] = true
+	}
+	return true
+}
+
+// columnDefault reads the DEFAULT expression of a column
+func (s *sqlSchema) columnDefault(col *sqlColumn, c *sqlCursor) {
+	begin := c.pos
+	for !c.done() {
+		t := c.peek()
+		if t.is("(") {
+			c.skipGroup()
+			continue
+		}
+		if c.pos > begin && t.kind == sqlWord && isColumnClause(t) {
+			break
+		}
+		c.next()
+	}
+
+	expr := sqlTypeCast.ReplaceAllString(c.text(s.src, begin, c.pos), "")
+	switch {
+	case strings.EqualFold(expr, "NULL"):
+	case strings.HasPrefix(strings.ToLower(expr), "nextval("):
+		col.autoIncrement = true
+	default:
+		col.defaultExpr = expr
+	}
+}
+
+// isColumnClause reports whether a keyword starts a new column clause after a DEFAULT expression
+func isColumnClause(t sqlToken) bool {
+	switch strings.ToUpper(t.text) {
+	case "NOT", "NULL", "PRIMARY", "UNIQUE", "REFERENCES", "CHECK", "CONSTRAINT", "COLLATE", "GENERATED",
+		"AUTO_INCREMENT", "COMMENT", "ON", "CHARACTER", "CHARSET":
+		return true
+	}
+	return false
+}
+
+// createIndex reads CREATE [UNIQUE] INDEX [CONCURRENTLY] [IF NOT EXISTS] [name] ON [ONLY] table [USING method] (columns)
+func (s *sqlSchema) createIndex(c *sqlCursor, line int) error {
+	unique := c.accept("UNIQUE")
+	if !c.accept("INDEX") {
+		return fmt.Errorf("line %d: expected INDEX", line)
+	}
+	c.accept("CONCURRENTLY")
+	c.accept("IF", "NOT", "EXISTS")
+	name := ""
+	if !c.at("ON") {
+		name, _ = c.name()
+	}
+	if !c.accept("ON") {
+		return fmt.Errorf("line %d: expected ON in CREATE INDEX", line)
+	}
+	c.accept("ONLY")
+	tableName, _ := c.name()
+	table := s.table(tableName)
+	if table == nil {
+		s.warn(constants.WarnUnsupportedConstraint, line, tableName, "", "index %s on an unknown table ignored", name)
+		return nil
+	}
+	if c.accept("USING") {
+		c.next()
+	}
+	columns, ok := s.columnList(c, table.name, line)
+	if !ok {
+		return nil
+	}
+	if !c.done() {
+		s.warn(constants.WarnUnsupportedConstraint, line, table.name, "", "partial or storage options of index %s ignored, the index is generated over all rows", name)
+	}
+	table.indexes = append(table.indexes, sqlIndex{name: name, columns: columns, unique: unique, line: line})
+	return nil
+}
+
+// createType reads CREATE TYPE name AS ENUM ('a', 'b')
+func (s *sqlSchema) createType(c *sqlCursor, line int) error {
+	c.accept("TYPE")
+	name, _ := c.name()
+	if !c.accept("AS", "ENUM") {
+		s.warn(constants.WarnUnsupportedStatement, line, "", "", "CREATE TYPE %s ignored, only enum types are supported", name)
+		return nil
+	}
+	items, ok := c.group()
+	if !ok {
+		return fmt.Errorf("line %d: expected enum values for type %s", line, name)
+	}
+	values := []string{}
+	for _, item := range items {
+		if len(item) == 1 && item[0].kind == sqlString {
+			values = append(values, item[0].text)
+		}
+	}
+	s.enums[strings.ToLower(name)] = values
+	return nil
+}
+
+// alterTable reads the ALTER TABLE actions found in schema dumps: added columns and
+// constraints, column defaults and identities. Ownership changes are ignored silently.
+func (s *sqlSchema) alterTable(c *sqlCursor, line int) error {
+	c.accept("IF", "EXISTS")
+	c.accept("ONLY")
+	name, ok := c.name()
+	if !ok {
+		return fmt.Errorf("line %d: expected a table name", line)
+	}
+	table := s.table(name)
+	if table == nil {
+		s.warn(constants.WarnUnsupportedStatement, line, name, "", "ALTER TABLE on an unknown table ignored")
+		return nil
+	}
+
+	for !c.done() {
+		var action []sqlToken
+		depth := 0
+		for !c.done() && !(depth == 0 && c.at(",")) {
+			t := c.next()
+			if t.is("(") {
+				depth++
+			} else if t.is(")") {
+				depth--
+			}
+			action = append(action, t)
+		}
+		c.accept(",")
+		if err := s.alterAction(table, &sqlCursor{tokens: action}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alterAction applies one ALTER TABLE action
+func (s *sqlSchema) alterAction(table *sqlTable, c *sqlCursor) error {
+	line := c.line()
+	switch {
+	case c.accept("ADD"):
+		if isTableConstraint(c) {
+			s.tableConstraint(table, c)
+			return nil
+		}
+		c.accept("COLUMN")
+		c.accept("IF", "NOT", "EXISTS")
+		return s.column(table, c)
+	case c.accept("ALTER"):
+		c.accept("COLUMN")
+		colName, _ := c.name()
+		col := table.column(colName)
+		switch {
+		case col == nil:
+			s.warn(constants.WarnUnsupportedStatement, line, table.name, colName, "ALTER COLUMN on an unknown column ignored")
+		case c.accept("SET", "DEFAULT"):
+			s.columnDefault(col, c)
+		case c.accept("ADD", "GENERATED"):
+			col.autoIncrement = true
+		case c.accept("SET", "NOT", "NULL"):
+			col.notNull = true
+		default:
+			s.warn(constants.WarnUnsupportedStatement, line, table.name, colName, "ALTER COLUMN action ignored")
+		}
+	case c.accept("OWNER", "TO"):
+	default:
+		s.warn(constants.WarnUnsupportedStatement, line, table.name, "", "ALTER TABLE %s action ignored", strings.ToUpper(c.peek().text))
+	}
+	return nil
+}
+
+// column returns the column with the given name
+func (t *sqlTable) column(name string) *sqlColumn {
+	for _, col := range t.columns {
+		if strings.EqualFold(col.name, name) {
+			return col
+		}
+	}
+	return nil
+}
+
+// entities converts the collected tables into entity definitions
+func (s *sqlSchema) entities() []models.EntityDef {
+	namePattern := regexp.MustCompile(constants.EntityNamePattern)
+
+	// Entity names first, so foreign keys can be resolved against the tables that are kept
+	names := make(map[*sqlTable]string)
+	seen := make(map[string]bool)
+	var tables []*sqlTable
+	for _, table := range s.tables {
+		name := entityNameForTable(table.name)
+		key := strings.ReplaceAll(name, "_", "")
+		switch {
+		case !namePattern.MatchString(table.name) || !namePattern.MatchString(name):
+			s.warn(constants.WarnSkippedTable, table.line, table.name, "", "table name cannot be used as an entity name, table skipped")
+		case key == "cache" || key == "error" || key == constants.APIModuleName:
+			s.warn(constants.WarnSkippedTable, table.line, table.name, "", "entity name %s is reserved, table skipped", name)
+		case seen[key]:
+			s.warn(constants.WarnSkippedTable, table.line, table.name, "", "entity name %s is already used by another table, table skipped", name)
+		case len(tables) == constants.MaxEntities:
+			s.warn(constants.WarnSkippedTable, table.line, table.name, "", "more than %d tables, table skipped", constants.MaxEntities)
+		default:
+			if reason := table.primaryKeyProblem(); reason != "" {
+				s.warn(constants.WarnSkippedTable, table.line, table.name, "", "%s, table skipped", reason)
+				continue
+			}
+			seen[key] = true
+			names[table] = name
+			tables = append(tables, table)
+		}
+	}
+
+	entities := make([]models.EntityDef, 0, len(tables))
+	for _, table := range tables {
+		entities = append(entities, s.entity(table, names))
+	}
+	return entities
+}
+
+// primaryKeyProblem explains why the primary key of the table cannot back an entity, or returns ""
+func (t *sqlTable) primaryKeyProblem() string {
+	switch len(t.primaryKey) {
+	case 0:
+		return "table has no primary key"
+	case 1:
+	default:
+		return fmt.Sprintf("composite primary key (%s) is not supported", strings.Join(t.primaryKey, ", "))
+	}
+	col := t.column(t.primaryKey[0])
+	switch {
+	case col == nil:
+		return fmt.Sprintf("primary key column %s is not defined", t.primaryKey[0])
+	case col.fieldType != constants.FieldTypeInt && col.fieldType != constants.FieldTypeUUID && col.fieldType != constants.FieldTypeString:
+		return fmt.Sprintf("primary key column %s must be an integer, uuid or character column", col.name)
+	}
+	return ""
+}
+
+// entity converts one table into an entity definition
+func (s *sqlSchema) entity(table *sqlTable, names map[*sqlTable]string) models.EntityDef {
+	namePattern := regexp.MustCompile(constants.EntityNamePattern)
+	entity := models.EntityDef{Name: names[table], Table: table.name}
+	fields := make(map[string]*models.FieldDef)
+
+	for _, col := range table.columns {
+		primary := strings.EqualFold(col.name, table.primaryKey[0])
+		switch {
+		case col.fieldType == "":
+			continue
+		case !namePattern.MatchString(col.name):
+			s.warn(constants.WarnSkippedColumn, col.line, table.name, col.name, "column name cannot be used as a field name, column skipped")
+			continue
+		case strings.EqualFold(col.name, "id") && !primary:
+			s.warn(constants.WarnSkippedColumn, col.line, table.name, col.name, "a column named id that is not the primary key is not supported, column skipped")
+			continue
+		case len(entity.Fields) == constants.MaxEntityFields:
+			s.warn(constants.WarnSkippedColumn, col.line, table.name, col.name, "more than %d columns, column skipped", constants.MaxEntityFields)
+			continue
+		}
+
+		field := models.FieldDef{
+			Name:          col.name,
+			Column:        col.name,
+			Type:          col.fieldType,
+			Values:        col.values,
+			SQLType:       col.sqlType,
+			Default:       col.defaultExpr,
+			PrimaryKey:    primary,
+			AutoIncrement: primary && col.autoIncrement && col.fieldType == constants.FieldTypeInt,
+		}
+		if !primary {
+			field.Required = col.notNull && col.defaultExpr == "" && !col.autoIncrement
+			field.Nullable = !col.notNull
+		}
+		if col.length > 0 {
+			field.Validate = fmt.Sprintf("max=%d", col.length)
+		}
+		if !regexp.MustCompile(constants.SQLTypePattern).MatchString(field.SQLType) {
+			field.SQLType = ""
+		}
+		if strings.ContainsAny(field.Default, "`\";\n") {
+			s.warn(constants.WarnUnsupportedConstraint, col.line, table.name, col.name, "DEFAULT %s cannot be written into a struct tag, ignored", field.Default)
+			field.Default = ""
+		}
+		entity.Fields = append(entity.Fields, field)
+	}
+
+	// Pointers into entity.Fields are only taken once the slice stops growing
+	for i := range entity.Fields {
+		fields[strings.ToLower(entity.Fields[i].Name)] = &entity.Fields[i]
+	}
+
+	for _, idx := range table.indexes {
+		s.index(&entity, table, idx, fields)
+	}
+	for _, fk := range table.foreignKeys {
+		s.foreignKey(table, fk, fields, names)
+	}
+	return entity
+}
+
+// index adds an index to the entity: single columns are flagged on the field, others declared as indexes
+func (s *sqlSchema) index(entity *models.EntityDef, table *sqlTable, idx sqlIndex, fields map[string]*models.FieldDef) {
+	for _, column := range idx.columns {
+		if fields[strings.ToLower(column)] == nil {
+			s.warn(constants.WarnUnsupportedConstraint, idx.line, table.name, column, "index %s covers a skipped or unknown column, ignored", idx.name)
+			return
+		}
+	}
+	if len(idx.columns) == 1 {
+		field := fields[strings.ToLower(idx.columns[0])]
+		if field.PrimaryKey {
+			return
+		}
+		if idx.unique {
+			field.Unique = true
+		} else {
+			field.Indexed = true
+		}
+		return
+	}
+
+	name := idx.name
+	if !regexp.MustCompile(constants.EntityNamePattern).MatchString(name) {
+		name = ""
+	}
+	def := models.IndexDef{Name: name, Unique: idx.unique}
+	for _, column := range idx.columns {
+		def.Fields = append(def.Fields, fields[strings.ToLower(column)].Name)
+	}
+	entity.Indexes = append(entity.Indexes, def)
+}
+
+// foreignKey turns a foreign key into a reference from the field to the entity of the referenced table
+func (s *sqlSchema) foreignKey(table *sqlTable, fk sqlForeignKey, fields map[string]*models.FieldDef, names map[*sqlTable]string) {
+	if len(fk.columns) != 1 {
+		s.warn(constants.WarnSkippedRelation, fk.line, table.name, "", "composite foreign key (%s) is not supported, kept as plain columns", strings.Join(fk.columns, ", "))
+		return
+	}
+	field := fields[strings.ToLower(fk.columns[0])]
+	if field == nil {
+		s.warn(constants.WarnSkippedRelation, fk.line, table.name, fk.columns[0], "foreign key on a skipped or unknown column ignored")
+		return
+	}
+
+	target := s.table(fk.refTable)
+	if target == nil || names[target] == "" {
+		s.warn(constants.WarnSkippedRelation, fk.line, table.name, field.Name, "references table %s which is not generated, kept as a plain column", fk.refTable)
+		return
+	}
+	if len(fk.refColumns) > 0 && (len(fk.refColumns) != 1 || !strings.EqualFold(fk.refColumns[0], target.primaryKey[0])) {
+		s.warn(constants.WarnSkippedRelation, fk.line, table.name, field.Name, "references %s(%s) which is not its primary key, kept as a plain column", fk.refTable, strings.Join(fk.refColumns, ", "))
+		return
+	}
+	if pk := target.column(target.primaryKey[0]); pk.fieldType != field.Type {
+		s.warn(constants.WarnSkippedRelation, fk.line, table.name, field.Name, "type %s does not match the primary key of %s, kept as a plain column", field.SQLType, fk.refTable)
+		return
+	}
+	if field.PrimaryKey {
+		s.warn(constants.WarnSkippedRelation, fk.line, table.name, field.Name, "foreign keys on the primary key are not supported, kept as a plain column")
+		return
+	}
+
+	field.References = names[target]
+	field.OnDelete = fk.onDelete
+	field.OnUpdate = fk.onUpdate
+}
+
+// entityNameForTable derives the entity name from a table name, e.g. "order_items" -> "order_item"
+func entityNameForTable(table string) string {
+	words := splitWords(table)
+	if len(words) == 0 {
+		return ""
+	}
+	words[len(words)-1] = singular(words[len(words)-1])
+	return strings.Join(words, "_")
+}
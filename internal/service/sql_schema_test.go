@@ -0,0 +1,230 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/models"
+)
+
+const postgresSchema = `
+-- customers and their orders
+CREATE TYPE order_status AS ENUM ('pending', 'paid', 'shipped');
+
+CREATE TABLE public.customers (
+    customer_id bigserial PRIMARY KEY,
+    email varchar(255) NOT NULL UNIQUE,
+    name text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE orders (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    customer_id bigint NOT NULL REFERENCES customers (customer_id) ON DELETE CASCADE,
+    status order_status NOT NULL DEFAULT 'pending'::order_status,
+    total numeric(12,2) NOT NULL,
+    tags text[],
+    note character varying(50),
+    CONSTRAINT orders_pkey PRIMARY KEY (id),
+    CONSTRAINT orders_total_check CHECK (total >= 0)
+);
+
+CREATE UNIQUE INDEX orders_customer_status_idx ON public.orders USING btree (customer_id, status);
+CREATE INDEX orders_created_idx ON orders (lower(note));
+
+CREATE TABLE order_lines (
+    order_id uuid NOT NULL,
+    line integer NOT NULL,
+    PRIMARY KEY (order_id, line)
+);
+
+CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 1000;
+`
+
+const mysqlSchema = "SET NAMES utf8mb4;\n" +
+	"DROP TABLE IF EXISTS `products`;\n" +
+	"CREATE TABLE `products` (\n" +
+	"  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,\n" +
+	"  `sku` char(36) NOT NULL,\n" +
+	"  `active` tinyint(1) NOT NULL DEFAULT '1',\n" +
+	"  `kind` enum('physical','digital') DEFAULT NULL,\n" +
+	"  `price` double NOT NULL,\n" +
+	"  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+	"  `picture` blob,\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  UNIQUE KEY `uk_sku` (`sku`),\n" +
+	"  KEY `idx_kind_price` (`kind`, `price`)\n" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4; # trailing comment\n"
+
+func TestParseSQLSchema_Postgres(t *testing.T) {
+	entities, warnings, err := parseSQLSchema(postgresSchema, "postgres")
+	if err != nil {
+		t.Fatalf("parseSQLSchema() error = %v", err)
+	}
+	if len(entities) != 2 {
+		t.Fatalf("got %d entities, want 2 (customer, order)", len(entities))
+	}
+
+	customer := entities[0]
+	if customer.Name != "customer" || customer.Table != "customers" {
+		t.Errorf("customer entity = %s (%s), want customer (customers)", customer.Name, customer.Table)
+	}
+	pk := findField(t, customer, "customer_id")
+	if !pk.PrimaryKey || !pk.AutoIncrement || pk.Type != constants.FieldTypeInt {
+		t.Errorf("customer_id = %+v, want an auto-increment int primary key", pk)
+	}
+	email := findField(t, customer, "email")
+	if !email.Required || !email.Unique || email.Validate != "max=255" {
+		t.Errorf("email = %+v, want required, unique, max=255", email)
+	}
+	if name := findField(t, customer, "name"); !name.Nullable || name.Required {
+		t.Errorf("name = %+v, want nullable", name)
+	}
+	if created := findField(t, customer, "created_at"); created.Required || created.Default != "now()" {
+		t.Errorf("created_at = %+v, want optional with default now()", created)
+	}
+
+	order := entities[1]
+	if id := findField(t, order, "id"); !id.PrimaryKey || id.Type != constants.FieldTypeUUID || id.Default != "gen_random_uuid()" {
+		t.Errorf("id = %+v, want a uuid primary key with a default", id)
+	}
+	fk := findField(t, order, "customer_id")
+	if fk.References != "customer" || fk.OnDelete != "CASCADE" {
+		t.Errorf("customer_id = %+v, want a reference to customer with ON DELETE CASCADE", fk)
+	}
+	status := findField(t, order, "status")
+	if status.Type != constants.FieldTypeEnum || strings.Join(status.Values, ",") != "pending,paid,shipped" || status.Default != "'pending'" {
+		t.Errorf("status = %+v, want the order_status enum with default 'pending'", status)
+	}
+	if total := findField(t, order, "total"); total.Type != constants.FieldTypeDecimal || total.SQLType != "numeric(12,2)" {
+		t.Errorf("total = %+v, want decimal numeric(12,2)", total)
+	}
+	if len(order.Indexes) != 1 || !order.Indexes[0].Unique || order.Indexes[0].Name != "orders_customer_status_idx" {
+		t.Errorf("order indexes = %+v, want the composite unique index", order.Indexes)
+	}
+
+	wantWarnings := map[string]string{
+		constants.WarnUnsupportedType:       "tags",
+		constants.WarnUnsupportedConstraint: "CHECK",
+		constants.WarnSkippedTable:          "composite primary key",
+		constants.WarnUnsupportedStatement:  "CREATE VIEW",
+	}
+	for code, text := range wantWarnings {
+		if !hasWarning(warnings, code, text) {
+			t.Errorf("missing %s warning mentioning %q in %+v", code, text, warnings)
+		}
+	}
+	if !hasWarning(warnings, constants.WarnUnsupportedConstraint, "expression") {
+		t.Errorf("missing warning for the expression index in %+v", warnings)
+	}
+
+	req := &models.GenerateRequest{
+		ProjectName: "shop",
+		ModuleName:  "github.com/acme/shop",
+		Framework:   "gin",
+		Libs:        []string{"postgres"},
+		Entities:    entities,
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("generated entities do not validate: %v", err)
+	}
+}
+
+func TestParseSQLSchema_MySQL(t *testing.T) {
+	entities, warnings, err := parseSQLSchema(mysqlSchema, "mysql")
+	if err != nil {
+		t.Fatalf("parseSQLSchema() error = %v", err)
+	}
+	if len(entities) != 1 || entities[0].Name != "product" {
+		t.Fatalf("entities = %+v, want product", entities)
+	}
+	product := entities[0]
+
+	if id := findField(t, product, "id"); !id.PrimaryKey || !id.AutoIncrement {
+		t.Errorf("id = %+v, want an auto-increment primary key", id)
+	}
+	if sku := findField(t, product, "sku"); !sku.Unique || sku.Type != constants.FieldTypeString {
+		t.Errorf("sku = %+v, want a unique string", sku)
+	}
+	if active := findField(t, product, "active"); active.Type != constants.FieldTypeBool || active.Default != "'1'" {
+		t.Errorf("active = %+v, want bool with default '1'", active)
+	}
+	if kind := findField(t, product, "kind"); kind.Type != constants.FieldTypeEnum || !kind.Nullable || kind.Default != "" {
+		t.Errorf("kind = %+v, want a nullable enum without default", kind)
+	}
+	if price := findField(t, product, "price"); price.Type != constants.FieldTypeFloat {
+		t.Errorf("price = %+v, want float", price)
+	}
+	if len(product.Indexes) != 1 || product.Indexes[0].Unique {
+		t.Errorf("indexes = %+v, want one composite index", product.Indexes)
+	}
+	for _, f := range product.Fields {
+		if f.Name == "picture" {
+			t.Errorf("blob column picture should be skipped")
+		}
+	}
+
+	if !hasWarning(warnings, constants.WarnUnsupportedType, "blob") {
+		t.Errorf("missing warning for the blob column in %+v", warnings)
+	}
+	if !hasWarning(warnings, constants.WarnUnsupportedConstraint, "ON UPDATE") {
+		t.Errorf("missing warning for ON UPDATE in %+v", warnings)
+	}
+	for _, w := range warnings {
+		if w.Code == constants.WarnUnsupportedStatement {
+			t.Errorf("SET and DROP should be ignored silently, got %+v", w)
+		}
+	}
+}
+
+func TestParseSQLSchema_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+	}{
+		{"unterminated string", "CREATE TABLE t (id int PRIMARY KEY DEFAULT 'x);"},
+		{"unterminated comment", "/* CREATE TABLE t (id int);"},
+		{"missing table name", "CREATE TABLE (id int);"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseSQLSchema(tt.sql, "postgres"); err == nil {
+				t.Errorf("parseSQLSchema() expected an error")
+			}
+		})
+	}
+}
+
+func TestSingular(t *testing.T) {
+	for plural, want := range map[string]string{
+		"users": "user", "categories": "category", "addresses": "address", "boxes": "box",
+		"status": "status", "user": "user", "branches": "branch",
+	} {
+		if got := singular(plural); got != want {
+			t.Errorf("singular(%q) = %q, want %q", plural, got, want)
+		}
+	}
+}
+
+// findField returns the field with the given name or fails the test
+func findField(t *testing.T, entity models.EntityDef, name string) models.FieldDef {
+	t.Helper()
+	for _, f := range entity.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	t.Fatalf("entity %s has no field %s", entity.Name, name)
+	return models.FieldDef{}
+}
+
+// hasWarning reports whether a warning with the code mentions text
+func hasWarning(warnings []models.Warning, code, text string) bool {
+	for _, w := range warnings {
+		if w.Code == code && strings.Contains(w.String(), text) {
+			return true
+		}
+	}
+	return false
+}
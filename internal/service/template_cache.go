@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+// coreTemplates are the templates rendered independently of the manifest
+var coreTemplates = []string{
+	constants.TemplateDockerfile, constants.TemplateMakefile, constants.TemplateGitignore,
+	constants.TemplateEnvExample, constants.TemplateReadme, constants.TemplateMain,
+	constants.TemplateDocs, constants.TemplateDocsOpenAPI, constants.TemplateGoMod,
+	constants.TemplateDomainEntity, constants.TemplateDomainCache, constants.TemplateErrors,
+	constants.TemplateEntityModel, constants.TemplateEntityRepo, constants.TemplateCacheRepo,
+	constants.TemplateEntityUsecase, constants.TemplateEntityHandler, constants.TemplateHandlerResponse,
+	constants.TemplateAPITypes, constants.TemplateAPIHandler, constants.TemplateAPIOperations,
+	constants.TemplateExampleJob, constants.TemplateRabbitMQConsumer, constants.TemplateKafkaConsumer,
+	constants.TemplateActiveMQConsumer, constants.TemplateServerSimple, constants.TemplateServer,
+	constants.TemplateRoutesSample, constants.TemplateRoutes, constants.TemplateBootstrapSample,
+	constants.TemplateBootstrap, constants.TemplateGateway, constants.TemplateProto,
+	constants.TemplateProtoGRPC, constants.TemplateBuf, constants.TemplateBufGen,
+	constants.TemplateDeps, constants.TemplateConfig,
+}
+
+// templateCache holds every template of a directory, parsed once with templateFuncs and
+// keyed by its slash-separated path, e.g. "templates/cmd/main.tmpl"
+type templateCache struct {
+	dir       string
+	templates map[string]*template.Template
+	stamp     string // paths, sizes and modification times of the parsed files
+}
+
+// loadTemplateCache parses every template under dir
+func loadTemplateCache(dir string) (*templateCache, error) {
+	stamp, err := templateStamp(dir)
+	if err != nil {
+		return nil, errors.ErrFileSystem("Failed to list templates", err).
+			WithContext("path", dir)
+	}
+
+	c := &templateCache{dir: dir, templates: make(map[string]*template.Template), stamp: stamp}
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, constants.TemplateExtension) {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return errors.ErrFileSystem("Failed to read template", err).
+				WithContext("template_path", p)
+		}
+		name := filepath.ToSlash(p)
+		tpl, err := template.New(name).Funcs(templateFuncs).Parse(string(data))
+		if err != nil {
+			return errors.ErrTemplate("Failed to parse template", err).
+				WithContext("template_path", name)
+		}
+		c.templates[name] = tpl
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*errors.AppError); ok {
+			return nil, err
+		}
+		return nil, errors.ErrFileSystem("Failed to list templates", err).
+			WithContext("path", dir)
+	}
+	return c, nil
+}
+
+// templateStamp describes the templates under dir by path, size and modification time,
+// so a change to any of them changes the stamp
+func templateStamp(dir string) (string, error) {
+	return dirStamp(dir, constants.TemplateExtension)
+}
+
+// dirStamp describes the files under dir with extension ext by path, size and modification
+// time
+func dirStamp(dir, ext string) (string, error) {
+	var b strings.Builder
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ext) {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", filepath.ToSlash(p), info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
+
+// lookup returns the parsed template at a path
+func (c *templateCache) lookup(tmplPath string) (*template.Template, bool) {
+	tpl, ok := c.templates[filepath.ToSlash(tmplPath)]
+	return tpl, ok
+}
+
+// list returns the paths of the templates directly in dir, in path order
+func (c *templateCache) list(dir string) []string {
+	dir = filepath.ToSlash(dir)
+	var paths []string
+	for name := range c.templates {
+		if path.Dir(name) == dir {
+			paths = append(paths, name)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// validateTemplates checks that every template the manifest and the generator render was parsed
+func (snap *snapshot) validateTemplates(c *templateCache) error {
+	required := append([]string(nil), coreTemplates...)
+	for _, name := range sortedKeys(snap.manifest.Frameworks) {
+		required = append(required, snap.manifest.Frameworks[name].Templates...)
+	}
+	for _, name := range sortedKeys(snap.manifest.Libs) {
+		required = append(required, snap.manifest.Libs[name].Templates...)
+	}
+	for _, key := range sortedKeys(snap.depsMeta) {
+		for _, helperFile := range snap.depsMeta[key].HelperFiles {
+			required = append(required, path.Join(constants.TemplateDepsDir, path.Base(helperFile)))
+		}
+	}
+
+	var missing []string
+	seen := make(map[string]bool)
+	for _, name := range required {
+		if _, ok := c.lookup(name); !ok && !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.ErrConfig(fmt.Sprintf("Templates not found: %s", strings.Join(missing, ", ")), nil).
+			WithContext("path", c.dir)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+	"github.com/xhkzeroone/go-generator/internal/errors"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		data interface{}
+		want string
+	}{
+		{name: "hasLib map", tmpl: `{{if hasLib .Includes "redis"}}yes{{else}}no{{end}}`, data: map[string]interface{}{"Includes": map[string]bool{"redis": true}}, want: "yes"},
+		{name: "hasLib list", tmpl: `{{if hasLib .Libs "kafka"}}yes{{else}}no{{end}}`, data: map[string]interface{}{"Libs": []string{"redis"}}, want: "no"},
+		{name: "hasLib missing", tmpl: `{{if hasLib .Includes "redis"}}yes{{else}}no{{end}}`, data: map[string]interface{}{}, want: "no"},
+		{name: "join", tmpl: `{{.Topics | join ", "}}`, data: map[string]interface{}{"Topics": []string{"orders", "payments"}}, want: "orders, payments"},
+		{name: "naming", tmpl: `{{camel .}} {{snake .}} {{pascal .}} {{plural (snake .)}}`, data: "order item", want: "orderItem order_item OrderItem order_items"},
+		{name: "goIdent", tmpl: `{{goIdent "pets.list-all"}}`, want: "PetsListAll"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "t.tmpl"), []byte(tt.tmpl), 0644); err != nil {
+				t.Fatal(err)
+			}
+			c, err := loadTemplateCache(dir)
+			if err != nil {
+				t.Fatalf("loadTemplateCache() error = %v", err)
+			}
+			tpl, ok := c.lookup(filepath.Join(dir, "t.tmpl"))
+			if !ok {
+				t.Fatal("template not cached")
+			}
+			var b strings.Builder
+			if err := tpl.Execute(&b, tt.data); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateStamp(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.tmpl")
+	if err := os.WriteFile(file, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadTemplateCache(dir)
+	if err != nil {
+		t.Fatalf("loadTemplateCache() error = %v", err)
+	}
+
+	if stamp, _ := templateStamp(dir); stamp != c.stamp {
+		t.Errorf("stamp changed without a template change")
+	}
+
+	// Files other than templates do not count
+	if err := os.WriteFile(filepath.Join(dir, "config_section.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if stamp, _ := templateStamp(dir); stamp != c.stamp {
+		t.Errorf("stamp changed for a non-template file")
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if stamp, _ := templateStamp(dir); stamp == c.stamp {
+		t.Errorf("stamp unchanged after a template was modified")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte("{{if}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadTemplateCache(dir)
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != errors.ErrCodeTemplate {
+		t.Fatalf("loadTemplateCache() error = %#v, want a template error", err)
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	s := repoService(t)
+
+	if result, err := s.ReloadIfChanged(); err != nil || result.Reloaded {
+		t.Errorf("ReloadIfChanged() = %+v, %v; want no reload", result, err)
+	}
+
+	c, err := loadTemplateCache(constants.TemplateDir)
+	if err != nil {
+		t.Fatalf("loadTemplateCache() error = %v", err)
+	}
+	delete(c.templates, constants.TemplateMain)
+	delete(c.templates, s.manifest.Frameworks["gin"].Templates[0])
+
+	err = s.validateTemplates(c)
+	if err == nil {
+		t.Fatal("validateTemplates() error = nil, want missing templates")
+	}
+	for _, name := range []string{constants.TemplateMain, s.manifest.Frameworks["gin"].Templates[0]} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not name %s", err.Error(), name)
+		}
+	}
+}
+
+// BenchmarkGenerateProject_Uncached parses every template for each project, as every request
+// did before templates were cached; compare with BenchmarkGenerateProject_Memory
+func BenchmarkGenerateProject_Uncached(b *testing.B) {
+	// Each generation pins the current snapshot, which is replaced by one whose templates
+	// are parsed again
+	s := repoService(b)
+	req := benchmarkRequest()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		templates, err := loadTemplateCache(constants.TemplateDir)
+		if err != nil {
+			b.Fatal(err)
+		}
+		snap := *s.sources.current.Load()
+		snap.templates = templates
+		s.sources.current.Store(&snap)
+		out := newMemoryOutput()
+		if _, err := s.generate(context.Background(), req, out); err != nil {
+			b.Fatal(err)
+		}
+		if err := writeArchive(io.Discard, out, constants.ArchiveFormatZip, constants.MinArchiveTime); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
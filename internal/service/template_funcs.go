@@ -0,0 +1,35 @@
+package service
+
+import (
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the functions every template can call
+var templateFuncs = template.FuncMap{
+	"camel":   camelCase,
+	"snake":   snakeCase,
+	"pascal":  pascalCase,
+	"plural":  plural,
+	"goIdent": identifier,
+	"hasLib":  hasLib,
+	"join":    join,
+}
+
+// hasLib reports whether a lib is selected, given the Includes map or a list of libs,
+// e.g. {{if hasLib .Includes "redis"}}
+func hasLib(libs interface{}, lib string) bool {
+	switch typed := libs.(type) {
+	case map[string]bool:
+		return typed[lib]
+	case []string:
+		return containsString(typed, lib)
+	}
+	return false
+}
+
+// join joins a list with a separator; the list comes last so it can be piped,
+// e.g. {{.Topics | join ", "}}
+func join(sep string, items []string) string {
+	return strings.Join(items, sep)
+}
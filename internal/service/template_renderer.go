@@ -1,57 +1,60 @@
 package service
 
 import (
-	"os"
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"path"
 	"path/filepath"
 	"strings"
-	"text/template"
 
 	"github.com/xhkzeroone/go-generator/internal/constants"
 	"github.com/xhkzeroone/go-generator/internal/errors"
 	"github.com/xhkzeroone/go-generator/internal/models"
 )
 
-// renderTemplate renders a template file to a destination path
-func (s *GeneratorService) renderTemplate(tmplPath, dstPath string, data interface{}) error {
-	tpl, err := template.ParseFiles(tmplPath)
-	if err != nil {
-		return errors.ErrTemplate("Failed to parse template", err).
+// renderTemplate renders a cached template to a file of the output
+func (s *GeneratorService) renderTemplate(out outputSink, tmplPath, name string, data interface{}) error {
+	tpl, ok := s.templates.lookup(tmplPath)
+	if !ok {
+		return errors.ErrTemplate("Template not found", nil).
 			WithContext("template_path", tmplPath)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(dstPath), constants.DirPerm); err != nil {
-		return errors.ErrFileSystem("Failed to create directory", err).
-			WithContext("path", filepath.Dir(dstPath))
-	}
-
-	f, err := os.Create(dstPath)
-	if err != nil {
-		return errors.ErrFileSystem("Failed to create file", err).
-			WithContext("path", dstPath)
-	}
-	defer f.Close()
-
-	if err := tpl.Execute(f, data); err != nil {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
 		return errors.ErrTemplate("Failed to execute template", err).
 			WithContext("template_path", tmplPath).
-			WithContext("output_path", dstPath)
+			WithContext("output_path", name)
 	}
 
+	if err := out.WriteFile(name, buf.Bytes()); err != nil {
+		if stderrors.Is(err, fs.ErrExist) {
+			return errors.ErrTemplate(fmt.Sprintf("Template %s writes %s, which is already generated", tmplPath, name), err).
+				WithContext("template_path", tmplPath).
+				WithContext("output_path", name)
+		}
+		return errors.ErrFileSystem("Failed to write file", err).
+			WithContext("template_path", tmplPath).
+			WithContext("output_path", name)
+	}
 	return nil
 }
 
-// renderFrameworkTemplates renders framework-specific templates
-func (s *GeneratorService) renderFrameworkTemplates(tmp string, req *GenerateRequest, fdef models.FrameworkDef) error {
+// renderFrameworkTemplates renders framework-specific templates into internal/app, prefixed
+// with the framework (gin_server.go) so they do not collide with the app's own server.go
+func (s *GeneratorService) renderFrameworkTemplates(out outputSink, req *GenerateRequest, fdef models.FrameworkDef) error {
 	for _, t := range fdef.Templates {
 		baseName := filepath.Base(strings.TrimSuffix(t, constants.TemplateExtension))
-		outPath := filepath.Join(tmp, constants.DirInternalApp, baseName+constants.GoFileExtension)
+		outPath := path.Join(constants.DirInternalApp, req.Framework+"_"+baseName+constants.GoFileExtension)
 
 		data := map[string]interface{}{
 			"ModuleName":  req.ModuleName,
 			"ProjectName": req.ProjectName,
 			"Framework":   req.Framework,
 		}
-		if err := s.renderTemplate(t, outPath, data); err != nil {
+		if err := s.renderTemplate(out, t, outPath, data); err != nil {
 			return err
 		}
 	}
@@ -59,17 +62,18 @@ func (s *GeneratorService) renderFrameworkTemplates(tmp string, req *GenerateReq
 }
 
 // renderLibTemplates renders library-specific templates
-func (s *GeneratorService) renderLibTemplates(tmp string, req *GenerateRequest, lib string, ldef models.LibDef) error {
+func (s *GeneratorService) renderLibTemplates(out outputSink, req *GenerateRequest, lib string, ldef models.LibDef) error {
 	for _, t := range ldef.Templates {
 		baseName := filepath.Base(strings.TrimSuffix(t, constants.TemplateExtension))
-		outPath := filepath.Join(tmp, constants.DirInternalInfra, lib, baseName+constants.GoFileExtension)
+		outPath := path.Join(constants.DirInternalInfra, lib, baseName+constants.GoFileExtension)
 
 		data := map[string]interface{}{
 			"ModuleName":  req.ModuleName,
 			"ProjectName": req.ProjectName,
 			"Lib":         lib,
+			"Options":     s.libOptions(req, lib),
 		}
-		if err := s.renderTemplate(t, outPath, data); err != nil {
+		if err := s.renderTemplate(out, t, outPath, data); err != nil {
 			return err
 		}
 	}
@@ -77,47 +81,57 @@ func (s *GeneratorService) renderLibTemplates(tmp string, req *GenerateRequest,
 }
 
 // renderMainFile renders the main.go file
-func (s *GeneratorService) renderMainFile(tmp string, req *GenerateRequest, includes map[string]bool) error {
-	outPath := filepath.Join(tmp, constants.DirCmd, "main.go")
+func (s *GeneratorService) renderMainFile(out outputSink, req *GenerateRequest, includes map[string]bool) error {
+	outPath := path.Join(constants.DirCmd, "main.go")
 	data := map[string]interface{}{
 		"ModuleName":  req.ModuleName,
 		"ProjectName": req.ProjectName,
 		"Framework":   req.Framework,
 		"Includes":    includes,
+		"ConfigPath":  configFilePath(req),
 	}
-	return s.renderTemplate(constants.TemplateMain, outPath, data)
+	return s.renderTemplate(out, constants.TemplateMain, outPath, data)
 }
 
-// renderDocsStub renders the Swagger docs stub
-func (s *GeneratorService) renderDocsStub(tmp string, req *GenerateRequest) error {
-	outPath := filepath.Join(tmp, constants.DirDocs, "docs.go")
+// renderDocs renders the Swagger docs package: the supplied OpenAPI document, or a stub
+// that `swag init` replaces
+func (s *GeneratorService) renderDocs(out outputSink, req *GenerateRequest, api *APIView) error {
+	outPath := path.Join(constants.DirDocs, "docs.go")
 	data := map[string]interface{}{
 		"ModuleName":  req.ModuleName,
 		"ProjectName": req.ProjectName,
 	}
-	return s.renderTemplate(constants.TemplateDocs, outPath, data)
+	if api == nil {
+		return s.renderTemplate(out, constants.TemplateDocs, outPath, data)
+	}
+
+	// The document is embedded in a raw string literal; backquotes are spliced in the way swag does
+	data["API"] = api
+	data["Spec"] = strings.ReplaceAll(api.Spec, "`", "` + \"`\" + `")
+	return s.renderTemplate(out, constants.TemplateDocsOpenAPI, outPath, data)
 }
 
-// renderGoMod renders the go.mod file
-func (s *GeneratorService) renderGoMod(tmp string, req *GenerateRequest, imports []string) error {
-	outPath := filepath.Join(tmp, constants.GoModFileName)
+// renderGoMod renders the go.mod file, requiring the modules the project imports
+func (s *GeneratorService) renderGoMod(out outputSink, req *GenerateRequest, requires []ModuleRequirement) error {
+	outPath := constants.GoModFileName
+	goVersion := constants.GoModVersion
+	if req.Framework == constants.FrameworkNetHTTP {
+		goVersion = constants.GoModVersionServeMux
+	}
 	data := map[string]interface{}{
 		"ModuleName": req.ModuleName,
-		"Imports":    imports,
+		"GoVersion":  goVersion,
+		"Requires":   requires,
 	}
-	return s.renderTemplate(constants.TemplateGoMod, outPath, data)
+	return s.renderTemplate(out, constants.TemplateGoMod, outPath, data)
 }
 
-// renderMiddlewareTemplates renders middleware templates for the selected framework
-func (s *GeneratorService) renderMiddlewareTemplates(tmp string, req *GenerateRequest) error {
-	// Define middleware template files based on framework
-	middlewareDir := filepath.Join("templates", "middleware", req.Framework)
-	middlewareFiles := []string{"logging.tmpl", "tracing.tmpl", "ratelimit.tmpl"}
-
-	for _, middlewareFile := range middlewareFiles {
-		tmplPath := filepath.Join(middlewareDir, middlewareFile)
-		baseName := filepath.Base(strings.TrimSuffix(middlewareFile, constants.TemplateExtension))
-		outPath := filepath.Join(tmp, constants.DirInternalMiddleware, baseName+constants.GoFileExtension)
+// renderMiddlewareTemplates renders every middleware template of the selected framework
+func (s *GeneratorService) renderMiddlewareTemplates(out outputSink, req *GenerateRequest) error {
+	middlewareDir := path.Join(constants.TemplateDir, "middleware", req.Framework)
+	for _, tmplPath := range s.templates.list(middlewareDir) {
+		baseName := filepath.Base(strings.TrimSuffix(tmplPath, constants.TemplateExtension))
+		outPath := path.Join(constants.DirInternalMiddleware, baseName+constants.GoFileExtension)
 
 		data := map[string]interface{}{
 			"ModuleName":  req.ModuleName,
@@ -125,7 +139,7 @@ func (s *GeneratorService) renderMiddlewareTemplates(tmp string, req *GenerateRe
 			"Framework":   req.Framework,
 		}
 
-		if err := s.renderTemplate(tmplPath, outPath, data); err != nil {
+		if err := s.renderTemplate(out, tmplPath, outPath, data); err != nil {
 			return err
 		}
 	}
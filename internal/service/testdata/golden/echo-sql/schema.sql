@@ -0,0 +1,18 @@
+CREATE TYPE order_status AS ENUM ('pending', 'paid', 'shipped');
+
+CREATE TABLE customers (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE orders (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+    status order_status NOT NULL DEFAULT 'pending',
+    total NUMERIC(12, 2) NOT NULL,
+    tags TEXT[]
+);
+
+CREATE INDEX idx_orders_customer ON orders (customer_id);
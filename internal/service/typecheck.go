@@ -0,0 +1,189 @@
+package service
+
+import (
+	stderrors "errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/xhkzeroone/go-generator/internal/constants"
+)
+
+// SourceError is a problem found in a generated file
+type SourceError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e SourceError) String() string {
+	switch {
+	case e.File == "":
+		return e.Message
+	case e.Line == 0:
+		return e.File + ": " + e.Message
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// errStubPackage marks the imports the type checker resolves to a stub package
+var errStubPackage = stderrors.New("third-party stub package")
+
+// typeChecker type-checks the Go packages of a generated project. Imports inside the
+// project's module are checked from the generated files, standard library imports come from
+// std, and any other import resolves to an empty stub package, so no module has to be
+// downloaded. Only the project's own code is checked; its uses of third-party packages are not.
+type typeChecker struct {
+	fset      *token.FileSet
+	module    string
+	goVersion string
+	std       types.Importer
+
+	dirs     map[string][]*ast.File
+	stubs    map[string]*types.Package
+	packages map[string]*types.Package
+	checking map[string]bool
+	errors   []SourceError
+}
+
+// typeCheckProject parses and type-checks every Go package of a generated project
+func typeCheckProject(out outputSink, module string, std types.Importer) ([]SourceError, error) {
+	names, err := out.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &typeChecker{
+		fset:      token.NewFileSet(),
+		module:    module,
+		goVersion: "go" + constants.DefaultGoVersion,
+		std:       std,
+		dirs:      make(map[string][]*ast.File),
+		packages:  make(map[string]*types.Package),
+		stubs:     make(map[string]*types.Package),
+		checking:  make(map[string]bool),
+	}
+	for _, name := range names {
+		if name == "go.mod" {
+			if data, err := out.ReadFile(name); err == nil {
+				c.goVersion = "go" + goDirective(data)
+			}
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := out.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(c.fset, name, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		dir := path.Dir(name)
+		c.dirs[dir] = append(c.dirs[dir], f)
+	}
+
+	for _, dir := range sortedKeys(c.dirs) {
+		c.check(c.importPath(dir))
+	}
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i], c.errors[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.errors, nil
+}
+
+// Import implements types.Importer
+func (c *typeChecker) Import(importPath string) (*types.Package, error) {
+	switch {
+	case c.isProject(importPath):
+		return c.check(importPath), nil
+	case isStdlib(importPath):
+		return c.std.Import(importPath)
+	}
+	stub, ok := c.stubs[importPath]
+	if !ok {
+		stub = types.NewPackage(importPath, importPathToName(importPath))
+		stub.MarkComplete()
+		c.stubs[importPath] = stub
+	}
+	// Returned with an error, the empty stub becomes a fake package: the checker resolves
+	// every name selected from it silently. Unused imports of it go unreported, but
+	// formatGoFiles has removed those already.
+	return stub, errStubPackage
+}
+
+// check type-checks a package of the project once, recording its errors
+func (c *typeChecker) check(importPath string) *types.Package {
+	if pkg, ok := c.packages[importPath]; ok || c.checking[importPath] {
+		return pkg
+	}
+	c.checking[importPath] = true
+	defer delete(c.checking, importPath)
+
+	dir := strings.TrimPrefix(strings.TrimPrefix(importPath, c.module), "/")
+	if dir == "" {
+		dir = "."
+	}
+	conf := types.Config{
+		GoVersion: c.goVersion,
+		Importer:  c,
+		Error: func(err error) {
+			if terr, ok := err.(types.Error); ok {
+				if strings.HasSuffix(terr.Msg, "("+errStubPackage.Error()+")") {
+					return
+				}
+				pos := c.fset.Position(terr.Pos)
+				c.errors = append(c.errors, SourceError{File: pos.Filename, Line: pos.Line, Column: pos.Column, Message: terr.Msg})
+				return
+			}
+			c.errors = append(c.errors, SourceError{Message: err.Error()})
+		},
+	}
+	// The errors reach conf.Error; a package with errors is still complete enough to import
+	pkg, _ := conf.Check(importPath, c.fset, c.dirs[dir], nil)
+	c.packages[importPath] = pkg
+	return pkg
+}
+
+func (c *typeChecker) isProject(importPath string) bool {
+	return importPath == c.module || strings.HasPrefix(importPath, c.module+"/")
+}
+
+func (c *typeChecker) importPath(dir string) string {
+	if dir == "." {
+		return c.module
+	}
+	return c.module + "/" + dir
+}
+
+// isStdlib reports whether an import path belongs to the standard library, whose paths
+// have no dot in their first element
+func isStdlib(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// goDirective returns the go version of a go.mod file
+func goDirective(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "go "); ok {
+			return strings.TrimSpace(version)
+		}
+	}
+	return constants.DefaultGoVersion
+}
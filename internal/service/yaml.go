@@ -0,0 +1,39 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// YAMLToJSON converts a YAML document into JSON so it can be decoded with json tags
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(normalizeYAML(doc))
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values produced by the
+// YAML decoder into map[string]interface{} values that encoding/json accepts.
+// Scalar keys such as the unquoted 200 of an OpenAPI responses map become strings.
+func normalizeYAML(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(typed))
+		for k, val := range typed {
+			out[fmt.Sprint(k)] = normalizeYAML(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(typed))
+		for i, val := range typed {
+			out[i] = normalizeYAML(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -1,53 +0,0 @@
-package service
-
-import (
-	"archive/zip"
-	"bytes"
-	"io/fs"
-	"os"
-	"path/filepath"
-)
-
-// createZip creates a ZIP archive from the temporary directory
-func (s *GeneratorService) createZip(tmp string) ([]byte, error) {
-	var buf bytes.Buffer
-	zw := zip.NewWriter(&buf)
-
-	err := filepath.WalkDir(tmp, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
-
-		rel, err := filepath.Rel(tmp, path)
-		if err != nil {
-			return err
-		}
-
-		f, err := zw.Create(rel)
-		if err != nil {
-			return err
-		}
-
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-
-		_, err = f.Write(data)
-		return err
-	})
-
-	if err != nil {
-		zw.Close()
-		return nil, err
-	}
-
-	if err := zw.Close(); err != nil {
-		return nil, err
-	}
-
-	return buf.Bytes(), nil
-}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
+	"runtime"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -44,14 +47,51 @@ func main() {
 		logger.WithError(err).Fatal("Failed to initialize generator service")
 	}
 
+	// Generation deadline (e.g. GENERATION_TIMEOUT=5s, 0 disables it)
+	genService.SetGenerationTimeout(envDuration(logger, "GENERATION_TIMEOUT", constants.DefaultGenerationTimeout))
+
+	// protoc-gen-go compiles the stubs of gRPC projects; without it only gRPC generation fails
+	protocGenGo := constants.ProtocGenGo
+	if plugin := os.Getenv("PROTOC_GEN_GO"); plugin != "" {
+		protocGenGo = plugin
+	}
+	if _, err := exec.LookPath(protocGenGo); err != nil {
+		logger.WithError(err).Warn("protoc-gen-go not found; gRPC projects cannot be generated")
+	}
+	genService.SetProtocGenGo(protocGenGo)
+
 	// Initialize rate limiter (100 requests per minute per IP)
 	rateLimiter := middleware.NewRateLimiter(100, time.Minute, logger)
 	defer rateLimiter.Stop()
 
+	// Initialize generation limiter (workers, wait queue and longest wait in the queue)
+	genLimiter := middleware.NewGenerationLimiter(
+		envInt(logger, "GENERATION_WORKERS", runtime.NumCPU()),
+		envInt(logger, "GENERATION_QUEUE", constants.DefaultGenerationQueue),
+		envDuration(logger, "GENERATION_QUEUE_WAIT", constants.DefaultGenerationQueueWait),
+		logger,
+	)
+
+	// Initialize job store (finished jobs are kept for JOB_TTL)
+	jobStore := service.NewJobStore(envDuration(logger, "JOB_TTL", constants.DefaultJobTTL),
+		envInt(logger, "MAX_JOBS", constants.DefaultMaxJobs))
+	defer jobStore.Stop()
+
 	// Initialize handlers
-	genHandler := handler.NewGenerateHandler(genService, logger)
+	genHandler := handler.NewGenerateHandler(genService, genLimiter, logger)
+	previewHandler := handler.NewPreviewHandler(genService, genLimiter, logger)
 	healthHandler := handler.NewHealthHandler(logger)
 	manifestHandler := handler.NewManifestHandler(genService, logger)
+	jobHandler := handler.NewJobHandler(genService, jobStore, genLimiter,
+		envDuration(logger, "JOB_TIMEOUT", constants.DefaultJobTimeout), logger)
+	defer jobHandler.Stop()
+	adminHandler := handler.NewAdminHandler(genService, os.Getenv("ADMIN_TOKEN"), logger)
+
+	// Reload the manifest, metadata and templates when they change (RELOAD_MODE=watch, poll
+	// or off) and on SIGHUP
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	watchReloads(reloadCtx, genService, adminHandler, logger)
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -74,8 +114,15 @@ func main() {
 
 	// API endpoints with all middlewares and rate limiting (must be before the catch-all)
 	mux.Handle("/generate", chainMiddleware(rateLimiter.Limit(http.HandlerFunc(genHandler.HandleGenerate))))
+	mux.Handle("/generate/stream", chainMiddleware(rateLimiter.Limit(http.HandlerFunc(jobHandler.HandleGenerateStream))))
+	mux.Handle("/preview", chainMiddleware(rateLimiter.Limit(http.HandlerFunc(previewHandler.HandlePreview))))
+	mux.Handle("/preview/file", chainMiddleware(rateLimiter.Limit(http.HandlerFunc(previewHandler.HandlePreviewFile))))
+	mux.Handle("/jobs", chainMiddleware(rateLimiter.Limit(http.HandlerFunc(jobHandler.HandleCreateJob))))
+	mux.Handle("/jobs/{id}", chainMiddleware(http.HandlerFunc(jobHandler.HandleGetJob)))
+	mux.Handle("/jobs/{id}/archive", chainMiddleware(http.HandlerFunc(jobHandler.HandleJobArchive)))
 	mux.Handle("/health", chainMiddleware(http.HandlerFunc(healthHandler.HandleHealth)))
 	mux.Handle("/manifest", chainMiddleware(http.HandlerFunc(manifestHandler.HandleManifest)))
+	mux.Handle("/admin/reload", chainMiddleware(http.HandlerFunc(adminHandler.HandleReload)))
 	mux.Handle("/swagger/", chainMiddleware(httpSwagger.WrapHandler))
 
 	// Serve frontend as catch-all (with all middlewares)
@@ -121,3 +168,76 @@ func main() {
 
 	logger.Info("Server exited")
 }
+
+// watchReloads starts reloading the generator service when its files change, per
+// RELOAD_MODE, and on SIGHUP; reloads are reported through the admin handler
+func watchReloads(ctx context.Context, genService *service.GeneratorService, adminHandler *handler.AdminHandler, logger *logrus.Logger) {
+	report := func(trigger string) service.ReloadFunc {
+		return func(result *service.ReloadResult, err error) {
+			adminHandler.ReportReload(trigger, result, err)
+		}
+	}
+	interval := envDuration(logger, "RELOAD_INTERVAL", constants.DefaultReloadInterval)
+	if interval <= 0 {
+		logger.WithField("value", interval).Fatal("Invalid RELOAD_INTERVAL")
+	}
+
+	mode := os.Getenv("RELOAD_MODE")
+	switch mode {
+	case "", constants.ReloadModeWatch:
+		mode = constants.ReloadModeWatch
+		if err := genService.WatchFiles(ctx, report(middleware.ReloadWatch)); err != nil {
+			logger.WithError(err).Warn("Failed to watch templates, polling instead")
+			mode = constants.ReloadModePoll
+			go genService.PollFiles(ctx, interval, report(middleware.ReloadPoll))
+		}
+	case constants.ReloadModePoll:
+		go genService.PollFiles(ctx, interval, report(middleware.ReloadPoll))
+	case constants.ReloadModeOff:
+	default:
+		logger.WithField("value", mode).Fatal("Invalid RELOAD_MODE")
+	}
+	logger.WithField("mode", mode).Info("Template reloading configured")
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				result, err := genService.Reload()
+				adminHandler.ReportReload(middleware.ReloadSignal, result, err)
+			}
+		}
+	}()
+}
+
+// envInt returns the non-negative integer in an environment variable, or def when it is unset
+func envInt(logger *logrus.Logger, name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		logger.WithField("value", value).Fatal("Invalid " + name)
+	}
+	return n
+}
+
+// envDuration returns the non-negative duration (e.g. 5s) in an environment variable, or def
+// when it is unset
+func envDuration(logger *logrus.Logger, name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		logger.WithField("value", value).Fatal("Invalid " + name)
+	}
+	return d
+}